@@ -0,0 +1,141 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/github/github-mcp-server/pkg/oauth"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var loginCmd = &cobra.Command{
+	Use:   "login",
+	Short: "Log in to GitHub with the OAuth device flow",
+	Long: `Log in to the GitHub host of --gh-host with the OAuth device flow and store the token for the stdio server.
+
+The token is written to a file only readable by the current user under the user config directory
+(or --token-file). It is encrypted if GITHUB_TOKEN_ENCRYPTION_KEY holds a base64 encoded 32 byte key.
+Expiring tokens are refreshed by the server; refreshing tokens of some GitHub Apps also needs
+GITHUB_OAUTH_CLIENT_SECRET.`,
+	RunE: runLogin,
+}
+
+var logoutCmd = &cobra.Command{
+	Use:   "logout",
+	Short: "Delete the token stored by login",
+	Long:  `Delete the token stored by login for the GitHub host of --gh-host (or --token-file).`,
+	RunE:  runLogout,
+}
+
+func init() {
+	loginCmd.Flags().String("oauth-client-id", "", "Client ID of the OAuth app or GitHub App to log in with (fallback: GITHUB_OAUTH_CLIENT_ID env var)")
+	loginCmd.Flags().StringSlice("scopes", oauth.DefaultScopes, "Comma separated OAuth scopes to request, ignored by GitHub Apps")
+	_ = viper.BindPFlag("oauth_client_id", loginCmd.Flags().Lookup("oauth-client-id"))
+
+	rootCmd.AddCommand(loginCmd)
+	rootCmd.AddCommand(logoutCmd)
+}
+
+func runLogin(cmd *cobra.Command, _ []string) error {
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	host := viper.GetString("host")
+	client, err := oauth.NewClient(host, viper.GetString("oauth_client_id"), viper.GetString("oauth_client_secret"))
+	if err != nil {
+		return fmt.Errorf("failed to log in: %w", err)
+	}
+	store, err := tokenStore(host)
+	if err != nil {
+		return err
+	}
+	scopes, _ := cmd.Flags().GetStringSlice("scopes")
+
+	code, err := client.RequestDeviceCode(ctx, scopes)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.ErrOrStderr(), "Open %s and enter the code %s\n", code.VerificationURI, code.UserCode)
+
+	token, err := client.PollToken(ctx, code)
+	if err != nil {
+		return fmt.Errorf("failed to log in: %w", err)
+	}
+	if err := store.Save(token); err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.ErrOrStderr(), "Logged in, the token is stored in %s\n", store.Path())
+	return nil
+}
+
+func runLogout(cmd *cobra.Command, _ []string) error {
+	store, err := tokenStore(viper.GetString("host"))
+	if err != nil {
+		return err
+	}
+	if err := store.Delete(); err != nil {
+		if oauth.IsNotLoggedIn(err) {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Not logged in, there is no token in %s\n", store.Path())
+			return nil
+		}
+		return fmt.Errorf("failed to delete the stored token: %w", err)
+	}
+	fmt.Fprintf(cmd.ErrOrStderr(), "Logged out, deleted %s\n", store.Path())
+	return nil
+}
+
+// tokenStore returns the store of the login token of the GitHub host, encrypted with
+// GITHUB_TOKEN_ENCRYPTION_KEY if it is set
+func tokenStore(host string) (*oauth.Store, error) {
+	path := viper.GetString("token_file")
+	if path == "" {
+		var err error
+		path, err = oauth.DefaultTokenPath(host)
+		if err != nil {
+			return nil, fmt.Errorf("failed to locate the token file, set --token-file: %w", err)
+		}
+	}
+
+	var key []byte
+	if encoded := viper.GetString("token_encryption_key"); encoded != "" {
+		var err error
+		key, err = oauth.ParseEncryptionKey(encoded)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return oauth.NewStore(path, key), nil
+}
+
+// storedLogin returns the token source of the token stored by login for the GitHub host
+func storedLogin(host string) (*oauth.TokenSource, error) {
+	store, err := tokenStore(host)
+	if err != nil {
+		return nil, err
+	}
+	login, err := oauth.NewTokenSource(store, viper.GetString("oauth_client_secret"))
+	if err != nil {
+		if oauth.IsNotLoggedIn(err) {
+			return nil, errors.New("GITHUB_PERSONAL_ACCESS_TOKEN not provided in input or environment, and not logged in (run the login command)")
+		}
+		return nil, fmt.Errorf("failed to load the stored token: %w", err)
+	}
+
+	// A token file passed with --token-file may belong to another host
+	loginURL, err := oauth.WebURL(login.Host())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the stored token: %w", err)
+	}
+	hostURL, err := oauth.WebURL(host)
+	if err != nil {
+		return nil, err
+	}
+	if loginURL.String() != hostURL.String() {
+		return nil, fmt.Errorf("the stored token was issued by %s, not %s, log in again", loginURL, hostURL)
+	}
+	return login, nil
+}
@@ -5,10 +5,18 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/github/github-mcp-server/internal/ghmcp"
 	"github.com/github/github-mcp-server/pkg/access"
+	"github.com/github/github-mcp-server/pkg/audit"
 	"github.com/github/github-mcp-server/pkg/github"
+	"github.com/github/github-mcp-server/pkg/githubapp"
+	"github.com/github/github-mcp-server/pkg/httpcache"
+	"github.com/github/github-mcp-server/pkg/oauth"
+	"github.com/github/github-mcp-server/pkg/ratelimit"
+	"github.com/github/github-mcp-server/pkg/raw"
+	"github.com/github/github-mcp-server/pkg/tracing"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
@@ -41,12 +49,31 @@ var (
 				}
 			}
 
+			app := githubapp.Config{
+				AppID:          viper.GetInt64("app_id"),
+				InstallationID: viper.GetInt64("app_installation_id"),
+				PrivateKeyFile: viper.GetString("app_private_key_file"),
+			}
+
 			token := viper.GetString("personal_access_token")
 			if token == "" {
 				// Fallback to environment variable
 				token = os.Getenv("GITHUB_PERSONAL_ACCESS_TOKEN")
-				if token == "" {
-					return errors.New("GITHUB_PERSONAL_ACCESS_TOKEN not provided in input or environment")
+			}
+			var login *oauth.TokenSource
+			if app.Enabled() {
+				if token != "" {
+					return errors.New("set either GITHUB_PERSONAL_ACCESS_TOKEN or a GitHub App, not both")
+				}
+				if err := app.Validate(); err != nil {
+					return fmt.Errorf("invalid GitHub App configuration: %w", err)
+				}
+			} else if token == "" {
+				// Fall back to the token stored by the login command
+				var err error
+				login, err = storedLogin(viper.GetString("host"))
+				if err != nil {
+					return err
 				}
 			}
 
@@ -59,68 +86,108 @@ var (
 				return fmt.Errorf("failed to unmarshal toolsets: %w", err)
 			}
 
-			stdioServerConfig := ghmcp.StdioServerConfig{
-				Version:              version,
-				Host:                 viper.GetString("host"),
-				Token:                token,
-				UserEmail:            userEmail,
-				EnabledToolsets:      enabledToolsets,
-				DynamicToolsets:      viper.GetBool("dynamic_toolsets"),
-				ReadOnly:             viper.GetBool("read-only"),
-				ExportTranslations:   viper.GetBool("export-translations"),
-				EnableCommandLogging: viper.GetBool("enable-command-logging"),
-				LogFilePath:          viper.GetString("log-file"),
-				ContentWindowSize:    viper.GetInt("content-window-size"),
+			failureMode, err := access.ParseFailureMode(viper.GetString("access_failure_mode"))
+			if err != nil {
+				return err
 			}
-			return ghmcp.RunStdioServer(stdioServerConfig)
-		},
-	}
 
-	validateAccessCmd = &cobra.Command{
-		Use:   "validate-access",
-		Short: "Validate repository access for a user",
-		Long:  `Validate whether a user has access to a specific repository based on their email and the repository URL.`,
-		RunE: func(cmd *cobra.Command, _ []string) error {
-			
-			// Step 1: Extract and validate user-email flag
-			userEmail, err := cmd.Flags().GetString("user-email")
+			cacheFile, err := accessCacheFile(userEmail)
 			if err != nil {
-				return fmt.Errorf("failed to get user-email flag: %w", err)
-			}
-			if userEmail == "" {
-				return errors.New("user-email is required")
+				return err
 			}
 
-			// Step 2: Extract and validate repo-url flag
-			repoURL, err := cmd.Flags().GetString("repo-url")
+			userEmailCheck, err := ghmcp.ParseUserEmailCheck(viper.GetString("user_email_check"))
 			if err != nil {
-				return fmt.Errorf("failed to get repo-url flag: %w", err)
+				return err
 			}
-			if repoURL == "" {
-				return errors.New("repo-url is required")
+
+			stdioServerConfig := ghmcp.StdioServerConfig{
+				Version:                 version,
+				Host:                    viper.GetString("host"),
+				Endpoints:               apiEndpoints(),
+				Token:                   token,
+				App:                     app,
+				Login:                   login,
+				UserEmail:               userEmail,
+				AccessProvider:          viper.GetString("access_provider"),
+				AccessPolicyFile:        viper.GetString("access_policy_file"),
+				AccessOverrideFile:      viper.GetString("access_override_file"),
+				ResourceMap:             resourceMapConfig(),
+				AccessStartup:           accessStartupConfig(failureMode),
+				AccessCacheFile:         cacheFile,
+				AccessCacheMaxAge:       viper.GetDuration("access_cache_max_age"),
+				AuditLog:                auditLogConfig(),
+				AccessRefreshInterval:   viper.GetDuration("access_refresh_interval"),
+				AccessDefaultPermission: viper.GetString("access_default_permission"),
+				RateLimitBudget:         viper.GetDuration("rate_limit_budget"),
+				RESTCache:               restCacheConfig(),
+				ContentCache:            contentCacheConfig(),
+				Tracing:                 tracingConfig(),
+				UserEmailCheck:          userEmailCheck,
+				EnabledToolsets:         enabledToolsets,
+				DynamicToolsets:         viper.GetBool("dynamic_toolsets"),
+				ReadOnly:                viper.GetBool("read-only"),
+				ExportTranslations:      viper.GetBool("export-translations"),
+				EnableCommandLogging:    viper.GetBool("enable-command-logging"),
+				LogFilePath:             viper.GetString("log-file"),
+				ContentWindowSize:       viper.GetInt("content-window-size"),
 			}
+			return ghmcp.RunStdioServer(stdioServerConfig)
+		},
+	}
 
-			// Step 3: Create and initialize validator
-			validator := access.NewValidator(userEmail)
-			
-			if err := validator.Initialize(); err != nil {
-				return fmt.Errorf("failed to initialize validator: %w", err)
+	httpCmd = &cobra.Command{
+		Use:   "http",
+		Short: "Start streamable HTTP server",
+		Long:  `Start a server that serves MCP over streamable HTTP to several users. Every request carries its own GitHub token in the Authorization header and its user email in the ` + ghmcp.UserEmailHeader + ` header.`,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			// If you're wondering why we're not using viper.GetStringSlice("toolsets"),
+			// it's because viper doesn't handle comma-separated values correctly for env
+			// vars when using GetStringSlice.
+			// https://github.com/spf13/viper/issues/380
+			var enabledToolsets []string
+			if err := viper.UnmarshalKey("toolsets", &enabledToolsets); err != nil {
+				return fmt.Errorf("failed to unmarshal toolsets: %w", err)
 			}
 
-			// Step 4: Check repository access
-			hasAccess, err := validator.IsRepositoryAccessible(repoURL)
+			failureMode, err := access.ParseFailureMode(viper.GetString("access_failure_mode"))
 			if err != nil {
-				return fmt.Errorf("failed to validate repository access: %w", err)
+				return err
 			}
 
-			// Step 5: Output result
-			if hasAccess {
-				fmt.Printf("{\"hasAccess\": true, \"request\": %v, \"response\": %v}", validator.GetStoredRequest(), validator.GetStoredResponse())
-			} else {
-				fmt.Printf("{\"hasAccess\": false, \"request\": %v, \"response\": %v}", validator.GetStoredRequest(), validator.GetStoredResponse())
+			userEmailCheck, err := ghmcp.ParseUserEmailCheck(viper.GetString("user_email_check"))
+			if err != nil {
+				return err
 			}
 
-			return nil
+			httpServerConfig := ghmcp.HTTPServerConfig{
+				Version:                 version,
+				Host:                    viper.GetString("host"),
+				Endpoints:               apiEndpoints(),
+				ListenAddr:              viper.GetString("http_listen"),
+				EndpointPath:            viper.GetString("http_endpoint_path"),
+				SessionIdleTimeout:      viper.GetDuration("http_session_idle_timeout"),
+				AccessProvider:          viper.GetString("access_provider"),
+				AccessPolicyFile:        viper.GetString("access_policy_file"),
+				AccessOverrideFile:      viper.GetString("access_override_file"),
+				ResourceMap:             resourceMapConfig(),
+				AccessStartup:           accessStartupConfig(failureMode),
+				AuditLog:                auditLogConfig(),
+				AccessRefreshInterval:   viper.GetDuration("access_refresh_interval"),
+				AccessDefaultPermission: viper.GetString("access_default_permission"),
+				RateLimitBudget:         viper.GetDuration("rate_limit_budget"),
+				RESTCache:               restCacheConfig(),
+				ContentCache:            contentCacheConfig(),
+				Tracing:                 tracingConfig(),
+				UserEmailCheck:          userEmailCheck,
+				EnabledToolsets:         enabledToolsets,
+				DynamicToolsets:         viper.GetBool("dynamic_toolsets"),
+				ReadOnly:                viper.GetBool("read-only"),
+				ExportTranslations:      viper.GetBool("export-translations"),
+				LogFilePath:             viper.GetString("log-file"),
+				ContentWindowSize:       viper.GetInt("content-window-size"),
+			}
+			return ghmcp.RunHTTPServer(httpServerConfig)
 		},
 	}
 )
@@ -139,14 +206,46 @@ func init() {
 	rootCmd.PersistentFlags().Bool("enable-command-logging", false, "When enabled, the server will log all command requests and responses to the log file")
 	rootCmd.PersistentFlags().Bool("export-translations", false, "Save translations to a JSON file")
 	rootCmd.PersistentFlags().String("gh-host", "", "Specify the GitHub hostname (for GitHub Enterprise etc.)")
+	rootCmd.PersistentFlags().String("rest-url", "", "Override the REST API root derived from --gh-host (e.g. a local mock of the API)")
+	rootCmd.PersistentFlags().String("graphql-url", "", "Override the GraphQL endpoint derived from --gh-host")
+	rootCmd.PersistentFlags().String("upload-url", "", "Override the uploads API root derived from --gh-host")
+	rootCmd.PersistentFlags().String("raw-url", "", "Override the root of raw file contents derived from --gh-host")
 	rootCmd.PersistentFlags().String("user-email", "", "User email for repository access validation (fallback: GITHUB_USER_EMAIL env var)")
+	rootCmd.PersistentFlags().String("user-email-check", string(ghmcp.UserEmailCheckEnforce), "What to do if the user email is not a verified email address of the token owner: enforce (refuse to start), read-only (read-only tools only) or off")
 	rootCmd.PersistentFlags().Int("content-window-size", 5000, "Specify the content window size")
-
-	// Add command-specific flags for validate-access command
-	validateAccessCmd.Flags().String("user-email", "", "User email for repository access validation (required)")
-	validateAccessCmd.Flags().String("repo-url", "", "Repository URL to validate access for (required)")
-	validateAccessCmd.MarkFlagRequired("user-email")
-	validateAccessCmd.MarkFlagRequired("repo-url")
+	rootCmd.PersistentFlags().String("access-provider", access.ProviderResourceMap, "Source of repository access: resource-map, policy-file or chain (resource-map falling back to the policy file)")
+	rootCmd.PersistentFlags().String("access-policy-file", "", "Path to a YAML or JSON access policy file, used by the policy-file and chain providers")
+	rootCmd.PersistentFlags().String("access-override-file", "", "Path to a YAML or JSON file of allow and deny rules (wildcards allowed) layered on the access provider, deny rules always win")
+	rootCmd.PersistentFlags().Duration("access-refresh-interval", 30*time.Minute, "How often to refresh the accessible repositories (0 disables refreshing, SIGHUP forces a refresh)")
+	rootCmd.PersistentFlags().Duration("access-startup-timeout", access.DefaultStartupDeadline, "How long to retry loading the accessible repositories at startup (0 makes a single attempt)")
+	rootCmd.PersistentFlags().Duration("access-retry-backoff", access.DefaultInitialBackoff, "Delay before the first retry of a failed access load, doubled after each attempt")
+	rootCmd.PersistentFlags().Duration("access-retry-max-backoff", access.DefaultMaxBackoff, "Maximum delay between retries of a failed access load")
+	rootCmd.PersistentFlags().String("access-failure-mode", string(access.FailClosed), "What to do if access cannot be loaded by the startup timeout: fail-closed (exit), fail-open (allow every repository) or degraded (read-only tools until the server is restarted, every repository readable until access loads)")
+	rootCmd.PersistentFlags().Bool("access-cache", true, "Cache the accessible repositories on disk to start without waiting for the access provider")
+	rootCmd.PersistentFlags().String("access-cache-file", "", "Path of the access cache (defaults to a per-user file under the user config directory)")
+	rootCmd.PersistentFlags().Duration("access-cache-max-age", access.DefaultCacheMaxAge, "How old the access cache may be to start from it without fetching first (older caches are only used if the provider is down)")
+	rootCmd.PersistentFlags().String("audit-log", "", "Write a JSON Lines audit record of every tool call to this file, or to stderr (stdout is only allowed for non-stdio servers)")
+	rootCmd.PersistentFlags().Int64("audit-log-max-size", 100, "Size in megabytes at which the audit log file is rotated (0 disables rotation)")
+	rootCmd.PersistentFlags().Int("audit-log-max-backups", 5, "Number of rotated audit log files to keep")
+	rootCmd.PersistentFlags().String("resource-map-endpoint", access.DefaultResourceMapEndpoint, "host:port of the resource-map service")
+	rootCmd.PersistentFlags().String("resource-map-authority", "", "Authority header sent to the resource-map service (empty uses the mesh authority for the default endpoint and the endpoint host otherwise)")
+	rootCmd.PersistentFlags().Bool("resource-map-tls", false, "Connect to the resource-map service over TLS")
+	rootCmd.PersistentFlags().String("resource-map-ca-file", "", "PEM bundle of root CAs used to verify the resource-map service instead of the system roots")
+	rootCmd.PersistentFlags().String("resource-map-cert-file", "", "PEM client certificate for mutual TLS with the resource-map service")
+	rootCmd.PersistentFlags().String("resource-map-key-file", "", "PEM client key for mutual TLS with the resource-map service")
+	rootCmd.PersistentFlags().Duration("resource-map-dial-timeout", access.DefaultResourceMapDialTimeout, "Timeout for establishing the connection to the resource-map service")
+	rootCmd.PersistentFlags().Duration("resource-map-request-timeout", access.DefaultResourceMapRequestTimeout, "Timeout for each resource-map request (0 disables the timeout)")
+	rootCmd.PersistentFlags().Duration("rate-limit-budget", ratelimit.DefaultBudget, "How long a tool call may wait for GitHub rate limits and retries of transient errors (0 returns them right away)")
+	rootCmd.PersistentFlags().Int64("rest-cache-size", httpcache.DefaultMaxBytes/(1024*1024), "Size in megabytes of the cache of REST responses revalidated with conditional requests (0 disables the cache)")
+	rootCmd.PersistentFlags().String("rest-cache-dir", "", "Directory persisting the REST cache across restarts (empty keeps it in memory only)")
+	rootCmd.PersistentFlags().Int64("content-cache-size", raw.DefaultCacheMaxBytes/(1024*1024), "Size in megabytes of the cache of file content and trees read at a full commit SHA (0 disables the cache)")
+	rootCmd.PersistentFlags().String("content-cache-dir", "", "Directory persisting the content cache across restarts (empty keeps it in memory only)")
+	rootCmd.PersistentFlags().String("trace-exporter", tracing.ExporterNone, "Where to export OpenTelemetry traces of tool calls and GitHub requests: none, otlp, stdout (http only) or file")
+	rootCmd.PersistentFlags().String("trace-endpoint", "", "Host:port of the OTLP/gRPC collector (defaults to OTEL_EXPORTER_OTLP_ENDPOINT or localhost:4317)")
+	rootCmd.PersistentFlags().Bool("trace-insecure", false, "Connect to the OTLP collector without TLS")
+	rootCmd.PersistentFlags().String("trace-file", "", "File the file trace exporter appends spans to as JSON")
+	rootCmd.PersistentFlags().String("token-file", "", "Path of the token stored by login (defaults to a per-host file under the user config directory)")
+	rootCmd.PersistentFlags().String("access-default-permission", "read", "Permission assumed for repositories whose access provider does not report one: read, triage, write or admin")
 
 	// Bind flag to viper
 	_ = viper.BindPFlag("toolsets", rootCmd.PersistentFlags().Lookup("toolsets"))
@@ -156,12 +255,66 @@ func init() {
 	_ = viper.BindPFlag("enable-command-logging", rootCmd.PersistentFlags().Lookup("enable-command-logging"))
 	_ = viper.BindPFlag("export-translations", rootCmd.PersistentFlags().Lookup("export-translations"))
 	_ = viper.BindPFlag("host", rootCmd.PersistentFlags().Lookup("gh-host"))
+	_ = viper.BindPFlag("rest_url", rootCmd.PersistentFlags().Lookup("rest-url"))
+	_ = viper.BindPFlag("graphql_url", rootCmd.PersistentFlags().Lookup("graphql-url"))
+	_ = viper.BindPFlag("upload_url", rootCmd.PersistentFlags().Lookup("upload-url"))
+	_ = viper.BindPFlag("raw_url", rootCmd.PersistentFlags().Lookup("raw-url"))
 	_ = viper.BindPFlag("user_email", rootCmd.PersistentFlags().Lookup("user-email"))
+	_ = viper.BindPFlag("user_email_check", rootCmd.PersistentFlags().Lookup("user-email-check"))
 	_ = viper.BindPFlag("content-window-size", rootCmd.PersistentFlags().Lookup("content-window-size"))
+	_ = viper.BindPFlag("access_provider", rootCmd.PersistentFlags().Lookup("access-provider"))
+	_ = viper.BindPFlag("access_policy_file", rootCmd.PersistentFlags().Lookup("access-policy-file"))
+	_ = viper.BindPFlag("access_override_file", rootCmd.PersistentFlags().Lookup("access-override-file"))
+	_ = viper.BindPFlag("access_refresh_interval", rootCmd.PersistentFlags().Lookup("access-refresh-interval"))
+	_ = viper.BindPFlag("access_default_permission", rootCmd.PersistentFlags().Lookup("access-default-permission"))
+	_ = viper.BindPFlag("access_startup_timeout", rootCmd.PersistentFlags().Lookup("access-startup-timeout"))
+	_ = viper.BindPFlag("access_retry_backoff", rootCmd.PersistentFlags().Lookup("access-retry-backoff"))
+	_ = viper.BindPFlag("access_retry_max_backoff", rootCmd.PersistentFlags().Lookup("access-retry-max-backoff"))
+	_ = viper.BindPFlag("access_failure_mode", rootCmd.PersistentFlags().Lookup("access-failure-mode"))
+	_ = viper.BindPFlag("access_cache", rootCmd.PersistentFlags().Lookup("access-cache"))
+	_ = viper.BindPFlag("access_cache_file", rootCmd.PersistentFlags().Lookup("access-cache-file"))
+	_ = viper.BindPFlag("access_cache_max_age", rootCmd.PersistentFlags().Lookup("access-cache-max-age"))
+	_ = viper.BindPFlag("audit_log", rootCmd.PersistentFlags().Lookup("audit-log"))
+	_ = viper.BindPFlag("audit_log_max_size", rootCmd.PersistentFlags().Lookup("audit-log-max-size"))
+	_ = viper.BindPFlag("audit_log_max_backups", rootCmd.PersistentFlags().Lookup("audit-log-max-backups"))
+	_ = viper.BindPFlag("resource_map_endpoint", rootCmd.PersistentFlags().Lookup("resource-map-endpoint"))
+	_ = viper.BindPFlag("resource_map_authority", rootCmd.PersistentFlags().Lookup("resource-map-authority"))
+	_ = viper.BindPFlag("resource_map_tls", rootCmd.PersistentFlags().Lookup("resource-map-tls"))
+	_ = viper.BindPFlag("resource_map_ca_file", rootCmd.PersistentFlags().Lookup("resource-map-ca-file"))
+	_ = viper.BindPFlag("resource_map_cert_file", rootCmd.PersistentFlags().Lookup("resource-map-cert-file"))
+	_ = viper.BindPFlag("resource_map_key_file", rootCmd.PersistentFlags().Lookup("resource-map-key-file"))
+	_ = viper.BindPFlag("resource_map_dial_timeout", rootCmd.PersistentFlags().Lookup("resource-map-dial-timeout"))
+	_ = viper.BindPFlag("resource_map_request_timeout", rootCmd.PersistentFlags().Lookup("resource-map-request-timeout"))
+	_ = viper.BindPFlag("rate_limit_budget", rootCmd.PersistentFlags().Lookup("rate-limit-budget"))
+	_ = viper.BindPFlag("rest_cache_size", rootCmd.PersistentFlags().Lookup("rest-cache-size"))
+	_ = viper.BindPFlag("rest_cache_dir", rootCmd.PersistentFlags().Lookup("rest-cache-dir"))
+	_ = viper.BindPFlag("content_cache_size", rootCmd.PersistentFlags().Lookup("content-cache-size"))
+	_ = viper.BindPFlag("content_cache_dir", rootCmd.PersistentFlags().Lookup("content-cache-dir"))
+	_ = viper.BindPFlag("trace_exporter", rootCmd.PersistentFlags().Lookup("trace-exporter"))
+	_ = viper.BindPFlag("trace_endpoint", rootCmd.PersistentFlags().Lookup("trace-endpoint"))
+	_ = viper.BindPFlag("trace_insecure", rootCmd.PersistentFlags().Lookup("trace-insecure"))
+	_ = viper.BindPFlag("trace_file", rootCmd.PersistentFlags().Lookup("trace-file"))
+	_ = viper.BindPFlag("token_file", rootCmd.PersistentFlags().Lookup("token-file"))
+
+	// Add flags of the stdio command
+	stdioCmd.Flags().Int64("app-id", 0, "Authenticate as this GitHub App instead of with a personal access token")
+	stdioCmd.Flags().Int64("app-installation-id", 0, "Installation of the GitHub App to authenticate as")
+	stdioCmd.Flags().String("app-private-key-file", "", "Path to the PEM private key of the GitHub App")
+	_ = viper.BindPFlag("app_id", stdioCmd.Flags().Lookup("app-id"))
+	_ = viper.BindPFlag("app_installation_id", stdioCmd.Flags().Lookup("app-installation-id"))
+	_ = viper.BindPFlag("app_private_key_file", stdioCmd.Flags().Lookup("app-private-key-file"))
+
+	// Add flags of the http command
+	httpCmd.Flags().String("listen", "127.0.0.1:8080", "Address (host:port) the streamable HTTP server listens on")
+	httpCmd.Flags().String("endpoint-path", ghmcp.DefaultHTTPEndpointPath, "Path the streamable HTTP server serves MCP on")
+	httpCmd.Flags().Duration("session-idle-timeout", ghmcp.DefaultSessionIdleTimeout, "How long a session may go without a request before it is closed together with its access validator")
+	_ = viper.BindPFlag("http_listen", httpCmd.Flags().Lookup("listen"))
+	_ = viper.BindPFlag("http_endpoint_path", httpCmd.Flags().Lookup("endpoint-path"))
+	_ = viper.BindPFlag("http_session_idle_timeout", httpCmd.Flags().Lookup("session-idle-timeout"))
 
 	// Add subcommands
 	rootCmd.AddCommand(stdioCmd)
-	rootCmd.AddCommand(validateAccessCmd)
+	rootCmd.AddCommand(httpCmd)
 }
 
 func initConfig() {
@@ -171,8 +324,96 @@ func initConfig() {
 
 }
 
+// apiEndpoints reads the API endpoint overrides from flags and env vars
+func apiEndpoints() ghmcp.APIEndpoints {
+	return ghmcp.APIEndpoints{
+		RESTURL:    viper.GetString("rest_url"),
+		GraphQLURL: viper.GetString("graphql_url"),
+		UploadURL:  viper.GetString("upload_url"),
+		RawURL:     viper.GetString("raw_url"),
+	}
+}
+
+// resourceMapConfig reads the resource-map connection settings from flags and GITHUB_RESOURCE_MAP_* env vars
+func resourceMapConfig() access.ResourceMapConfig {
+	return access.ResourceMapConfig{
+		Endpoint:       viper.GetString("resource_map_endpoint"),
+		Authority:      viper.GetString("resource_map_authority"),
+		TLS:            viper.GetBool("resource_map_tls"),
+		CAFile:         viper.GetString("resource_map_ca_file"),
+		CertFile:       viper.GetString("resource_map_cert_file"),
+		KeyFile:        viper.GetString("resource_map_key_file"),
+		DialTimeout:    viper.GetDuration("resource_map_dial_timeout"),
+		RequestTimeout: viper.GetDuration("resource_map_request_timeout"),
+	}
+}
+
+// accessStartupConfig reads the startup retry settings from flags and env vars
+func accessStartupConfig(mode access.FailureMode) access.StartupConfig {
+	return access.StartupConfig{
+		Deadline:       viper.GetDuration("access_startup_timeout"),
+		InitialBackoff: viper.GetDuration("access_retry_backoff"),
+		MaxBackoff:     viper.GetDuration("access_retry_max_backoff"),
+		Mode:           mode,
+	}
+}
+
+// accessCacheFile returns the access cache path for the user, or an empty path if the cache is disabled
+func accessCacheFile(userEmail string) (string, error) {
+	if !viper.GetBool("access_cache") {
+		return "", nil
+	}
+	if path := viper.GetString("access_cache_file"); path != "" {
+		return path, nil
+	}
+	path, err := access.DefaultCachePath(userEmail)
+	if err != nil {
+		return "", fmt.Errorf("failed to locate access cache, set --access-cache-file or --access-cache=false: %w", err)
+	}
+	return path, nil
+}
+
+// restCacheConfig reads the REST cache settings from flags and env vars
+func restCacheConfig() httpcache.Config {
+	return httpcache.Config{
+		MaxBytes: viper.GetInt64("rest_cache_size") * 1024 * 1024,
+		Dir:      viper.GetString("rest_cache_dir"),
+	}
+}
+
+// contentCacheConfig reads the content cache settings from flags and env vars
+func contentCacheConfig() raw.CacheConfig {
+	return raw.CacheConfig{
+		MaxBytes: viper.GetInt64("content_cache_size") * 1024 * 1024,
+		Dir:      viper.GetString("content_cache_dir"),
+	}
+}
+
+// tracingConfig reads the trace exporter settings from flags and env vars
+func tracingConfig() tracing.Config {
+	return tracing.Config{
+		Exporter: viper.GetString("trace_exporter"),
+		Endpoint: viper.GetString("trace_endpoint"),
+		Insecure: viper.GetBool("trace_insecure"),
+		File:     viper.GetString("trace_file"),
+	}
+}
+
+// auditLogConfig reads the audit log settings from flags and env vars
+func auditLogConfig() audit.Config {
+	return audit.Config{
+		Path:       viper.GetString("audit_log"),
+		MaxSize:    viper.GetInt64("audit_log_max_size") * 1024 * 1024,
+		MaxBackups: viper.GetInt("audit_log_max_backups"),
+	}
+}
+
 func main() {
 	if err := rootCmd.Execute(); err != nil {
+		var exitErr *exitCodeError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.code)
+		}
 		fmt.Fprintf(os.Stderr, "%v\n", err)
 		os.Exit(1)
 	}
@@ -0,0 +1,336 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/github/github-mcp-server/pkg/access"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// Exit codes of the validate-access command, besides 0 when every repository is accessible
+// and 1 when the command itself fails
+const (
+	exitCodeDenied  = 2 // at least one repository is not accessible
+	exitCodeInvalid = 3 // at least one input is not a valid repository URL
+)
+
+// Output formats of the validate-access command
+const (
+	outputJSON = "json"
+	outputCSV  = "csv"
+)
+
+var validateAccessCmd = &cobra.Command{
+	Use:   "validate-access",
+	Short: "Validate repository access for a user",
+	Long: `Validate whether a user has access to repositories based on their email and the repository URLs.
+
+Repositories are passed with --repo-url (repeatable) and/or --from-file, one per line ("-" reads stdin).
+--list prints every repository accessible to the user instead, followed by the wildcard
+patterns granting access to whole orgs or name ranges.
+
+Exit codes: 0 when every repository is accessible, 1 when the command fails, 2 when at least one
+repository is not accessible, and 3 when at least one input is not a valid repository URL.`,
+	RunE: runValidateAccess,
+}
+
+func init() {
+	validateAccessCmd.Flags().String("user-email", "", "User email for repository access validation (required)")
+	validateAccessCmd.Flags().StringArray("repo-url", nil, "Repository URL to validate access for, can be repeated")
+	validateAccessCmd.Flags().String("from-file", "", "File with one repository URL per line to validate access for, - reads stdin")
+	validateAccessCmd.Flags().Bool("list", false, "List every repository accessible to the user instead of validating repositories")
+	validateAccessCmd.Flags().String("output", outputJSON, "Output format: json or csv")
+	validateAccessCmd.Flags().Bool("include-exchange", false, "Include the raw access provider request and response in the JSON output")
+	_ = validateAccessCmd.MarkFlagRequired("user-email")
+	validateAccessCmd.MarkFlagsMutuallyExclusive("list", "repo-url")
+	validateAccessCmd.MarkFlagsMutuallyExclusive("list", "from-file")
+	validateAccessCmd.MarkFlagsOneRequired("list", "repo-url", "from-file")
+
+	rootCmd.AddCommand(validateAccessCmd)
+}
+
+// exitCodeError makes the process exit with code without printing anything further,
+// for outcomes that are already reported on stdout
+type exitCodeError struct {
+	code int
+}
+
+func (e *exitCodeError) Error() string {
+	return fmt.Sprintf("exit code %d", e.code)
+}
+
+// accessReport is the JSON output of the validate-access command
+type accessReport struct {
+	UserEmail string         `json:"user_email"`
+	Results   []accessResult `json:"results"`
+	// Request and Response are the raw provider exchange, only set with --include-exchange.
+	// The resource-map provider reports arrays of its traversal calls.
+	Request  json.RawMessage `json:"request,omitempty"`
+	Response json.RawMessage `json:"response,omitempty"`
+}
+
+// accessResult is the access decision for a single repository
+type accessResult struct {
+	// Input is the repository as given, empty in list mode
+	Input      string `json:"input,omitempty"`
+	Repository string `json:"repository"`
+	HasAccess  bool   `json:"has_access"`
+	Permission string `json:"permission"`
+	Reason     string `json:"reason,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+func runValidateAccess(cmd *cobra.Command, _ []string) error {
+	flags := cmd.Flags()
+
+	// Step 1: Extract and validate flags
+	userEmail, err := flags.GetString("user-email")
+	if err != nil {
+		return fmt.Errorf("failed to get user-email flag: %w", err)
+	}
+	if userEmail == "" {
+		return errors.New("user-email is required")
+	}
+	list, _ := flags.GetBool("list")
+	output, _ := flags.GetString("output")
+	if output != outputJSON && output != outputCSV {
+		return fmt.Errorf("unknown output format %q (expected %s or %s)", output, outputJSON, outputCSV)
+	}
+	includeExchange, _ := flags.GetBool("include-exchange")
+
+	// Step 2: Collect the repositories to validate
+	var repoURLs []string
+	if !list {
+		repoURLs, err = flags.GetStringArray("repo-url")
+		if err != nil {
+			return fmt.Errorf("failed to get repo-url flag: %w", err)
+		}
+		fromFile, _ := flags.GetString("from-file")
+		if fromFile != "" {
+			fileURLs, err := readRepositoryURLs(fromFile, cmd.InOrStdin())
+			if err != nil {
+				return err
+			}
+			repoURLs = append(repoURLs, fileURLs...)
+		}
+		if len(repoURLs) == 0 {
+			return errors.New("no repository URLs to validate")
+		}
+	}
+
+	// Step 3: Create and initialize validator
+	provider, err := access.NewProvider(viper.GetString("access_provider"), viper.GetString("access_policy_file"), resourceMapConfig())
+	if err != nil {
+		return fmt.Errorf("failed to create access provider: %w", err)
+	}
+	validator := access.NewValidatorWithProvider(userEmail, provider)
+	defer func() { _ = validator.Close() }()
+	defaultPermission, err := access.ParsePermission(viper.GetString("access_default_permission"))
+	if err != nil {
+		return fmt.Errorf("invalid default access permission: %w", err)
+	}
+	validator.SetDefaultPermission(defaultPermission)
+	validator.SetOverrideFile(viper.GetString("access_override_file"))
+	if err := validator.SetHost(viper.GetString("host")); err != nil {
+		return err
+	}
+
+	if err := validator.Start(cmd.Context(), accessStartupConfig(access.FailClosed)); err != nil {
+		return fmt.Errorf("failed to initialize validator: %w", err)
+	}
+
+	// Step 4: Check repository access
+	var results []accessResult
+	if list {
+		results = listAccessibleRepositories(validator)
+	} else {
+		results = make([]accessResult, 0, len(repoURLs))
+		for _, repoURL := range repoURLs {
+			results = append(results, checkRepositoryAccess(validator, repoURL))
+		}
+	}
+
+	// Step 5: Output results
+	out := cmd.OutOrStdout()
+	switch output {
+	case outputCSV:
+		err = writeAccessCSV(out, results)
+	default:
+		report := accessReport{UserEmail: userEmail, Results: results}
+		if includeExchange {
+			report.Request = rawJSON(validator.GetStoredRequest())
+			report.Response = rawJSON(validator.GetStoredResponse())
+		}
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(report)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write results: %w", err)
+	}
+
+	if code := accessExitCode(results); code != 0 {
+		cmd.SilenceErrors = true
+		cmd.SilenceUsage = true
+		return &exitCodeError{code: code}
+	}
+	return nil
+}
+
+// readRepositoryURLs reads one repository URL per line from path, or from stdin if path is "-".
+// Blank lines and lines starting with # are skipped.
+func readRepositoryURLs(path string, stdin io.Reader) ([]string, error) {
+	r := stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open repository list: %w", err)
+		}
+		defer func() { _ = file.Close() }()
+		r = file
+	}
+
+	var repoURLs []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		repoURLs = append(repoURLs, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read repository list: %w", err)
+	}
+	return repoURLs, nil
+}
+
+// checkRepositoryAccess returns the access decision for a single repository URL
+func checkRepositoryAccess(validator *access.Validator, repoURL string) accessResult {
+	result := accessResult{Input: repoURL, Permission: access.PermissionNone.String()}
+
+	normalizedURL, err := validator.NormalizeRepositoryURL(repoURL)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Repository = normalizedURL
+
+	permission, err := validator.RepositoryPermission(repoURL)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	teams, err := validator.GrantingTeams(repoURL)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	rule, err := validator.DenyingRule(repoURL)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	result.HasAccess = permission != access.PermissionNone
+	result.Permission = permission.String()
+	switch {
+	case result.HasAccess:
+		result.Reason = access.DescribeGrant(teams)
+	case rule != "":
+		result.Reason = fmt.Sprintf("repository is denied by access rule %s", rule)
+	default:
+		result.Reason = "repository is not accessible to the user"
+	}
+	return result
+}
+
+// listAccessibleRepositories returns every accessible repository, sorted by URL, followed by
+// the wildcard grants
+func listAccessibleRepositories(validator *access.Validator) []accessResult {
+	permissions := validator.GetRepositoryPermissions()
+	repoURLs := make([]string, 0, len(permissions))
+	for repoURL := range permissions {
+		repoURLs = append(repoURLs, repoURL)
+	}
+	sort.Strings(repoURLs)
+
+	results := make([]accessResult, 0, len(repoURLs))
+	for _, repoURL := range repoURLs {
+		teams, _ := validator.GrantingTeams(repoURL)
+		results = append(results, accessResult{
+			Repository: repoURL,
+			HasAccess:  true,
+			Permission: permissions[repoURL].String(),
+			Reason:     access.DescribeGrant(teams),
+		})
+	}
+
+	// Wildcard grants cover repositories that cannot be enumerated, so they are listed as patterns
+	patterns := validator.GetGrantPatterns()
+	sorted := make([]string, 0, len(patterns))
+	for pattern := range patterns {
+		sorted = append(sorted, pattern)
+	}
+	sort.Strings(sorted)
+	for _, pattern := range sorted {
+		results = append(results, accessResult{
+			Repository: pattern,
+			HasAccess:  true,
+			Permission: patterns[pattern].String(),
+			Reason:     "every repository matching the pattern is accessible unless a deny rule matches it",
+		})
+	}
+	return results
+}
+
+// writeAccessCSV writes the results as CSV with a header row, using the JSON field names as columns
+func writeAccessCSV(w io.Writer, results []accessResult) error {
+	writer := csv.NewWriter(w)
+	_ = writer.Write([]string{"input", "repository", "has_access", "permission", "reason", "error"})
+	for _, result := range results {
+		_ = writer.Write([]string{
+			result.Input,
+			result.Repository,
+			strconv.FormatBool(result.HasAccess),
+			result.Permission,
+			result.Reason,
+			result.Error,
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// accessExitCode returns the exit code for the results, invalid inputs taking precedence over denials
+func accessExitCode(results []accessResult) int {
+	code := 0
+	for _, result := range results {
+		switch {
+		case result.Error != "":
+			return exitCodeInvalid
+		case !result.HasAccess:
+			code = exitCodeDenied
+		}
+	}
+	return code
+}
+
+// rawJSON returns s as raw JSON, or nil if it is empty or not valid JSON
+func rawJSON(s string) json.RawMessage {
+	if s == "" || !json.Valid([]byte(s)) {
+		return nil
+	}
+	return json.RawMessage(s)
+}
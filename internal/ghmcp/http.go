@@ -0,0 +1,739 @@
+package ghmcp
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/github/github-mcp-server/pkg/access"
+	"github.com/github/github-mcp-server/pkg/audit"
+	"github.com/github/github-mcp-server/pkg/httpcache"
+	"github.com/github/github-mcp-server/pkg/ratelimit"
+	"github.com/github/github-mcp-server/pkg/raw"
+	"github.com/github/github-mcp-server/pkg/toolsets"
+	"github.com/github/github-mcp-server/pkg/tracing"
+	"github.com/github/github-mcp-server/pkg/translations"
+	gogithub "github.com/google/go-github/v74/github"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/shurcooL/githubv4"
+)
+
+// UserEmailHeader carries the email of the user a streamable HTTP request is made for.
+// It must be a verified email address of the owner of the token in the Authorization header.
+const UserEmailHeader = "X-GitHub-User-Email"
+
+const (
+	// DefaultHTTPEndpointPath is the path the streamable HTTP server serves MCP on
+	DefaultHTTPEndpointPath = "/mcp"
+
+	// DefaultSessionIdleTimeout is how long a streamable HTTP session may go without a request
+	// before it is closed together with its access validator
+	DefaultSessionIdleTimeout = time.Hour
+)
+
+type HTTPServerConfig struct {
+	// Version of the server
+	Version string
+
+	// GitHub Host to target for API requests (e.g. github.com or github.enterprise.com)
+	Host string
+
+	// Endpoints overrides the API endpoints derived from Host
+	Endpoints APIEndpoints
+
+	// ListenAddr is the host:port to listen on
+	ListenAddr string
+
+	// EndpointPath is the path MCP is served on, defaults to DefaultHTTPEndpointPath
+	EndpointPath string
+
+	// SessionIdleTimeout is how long a session may go without a request before it is closed,
+	// defaults to DefaultSessionIdleTimeout
+	SessionIdleTimeout time.Duration
+
+	// AccessProvider selects the source of repository access (resource-map, policy-file or chain)
+	AccessProvider string
+
+	// AccessPolicyFile is the path to a local access policy file
+	AccessPolicyFile string
+
+	// AccessOverrideFile is the path to a file of allow and deny rules layered on the access provider
+	AccessOverrideFile string
+
+	// ResourceMap configures the connection to the resource-map service
+	ResourceMap access.ResourceMapConfig
+
+	// AccessRefreshInterval is how often the accessible repositories of each session are refetched,
+	// zero disables it
+	AccessRefreshInterval time.Duration
+
+	// AccessStartup controls retries, the deadline and the failure mode of the access load of each
+	// session. The degraded mode is refused because the tools are shared by every session.
+	AccessStartup access.StartupConfig
+
+	// AuditLog configures the audit log, an empty path disables it
+	AuditLog audit.Config
+
+	// Tracing selects where the spans of MCP requests and GitHub requests are exported
+	Tracing tracing.Config
+
+	// AccessDefaultPermission is the permission (read, triage, write or admin) assumed for
+	// repositories whose provider does not report one, defaults to read
+	AccessDefaultPermission string
+
+	// RateLimitBudget is how long a tool call may wait for GitHub rate limits and retries of
+	// transient errors, zero returns them to the model right away
+	RateLimitBudget time.Duration
+
+	// RESTCache sizes the cache of REST responses and its optional disk backing, a zero size
+	// disables it. Responses are cached per token, so users never see each other's responses.
+	RESTCache httpcache.Config
+
+	// ContentCache sizes the cache of content read at a full commit SHA and its optional disk
+	// backing, a zero size disables it. Content is cached per token, like REST responses.
+	ContentCache raw.CacheConfig
+
+	// UserEmailCheck decides what happens if the user email of a session is not a verified email
+	// address of the token owner. Only enforce and off are supported, as the tools are shared by
+	// every session.
+	UserEmailCheck UserEmailCheck
+
+	// EnabledToolsets is a list of toolsets to enable
+	// See: https://github.com/github/github-mcp-server?tab=readme-ov-file#tool-configuration
+	EnabledToolsets []string
+
+	// Whether to enable dynamic toolsets
+	// See: https://github.com/github/github-mcp-server?tab=readme-ov-file#dynamic-tool-discovery
+	DynamicToolsets bool
+
+	// ReadOnly indicates if we should only register read-only tools
+	ReadOnly bool
+
+	// ExportTranslations indicates if we should export translations
+	// See: https://github.com/github/github-mcp-server?tab=readme-ov-file#i18n--overriding-descriptions
+	ExportTranslations bool
+
+	// Path to the log file if not stderr
+	LogFilePath string
+
+	// Content window size
+	ContentWindowSize int
+}
+
+// RunHTTPServer serves MCP over streamable HTTP until the process is interrupted. Every request
+// carries the GitHub token and user email it is made for, and every session gets the access
+// validator of its user.
+func RunHTTPServer(cfg HTTPServerConfig) error {
+	// Create app context
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if cfg.UserEmailCheck == UserEmailCheckReadOnly {
+		return fmt.Errorf("the %s user email check is not supported by the http server, use %s or %s", UserEmailCheckReadOnly, UserEmailCheckEnforce, UserEmailCheckOff)
+	}
+	if cfg.AccessStartup.Mode == access.FailDegraded {
+		return fmt.Errorf("the %s access failure mode is not supported by the http server, use %s or %s", access.FailDegraded, access.FailClosed, access.FailOpen)
+	}
+
+	t, dumpTranslations := translations.TranslationHelper()
+
+	var slogHandler slog.Handler
+	if cfg.LogFilePath != "" {
+		file, err := os.OpenFile(cfg.LogFilePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			return fmt.Errorf("failed to open log file: %w", err)
+		}
+		slogHandler = slog.NewTextHandler(file, &slog.HandlerOptions{Level: slog.LevelDebug})
+	} else {
+		slogHandler = slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelInfo})
+	}
+	logger := slog.New(slogHandler)
+
+	stopTracing, err := startTracing(ctx, cfg.Tracing, cfg.Version, logger)
+	if err != nil {
+		return err
+	}
+	defer stopTracing()
+
+	var defaultPermission access.Permission
+	if cfg.AccessDefaultPermission != "" {
+		var err error
+		defaultPermission, err = access.ParsePermission(cfg.AccessDefaultPermission)
+		if err != nil {
+			return fmt.Errorf("invalid default access permission: %w", err)
+		}
+	}
+
+	var auditLogger *audit.Logger
+	if cfg.AuditLog.Path != "" {
+		var err error
+		auditLogger, err = audit.Open(cfg.AuditLog)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = auditLogger.Close() }()
+		auditLogger.SetErrorLogger(logger)
+	}
+
+	apiHost, err := parseAPIHost(cfg.Host, cfg.Endpoints)
+	if err != nil {
+		return fmt.Errorf("failed to parse API host: %w", err)
+	}
+
+	restCache, err := newRESTCache(cfg.RESTCache)
+	if err != nil {
+		return err
+	}
+	defer logRESTCacheStats(restCache, logger)
+
+	contentCache, err := newContentCache(cfg.ContentCache)
+	if err != nil {
+		return err
+	}
+	defer logContentCacheStats(contentCache, logger)
+
+	sessions := newHTTPSessions(func(ctx, background context.Context, identity requestIdentity) (*access.Validator, error) {
+		// Only load the repository access of the user the token belongs to
+		restClient := newRESTClient(apiHost, staticToken(identity.token), cfg.Version, http.DefaultTransport)
+		if _, err := enforceUserEmail(ctx, cfg.UserEmailCheck, restClient, identity.userEmail, logger); err != nil {
+			return nil, err
+		}
+
+		validator, err := newAccessValidator(identity.userEmail, cfg.Host, cfg.AccessProvider, cfg.AccessPolicyFile, cfg.AccessOverrideFile, cfg.ResourceMap)
+		if err != nil {
+			return nil, err
+		}
+		validator.SetRefreshInterval(cfg.AccessRefreshInterval)
+		if defaultPermission != access.PermissionNone {
+			validator.SetDefaultPermission(defaultPermission)
+		}
+		validator.SetLogger(logger.With("user", identity.userEmail))
+		// The load is retried in the background of the session, which outlives the request
+		if err := validator.StartInBackground(ctx, background, cfg.AccessStartup); err != nil {
+			_ = validator.Close()
+			return nil, fmt.Errorf("failed to initialize access validator: %w", err)
+		}
+		return validator, nil
+	}, logger)
+	defer sessions.closeAll()
+
+	ghServer, err := newHTTPMCPServer(MCPServerConfig{
+		Version:           cfg.Version,
+		Host:              cfg.Host,
+		AuditLogger:       auditLogger,
+		Logger:            logger,
+		RateLimitBudget:   cfg.RateLimitBudget,
+		RESTCache:         restCache,
+		ContentCache:      contentCache,
+		EnabledToolsets:   cfg.EnabledToolsets,
+		DynamicToolsets:   cfg.DynamicToolsets,
+		ReadOnly:          cfg.ReadOnly,
+		Translator:        t,
+		ContentWindowSize: cfg.ContentWindowSize,
+	}, apiHost, sessions)
+	if err != nil {
+		return fmt.Errorf("failed to create MCP server: %w", err)
+	}
+
+	endpointPath := cfg.EndpointPath
+	if endpointPath == "" {
+		endpointPath = DefaultHTTPEndpointPath
+	}
+	streamableServer := server.NewStreamableHTTPServer(ghServer,
+		server.WithEndpointPath(endpointPath),
+		server.WithSessionIdManager(sessions),
+		server.WithHTTPContextFunc(sessions.bind),
+	)
+	mux := http.NewServeMux()
+	mux.Handle(endpointPath, sessions.authenticate(streamableServer))
+	httpServer := &http.Server{
+		Addr:              cfg.ListenAddr,
+		Handler:           mux,
+		ReadHeaderTimeout: 30 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+
+	// Close the sessions clients stopped using, and let operators force a refresh of every
+	// session with SIGHUP
+	idleTimeout := cfg.SessionIdleTimeout
+	if idleTimeout <= 0 {
+		idleTimeout = DefaultSessionIdleTimeout
+	}
+	go sessions.expireIdle(ctx, idleTimeout)
+	watchRefreshSignal(ctx, sessions.refreshAll, logger)
+
+	if cfg.ExportTranslations {
+		// Once server is initialized, all translations are loaded
+		dumpTranslations()
+	}
+
+	listener, err := net.Listen("tcp", cfg.ListenAddr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", cfg.ListenAddr, err)
+	}
+
+	logger.Info("starting server", "version", cfg.Version, "host", cfg.Host, "addr", listener.Addr().String(), "path", endpointPath, "dynamicToolsets", cfg.DynamicToolsets, "readOnly", cfg.ReadOnly)
+	_, _ = fmt.Fprintf(os.Stderr, "GitHub MCP Server running on http://%s%s\n", listener.Addr(), endpointPath)
+
+	errC := make(chan error, 1)
+	go func() {
+		errC <- httpServer.Serve(listener)
+	}()
+
+	// Wait for shutdown signal
+	select {
+	case <-ctx.Done():
+		logger.Info("shutting down server", "signal", "context done")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := httpServer.Shutdown(shutdownCtx); err != nil {
+			_ = httpServer.Close()
+		}
+	case err := <-errC:
+		if err != nil && err != http.ErrServerClosed {
+			logger.Error("error running server", "error", err)
+			return fmt.Errorf("error running server: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// newHTTPMCPServer creates the MCP server of the streamable HTTP server. Its tools build their
+// clients from the token of each request, and validate access with the validator of its session.
+func newHTTPMCPServer(cfg MCPServerConfig, apiHost apiHost, sessions *httpSessions) (*server.MCPServer, error) {
+	// Wait out rate limits and transient errors within the budget of each tool call
+	var transport http.RoundTripper = ratelimit.NewTransport(http.DefaultTransport, cfg.RateLimitBudget, cfg.Logger)
+	toolMiddleware := []toolsets.ToolMiddleware{tracing.Middleware(), ratelimit.Middleware(cfg.RateLimitBudget)}
+
+	// Record GitHub status codes in the audit log when it is enabled
+	if cfg.AuditLogger != nil {
+		transport = &audit.Transport{Transport: transport}
+		// The user email of each request is recorded from its context
+		toolMiddleware = append(toolMiddleware, audit.Middleware(cfg.AuditLogger, ""))
+	}
+
+	// Record each GitHub request, including its retries, as a span of the tool call
+	transport = tracing.NewTransport(transport, apiHost.apiName)
+
+	// Cached responses are keyed by token, so sessions share the cache without sharing responses
+	restTransport := transport
+	if cfg.RESTCache != nil {
+		restTransport = cfg.RESTCache.Transport(transport)
+	}
+
+	getClient := func(ctx context.Context) (*gogithub.Client, error) {
+		session, identity, err := sessions.open(ctx)
+		if err != nil {
+			return nil, err
+		}
+		restClient := newRESTClient(apiHost, staticToken(identity.token), cfg.Version, restTransport)
+		if userAgent := session.getUserAgent(); userAgent != "" {
+			restClient.UserAgent = userAgent
+		}
+		return restClient, nil
+	}
+
+	getGQLClient := func(ctx context.Context) (*githubv4.Client, error) {
+		session, identity, err := sessions.open(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var gqlTransport http.RoundTripper = &bearerAuthTransport{
+			transport: transport,
+			tokens:    staticToken(identity.token),
+		}
+		if userAgent := session.getUserAgent(); userAgent != "" {
+			gqlTransport = &userAgentTransport{transport: gqlTransport, agent: userAgent}
+		}
+		return githubv4.NewEnterpriseClient(apiHost.graphqlURL.String(), &http.Client{Transport: gqlTransport}), nil
+	}
+
+	getRawClient := func(ctx context.Context) (*raw.Client, error) {
+		client, err := getClient(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get GitHub client: %w", err)
+		}
+		rawClient := raw.NewClient(client, apiHost.rawURL)
+		if cfg.ContentCache != nil {
+			// Cached content is only served to the token that read it, getClient checked it is set
+			identity, _ := identityFromContext(ctx)
+			rawClient = rawClient.WithCache(cfg.ContentCache, identity.token)
+		}
+		return rawClient, nil
+	}
+
+	getValidator := func(ctx context.Context) (*access.Validator, error) {
+		session, _, err := sessions.open(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return session.getValidator(), nil
+	}
+
+	// When a client initializes a session, include the client info in the user agent of its requests
+	beforeInit := func(ctx context.Context, _ any, message *mcp.InitializeRequest) {
+		sessions.setUserAgent(ctx, fmt.Sprintf(
+			"github-mcp-server/%s (%s/%s)",
+			cfg.Version,
+			message.Params.ClientInfo.Name,
+			message.Params.ClientInfo.Version,
+		))
+	}
+
+	hooks := &server.Hooks{
+		OnBeforeInitialize: []server.OnBeforeInitializeFunc{beforeInit},
+	}
+	return newToolsetServer(cfg, hooks, getClient, getGQLClient, getRawClient, getValidator, toolMiddleware)
+}
+
+// requestIdentity is who a streamable HTTP request is made for
+type requestIdentity struct {
+	token     string
+	userEmail string
+}
+
+type identityKey struct{}
+
+// identityFromContext returns the identity the authenticate handler stored in ctx
+func identityFromContext(ctx context.Context) (requestIdentity, bool) {
+	identity, ok := ctx.Value(identityKey{}).(requestIdentity)
+	return identity, ok
+}
+
+// parseAuthorization returns the token of a "Bearer <token>" or "token <token>" Authorization header
+func parseAuthorization(header string) (string, error) {
+	if header == "" {
+		return "", fmt.Errorf("missing Authorization header")
+	}
+	scheme, token, found := strings.Cut(header, " ")
+	if !found || (!strings.EqualFold(scheme, "bearer") && !strings.EqualFold(scheme, "token")) {
+		return "", fmt.Errorf("the Authorization header must be \"Bearer <GitHub token>\"")
+	}
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return "", fmt.Errorf("the Authorization header has no token")
+	}
+	return token, nil
+}
+
+// httpSession is a session of the streamable HTTP server. It is bound to the identity of the
+// request that initialized it, and owns the access validator of that user once it is opened.
+type httpSession struct {
+	// openMu serializes opening the session, so its validator is only loaded once
+	openMu sync.Mutex
+
+	mu        sync.Mutex
+	bound     bool
+	tokenHash [sha256.Size]byte
+	userEmail string
+	userAgent string
+	lastSeen  time.Time
+	validator *access.Validator
+	// stopRefresher stops refreshing the validator
+	stopRefresher context.CancelFunc
+	closed        bool
+}
+
+// matchesLocked reports whether the session is bound to the identity
+func (s *httpSession) matchesLocked(identity requestIdentity) bool {
+	tokenHash := sha256.Sum256([]byte(identity.token))
+	return s.bound && subtle.ConstantTimeCompare(s.tokenHash[:], tokenHash[:]) == 1 && strings.EqualFold(s.userEmail, identity.userEmail)
+}
+
+func (s *httpSession) getUserAgent() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.userAgent
+}
+
+func (s *httpSession) getValidator() *access.Validator {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.validator
+}
+
+// close stops and releases the access validator of the session
+func (s *httpSession) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	if s.stopRefresher != nil {
+		s.stopRefresher()
+	}
+	if s.validator != nil {
+		_ = s.validator.Close()
+	}
+}
+
+// httpSessions tracks the sessions of the streamable HTTP server. It implements
+// server.SessionIdManager, so sessions are created on initialize and removed when the client
+// deletes them or they go idle.
+type httpSessions struct {
+	// newValidator verifies the identity of a session and loads the repository access of its
+	// user within ctx, retrying in the background until background, the session's, is done
+	newValidator func(ctx, background context.Context, identity requestIdentity) (*access.Validator, error)
+	logger       *slog.Logger
+	now          func() time.Time
+
+	mu       sync.Mutex
+	sessions map[string]*httpSession
+	// refreshCtx is the parent context of the refreshers of the validators, and is canceled
+	// when every session is closed
+	refreshCtx    context.Context
+	refreshCancel context.CancelFunc
+}
+
+var _ server.SessionIdManager = (*httpSessions)(nil)
+
+func newHTTPSessions(newValidator func(ctx, background context.Context, identity requestIdentity) (*access.Validator, error), logger *slog.Logger) *httpSessions {
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+	refreshCtx, refreshCancel := context.WithCancel(context.Background())
+	return &httpSessions{
+		newValidator:  newValidator,
+		logger:        logger,
+		now:           time.Now,
+		sessions:      make(map[string]*httpSession),
+		refreshCtx:    refreshCtx,
+		refreshCancel: refreshCancel,
+	}
+}
+
+// Generate creates an unbound session, which is bound to the identity of the initialize request
+func (s *httpSessions) Generate() string {
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+	sessionID := "mcp-session-" + hex.EncodeToString(id)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions[sessionID] = &httpSession{lastSeen: s.now()}
+	return sessionID
+}
+
+// Validate reports unknown sessions as terminated, so clients of expired sessions and of a
+// restarted server initialize a new session
+func (s *httpSessions) Validate(sessionID string) (bool, error) {
+	if sessionID == "" {
+		return false, fmt.Errorf("missing session ID")
+	}
+	return s.get(sessionID) == nil, nil
+}
+
+// Terminate closes the session and its access validator
+func (s *httpSessions) Terminate(sessionID string) (bool, error) {
+	s.mu.Lock()
+	session := s.sessions[sessionID]
+	delete(s.sessions, sessionID)
+	s.mu.Unlock()
+
+	if session != nil {
+		session.close()
+	}
+	return false, nil
+}
+
+func (s *httpSessions) get(sessionID string) *httpSession {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sessions[sessionID]
+}
+
+// authenticate wraps the MCP handler to require a GitHub token and a user email on every
+// request, and to refuse requests for a session bound to someone else
+func (s *httpSessions) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, err := parseAuthorization(r.Header.Get("Authorization"))
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="github-mcp-server"`)
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		userEmail := strings.TrimSpace(r.Header.Get(UserEmailHeader))
+		if userEmail == "" {
+			http.Error(w, fmt.Sprintf("missing %s header", UserEmailHeader), http.StatusBadRequest)
+			return
+		}
+		identity := requestIdentity{token: token, userEmail: userEmail}
+
+		// Unknown sessions are answered by the session ID manager
+		if session := s.get(r.Header.Get(server.HeaderKeySessionID)); session != nil {
+			session.mu.Lock()
+			allowed := !session.bound || session.matchesLocked(identity)
+			session.lastSeen = s.now()
+			session.mu.Unlock()
+			if !allowed {
+				http.Error(w, "the session belongs to another token or user", http.StatusForbidden)
+				return
+			}
+		}
+
+		ctx := context.WithValue(r.Context(), identityKey{}, identity)
+		ctx = audit.ContextWithUserEmail(ctx, userEmail)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// bind is the HTTP context function of the streamable HTTP server. It binds a new session to
+// the identity of its initialize request.
+func (s *httpSessions) bind(ctx context.Context, _ *http.Request) context.Context {
+	clientSession := server.ClientSessionFromContext(ctx)
+	identity, ok := identityFromContext(ctx)
+	if clientSession == nil || !ok {
+		return ctx
+	}
+	session := s.get(clientSession.SessionID())
+	if session == nil {
+		return ctx
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	if !session.bound {
+		session.bound = true
+		session.tokenHash = sha256.Sum256([]byte(identity.token))
+		session.userEmail = identity.userEmail
+	}
+	return ctx
+}
+
+// open returns the session of the request in ctx and who the request is made for. The first
+// time a session is opened, the identity is verified and the access validator of the user is
+// loaded; if that fails, the next request of the session tries again.
+func (s *httpSessions) open(ctx context.Context) (*httpSession, requestIdentity, error) {
+	identity, ok := identityFromContext(ctx)
+	if !ok {
+		return nil, requestIdentity{}, fmt.Errorf("no GitHub token in the request")
+	}
+	clientSession := server.ClientSessionFromContext(ctx)
+	if clientSession == nil {
+		return nil, requestIdentity{}, fmt.Errorf("no MCP session in the request")
+	}
+	session := s.get(clientSession.SessionID())
+	if session == nil {
+		return nil, requestIdentity{}, fmt.Errorf("session %s is not active", clientSession.SessionID())
+	}
+
+	session.mu.Lock()
+	bound := session.matchesLocked(identity)
+	session.mu.Unlock()
+	if !bound {
+		return nil, requestIdentity{}, fmt.Errorf("the session belongs to another token or user")
+	}
+
+	session.openMu.Lock()
+	defer session.openMu.Unlock()
+	if session.getValidator() != nil {
+		return session, identity, nil
+	}
+
+	// The validator keeps loading and refreshing in the background until the session closes
+	refreshCtx, stopRefresher := context.WithCancel(s.refreshCtx)
+	validator, err := s.newValidator(ctx, refreshCtx, identity)
+	if err != nil {
+		stopRefresher()
+		s.logger.Warn("failed to open session", "user", identity.userEmail, "error", err)
+		return nil, requestIdentity{}, err
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	if session.closed {
+		stopRefresher()
+		_ = validator.Close()
+		return nil, requestIdentity{}, fmt.Errorf("session %s is not active", clientSession.SessionID())
+	}
+	// Keep the accessible repositories current for long running sessions
+	validator.StartRefresher(refreshCtx)
+	session.validator = validator
+	session.stopRefresher = stopRefresher
+	return session, identity, nil
+}
+
+// setUserAgent records the user agent of the session in ctx
+func (s *httpSessions) setUserAgent(ctx context.Context, userAgent string) {
+	clientSession := server.ClientSessionFromContext(ctx)
+	if clientSession == nil {
+		return
+	}
+	if session := s.get(clientSession.SessionID()); session != nil {
+		session.mu.Lock()
+		defer session.mu.Unlock()
+		session.userAgent = userAgent
+	}
+}
+
+// closeIdle closes the sessions that have gone without a request for longer than idleTimeout
+func (s *httpSessions) closeIdle(idleTimeout time.Duration) {
+	var idle []*httpSession
+	s.mu.Lock()
+	for sessionID, session := range s.sessions {
+		session.mu.Lock()
+		expired := s.now().Sub(session.lastSeen) > idleTimeout
+		session.mu.Unlock()
+		if expired {
+			idle = append(idle, session)
+			delete(s.sessions, sessionID)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, session := range idle {
+		session.close()
+	}
+}
+
+// expireIdle closes idle sessions until ctx is done
+func (s *httpSessions) expireIdle(ctx context.Context, idleTimeout time.Duration) {
+	ticker := time.NewTicker(max(idleTimeout/4, time.Second))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.closeIdle(idleTimeout)
+		}
+	}
+}
+
+// refreshAll refetches the accessible repositories of every open session in the background
+func (s *httpSessions) refreshAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, session := range s.sessions {
+		if validator := session.getValidator(); validator != nil {
+			validator.RefreshAsync()
+		}
+	}
+}
+
+// closeAll closes every session and stops refreshing their validators
+func (s *httpSessions) closeAll() {
+	s.refreshCancel()
+
+	s.mu.Lock()
+	sessions := s.sessions
+	s.sessions = make(map[string]*httpSession)
+	s.mu.Unlock()
+
+	for _, session := range sessions {
+		session.close()
+	}
+}
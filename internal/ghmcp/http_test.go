@@ -0,0 +1,286 @@
+package ghmcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/github/github-mcp-server/pkg/access"
+	"github.com/github/github-mcp-server/pkg/translations"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// httpTestServer is a streamable HTTP server whose GitHub API answers GET /user with the login
+// the token belongs to
+type httpTestServer struct {
+	url      string
+	sessions *httpSessions
+
+	mu         sync.Mutex
+	validators map[string]int
+}
+
+func newHTTPTestServer(t *testing.T, newValidator func(ctx, background context.Context, identity requestIdentity) (*access.Validator, error)) *httpTestServer {
+	t.Helper()
+
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]string{"login": strings.TrimPrefix(token, "token-")})
+	}))
+	t.Cleanup(api.Close)
+	apiURL, err := url.Parse(api.URL + "/")
+	require.NoError(t, err)
+
+	ts := &httpTestServer{validators: make(map[string]int)}
+	ts.sessions = newHTTPSessions(func(ctx, background context.Context, identity requestIdentity) (*access.Validator, error) {
+		ts.mu.Lock()
+		ts.validators[identity.userEmail]++
+		ts.mu.Unlock()
+		return newValidator(ctx, background, identity)
+	}, nil)
+	t.Cleanup(ts.sessions.closeAll)
+
+	ghServer, err := newHTTPMCPServer(MCPServerConfig{
+		Version:           "test",
+		EnabledToolsets:   []string{"all"},
+		Translator:        translations.NullTranslationHelper,
+		ContentWindowSize: 5000,
+	}, apiHost{baseRESTURL: apiURL, graphqlURL: apiURL, uploadURL: apiURL, rawURL: apiURL}, ts.sessions)
+	require.NoError(t, err)
+
+	streamableServer := server.NewStreamableHTTPServer(ghServer,
+		server.WithSessionIdManager(ts.sessions),
+		server.WithHTTPContextFunc(ts.sessions.bind),
+	)
+	mux := http.NewServeMux()
+	mux.Handle(DefaultHTTPEndpointPath, ts.sessions.authenticate(streamableServer))
+	httpServer := httptest.NewServer(mux)
+	t.Cleanup(httpServer.Close)
+	ts.url = httpServer.URL + DefaultHTTPEndpointPath
+	return ts
+}
+
+// validatorsCreated returns how many validators were created for the user
+func (ts *httpTestServer) validatorsCreated(userEmail string) int {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	return ts.validators[userEmail]
+}
+
+// do sends a request to the MCP endpoint and returns the response, with its JSON-RPC body decoded
+func (ts *httpTestServer) do(t *testing.T, method, token, userEmail, sessionID string, message any) (*http.Response, map[string]any) {
+	t.Helper()
+
+	var body bytes.Buffer
+	if message != nil {
+		require.NoError(t, json.NewEncoder(&body).Encode(message))
+	}
+	req, err := http.NewRequest(method, ts.url, &body)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if userEmail != "" {
+		req.Header.Set(UserEmailHeader, userEmail)
+	}
+	if sessionID != "" {
+		req.Header.Set(server.HeaderKeySessionID, sessionID)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+
+	var decoded map[string]any
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&decoded))
+	}
+	return resp, decoded
+}
+
+// initialize starts a session and returns its ID
+func (ts *httpTestServer) initialize(t *testing.T, token, userEmail string) string {
+	t.Helper()
+	resp, _ := ts.do(t, http.MethodPost, token, userEmail, "", map[string]any{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  "initialize",
+		"params": map[string]any{
+			"protocolVersion": "2025-03-26",
+			"clientInfo":      map[string]any{"name": "test-client", "version": "1.0"},
+		},
+	})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	sessionID := resp.Header.Get(server.HeaderKeySessionID)
+	require.NotEmpty(t, sessionID)
+	return sessionID
+}
+
+// callTool calls a tool in the session and returns the status code and the text of its result
+func (ts *httpTestServer) callTool(t *testing.T, token, userEmail, sessionID, name string, args map[string]any) (int, string, bool) {
+	t.Helper()
+	resp, body := ts.do(t, http.MethodPost, token, userEmail, sessionID, map[string]any{
+		"jsonrpc": "2.0",
+		"id":      2,
+		"method":  "tools/call",
+		"params":  map[string]any{"name": name, "arguments": args},
+	})
+	if resp.StatusCode != http.StatusOK {
+		return resp.StatusCode, "", false
+	}
+	result, ok := body["result"].(map[string]any)
+	require.True(t, ok, "unexpected response: %v", body)
+	content := result["content"].([]any)[0].(map[string]any)
+	isError, _ := result["isError"].(bool)
+	return resp.StatusCode, content["text"].(string), isError
+}
+
+func newStaticValidator(t *testing.T, userEmail string, repos ...string) *access.Validator {
+	t.Helper()
+	validator := access.NewValidatorWithProvider(userEmail, access.NewStaticProvider(repos...))
+	require.NoError(t, validator.Initialize())
+	return validator
+}
+
+func TestParseAuthorization(t *testing.T) {
+	for _, header := range []string{"Bearer ghp_token", "bearer ghp_token", "token ghp_token", "Bearer  ghp_token "} {
+		token, err := parseAuthorization(header)
+		require.NoError(t, err, header)
+		assert.Equal(t, "ghp_token", token)
+	}
+
+	for _, header := range []string{"", "ghp_token", "Basic dXNlcjpwYXNz", "Bearer "} {
+		_, err := parseAuthorization(header)
+		assert.Error(t, err, header)
+	}
+}
+
+func TestHTTPServer_Authentication(t *testing.T) {
+	ts := newHTTPTestServer(t, func(_, _ context.Context, identity requestIdentity) (*access.Validator, error) {
+		return newStaticValidator(t, identity.userEmail), nil
+	})
+	initialize := map[string]any{"jsonrpc": "2.0", "id": 1, "method": "initialize", "params": map[string]any{}}
+
+	resp, _ := ts.do(t, http.MethodPost, "", "alice@example.com", "", initialize)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.NotEmpty(t, resp.Header.Get("WWW-Authenticate"))
+
+	resp, _ = ts.do(t, http.MethodPost, "token-alice", "", "", initialize)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	// Unknown sessions are reported as terminated, so clients start a new one
+	resp, _ = ts.do(t, http.MethodPost, "token-alice", "alice@example.com", "mcp-session-unknown", map[string]any{"jsonrpc": "2.0", "id": 2, "method": "tools/list"})
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestHTTPServer_Sessions(t *testing.T) {
+	ts := newHTTPTestServer(t, func(_, _ context.Context, identity requestIdentity) (*access.Validator, error) {
+		switch identity.userEmail {
+		case "alice@example.com":
+			return newStaticValidator(t, identity.userEmail, "octo-org/alice-repo"), nil
+		case "bob@example.com":
+			return newStaticValidator(t, identity.userEmail, "octo-org/bob-repo"), nil
+		}
+		return nil, fmt.Errorf("user email %s is not a verified email address", identity.userEmail)
+	})
+
+	alice := ts.initialize(t, "token-alice", "alice@example.com")
+	bob := ts.initialize(t, "token-bob", "bob@example.com")
+	assert.NotEqual(t, alice, bob)
+
+	checkAccess := func(token, userEmail, sessionID, repo string) string {
+		status, text, isError := ts.callTool(t, token, userEmail, sessionID, "check_repository_access", map[string]any{"owner": "octo-org", "repo": repo})
+		require.Equal(t, http.StatusOK, status)
+		require.False(t, isError, text)
+		return text
+	}
+
+	t.Run("each session validates access as its user", func(t *testing.T) {
+		assert.Contains(t, checkAccess("token-alice", "alice@example.com", alice, "alice-repo"), `"allowed":true`)
+		assert.Contains(t, checkAccess("token-alice", "alice@example.com", alice, "bob-repo"), `"allowed":false`)
+		assert.Contains(t, checkAccess("token-bob", "bob@example.com", bob, "bob-repo"), `"allowed":true`)
+		assert.Contains(t, checkAccess("token-bob", "bob@example.com", bob, "alice-repo"), `"allowed":false`)
+
+		// The validator is created once per session
+		assert.Equal(t, 1, ts.validatorsCreated("alice@example.com"))
+		assert.Equal(t, 1, ts.validatorsCreated("bob@example.com"))
+	})
+
+	t.Run("clients are built from the token of each request", func(t *testing.T) {
+		status, text, isError := ts.callTool(t, "token-alice", "alice@example.com", alice, "get_me", nil)
+		require.Equal(t, http.StatusOK, status)
+		require.False(t, isError, text)
+		assert.Contains(t, text, `"login":"alice"`)
+	})
+
+	t.Run("sessions cannot be used with another identity", func(t *testing.T) {
+		status, _, _ := ts.callTool(t, "token-bob", "bob@example.com", alice, "get_me", nil)
+		assert.Equal(t, http.StatusForbidden, status)
+
+		status, _, _ = ts.callTool(t, "token-bob", "alice@example.com", alice, "get_me", nil)
+		assert.Equal(t, http.StatusForbidden, status)
+
+		resp, _ := ts.do(t, http.MethodDelete, "token-bob", "bob@example.com", alice, nil)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	})
+
+	t.Run("unverified users cannot use their session", func(t *testing.T) {
+		mallory := ts.initialize(t, "token-mallory", "mallory@example.com")
+		_, text, isError := ts.callTool(t, "token-mallory", "mallory@example.com", mallory, "get_me", nil)
+		assert.True(t, isError)
+		assert.Contains(t, text, "not a verified email address")
+
+		// Opening the session is retried on the next request
+		_, _, isError = ts.callTool(t, "token-mallory", "mallory@example.com", mallory, "check_repository_access", map[string]any{"owner": "octo-org", "repo": "alice-repo"})
+		assert.True(t, isError)
+		assert.Equal(t, 2, ts.validatorsCreated("mallory@example.com"))
+	})
+
+	t.Run("terminated sessions are closed", func(t *testing.T) {
+		resp, _ := ts.do(t, http.MethodDelete, "token-bob", "bob@example.com", bob, nil)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		status, _, _ := ts.callTool(t, "token-bob", "bob@example.com", bob, "get_me", nil)
+		assert.Equal(t, http.StatusNotFound, status)
+
+		// Alice's session is unaffected
+		assert.Contains(t, checkAccess("token-alice", "alice@example.com", alice, "alice-repo"), `"allowed":true`)
+	})
+}
+
+func TestHTTPSessions_CloseIdle(t *testing.T) {
+	now := time.Now()
+	sessions := newHTTPSessions(nil, nil)
+	sessions.now = func() time.Time { return now }
+	defer sessions.closeAll()
+
+	idle := sessions.Generate()
+	now = now.Add(30 * time.Minute)
+	active := sessions.Generate()
+	now = now.Add(31 * time.Minute)
+
+	sessions.closeIdle(time.Hour)
+
+	terminated, err := sessions.Validate(idle)
+	require.NoError(t, err)
+	assert.True(t, terminated)
+
+	terminated, err = sessions.Validate(active)
+	require.NoError(t, err)
+	assert.False(t, terminated)
+
+	_, err = sessions.Validate("")
+	assert.Error(t, err)
+}
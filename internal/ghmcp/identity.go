@@ -0,0 +1,116 @@
+package ghmcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	gogithub "github.com/google/go-github/v74/github"
+)
+
+// UserEmailCheck decides what happens when the configured user email is not a verified email
+// address of the owner of the GitHub token, which would let the token owner be granted the
+// repository access of someone else
+type UserEmailCheck string
+
+const (
+	// UserEmailCheckEnforce refuses to start on a mismatch
+	UserEmailCheckEnforce UserEmailCheck = "enforce"
+	// UserEmailCheckReadOnly starts with read-only tools only on a mismatch
+	UserEmailCheckReadOnly UserEmailCheck = "read-only"
+	// UserEmailCheckOff skips the check
+	UserEmailCheckOff UserEmailCheck = "off"
+)
+
+// ParseUserEmailCheck parses a user email check mode, an empty string selects UserEmailCheckEnforce
+func ParseUserEmailCheck(s string) (UserEmailCheck, error) {
+	switch check := UserEmailCheck(s); check {
+	case "":
+		return UserEmailCheckEnforce, nil
+	case UserEmailCheckEnforce, UserEmailCheckReadOnly, UserEmailCheckOff:
+		return check, nil
+	default:
+		return "", fmt.Errorf("unknown user email check %q (expected one of %s, %s, %s)", s, UserEmailCheckEnforce, UserEmailCheckReadOnly, UserEmailCheckOff)
+	}
+}
+
+// enforceUserEmail runs the user email check. It returns an error if the server must not
+// start, and true if it must start with read-only tools only.
+func enforceUserEmail(ctx context.Context, check UserEmailCheck, client *gogithub.Client, userEmail string, logger *slog.Logger) (bool, error) {
+	if check == UserEmailCheckOff {
+		return false, nil
+	}
+
+	err := verifyUserEmail(ctx, client, userEmail)
+	switch {
+	case err == nil:
+		return false, nil
+	case check == UserEmailCheckReadOnly:
+		logger.Warn("starting with read-only tools only, the user email could not be verified", "user", userEmail, "error", err)
+		return true, nil
+	default:
+		return false, fmt.Errorf("failed to verify user email: %w", err)
+	}
+}
+
+// verifyUserEmail checks that userEmail is a verified email address of the user the client
+// is authenticated as. The email addresses are listed with the user:email scope (or the
+// "Email addresses" read permission of fine-grained tokens); without it only the public
+// profile email, which GitHub requires to be verified, can be matched.
+func verifyUserEmail(ctx context.Context, client *gogithub.Client, userEmail string) error {
+	if userEmail == "" {
+		return errors.New("no user email to verify")
+	}
+
+	user, _, err := client.Users.Get(ctx, "")
+	if err != nil {
+		return fmt.Errorf("failed to get the authenticated user: %w", err)
+	}
+
+	emails, err := listVerifiedEmails(ctx, client)
+	if err != nil {
+		var errResp *gogithub.ErrorResponse
+		if !errors.As(err, &errResp) || errResp.Response == nil ||
+			(errResp.Response.StatusCode != http.StatusForbidden && errResp.Response.StatusCode != http.StatusNotFound) {
+			return fmt.Errorf("failed to list the email addresses of %s: %w", user.GetLogin(), err)
+		}
+
+		// The token may not read email addresses, fall back to the public profile email
+		if strings.EqualFold(user.GetEmail(), userEmail) {
+			return nil
+		}
+		return fmt.Errorf("user email %s is not the public email of %s, the owner of the GitHub token, "+
+			"and the token cannot list its other email addresses (grant the user:email scope to check them)", userEmail, user.GetLogin())
+	}
+
+	for _, email := range emails {
+		if strings.EqualFold(email, userEmail) {
+			return nil
+		}
+	}
+	return fmt.Errorf("user email %s is not a verified email address of %s, the owner of the GitHub token", userEmail, user.GetLogin())
+}
+
+// listVerifiedEmails returns every verified email address of the authenticated user
+func listVerifiedEmails(ctx context.Context, client *gogithub.Client) ([]string, error) {
+	var verified []string
+	opts := &gogithub.ListOptions{PerPage: 100}
+	for {
+		emails, resp, err := client.Users.ListEmails(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, email := range emails {
+			if email.GetVerified() {
+				verified = append(verified, email.GetEmail())
+			}
+		}
+		if resp.NextPage == 0 {
+			return verified, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
@@ -0,0 +1,147 @@
+package ghmcp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"testing"
+
+	gogithub "github.com/google/go-github/v74/github"
+	"github.com/migueleliasweb/go-github-mock/src/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func jsonHandler(t *testing.T, code int, body interface{}) http.HandlerFunc {
+	t.Helper()
+	return func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(code)
+		require.NoError(t, json.NewEncoder(w).Encode(body))
+	}
+}
+
+func TestParseUserEmailCheck(t *testing.T) {
+	check, err := ParseUserEmailCheck("")
+	require.NoError(t, err)
+	assert.Equal(t, UserEmailCheckEnforce, check)
+
+	for _, s := range []string{"enforce", "read-only", "off"} {
+		check, err := ParseUserEmailCheck(s)
+		require.NoError(t, err)
+		assert.Equal(t, UserEmailCheck(s), check)
+	}
+
+	_, err = ParseUserEmailCheck("warn")
+	require.Error(t, err)
+}
+
+func TestVerifyUserEmail(t *testing.T) {
+	user := &gogithub.User{Login: gogithub.Ptr("octocat"), Email: gogithub.Ptr("public@example.com")}
+	emails := []*gogithub.UserEmail{
+		{Email: gogithub.Ptr("octocat@example.com"), Verified: gogithub.Ptr(true), Primary: gogithub.Ptr(true)},
+		{Email: gogithub.Ptr("unverified@example.com"), Verified: gogithub.Ptr(false)},
+	}
+
+	tests := []struct {
+		name           string
+		userEmail      string
+		emailsHandler  http.HandlerFunc
+		expectedErrMsg string
+	}{
+		{
+			name:          "verified email",
+			userEmail:     "octocat@example.com",
+			emailsHandler: jsonHandler(t, http.StatusOK, emails),
+		},
+		{
+			name:          "verified email, case insensitive",
+			userEmail:     "OctoCat@Example.com",
+			emailsHandler: jsonHandler(t, http.StatusOK, emails),
+		},
+		{
+			name:           "unverified email",
+			userEmail:      "unverified@example.com",
+			emailsHandler:  jsonHandler(t, http.StatusOK, emails),
+			expectedErrMsg: "user email unverified@example.com is not a verified email address of octocat",
+		},
+		{
+			name:           "email of someone else",
+			userEmail:      "admin@example.com",
+			emailsHandler:  jsonHandler(t, http.StatusOK, emails),
+			expectedErrMsg: "user email admin@example.com is not a verified email address of octocat",
+		},
+		{
+			name:          "public email without the user:email scope",
+			userEmail:     "public@example.com",
+			emailsHandler: jsonHandler(t, http.StatusNotFound, map[string]string{"message": "Not Found"}),
+		},
+		{
+			name:           "other email without the user:email scope",
+			userEmail:      "octocat@example.com",
+			emailsHandler:  jsonHandler(t, http.StatusForbidden, map[string]string{"message": "Resource not accessible by personal access token"}),
+			expectedErrMsg: "grant the user:email scope",
+		},
+		{
+			name:           "listing fails",
+			userEmail:      "octocat@example.com",
+			emailsHandler:  jsonHandler(t, http.StatusInternalServerError, map[string]string{"message": "Internal Server Error"}),
+			expectedErrMsg: "failed to list the email addresses of octocat",
+		},
+		{
+			name:           "no user email",
+			emailsHandler:  jsonHandler(t, http.StatusOK, emails),
+			expectedErrMsg: "no user email to verify",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			client := gogithub.NewClient(mock.NewMockedHTTPClient(
+				mock.WithRequestMatch(mock.GetUser, user),
+				mock.WithRequestMatchHandler(mock.GetUserEmails, tc.emailsHandler),
+			))
+
+			err := verifyUserEmail(context.Background(), client, tc.userEmail)
+			if tc.expectedErrMsg == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.expectedErrMsg)
+		})
+	}
+}
+
+func TestEnforceUserEmail(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	newClient := func() *gogithub.Client {
+		return gogithub.NewClient(mock.NewMockedHTTPClient(
+			mock.WithRequestMatch(mock.GetUser, &gogithub.User{Login: gogithub.Ptr("octocat")}),
+			mock.WithRequestMatch(mock.GetUserEmails, []*gogithub.UserEmail{
+				{Email: gogithub.Ptr("octocat@example.com"), Verified: gogithub.Ptr(true)},
+			}),
+		))
+	}
+
+	readOnly, err := enforceUserEmail(context.Background(), UserEmailCheckEnforce, newClient(), "octocat@example.com", logger)
+	require.NoError(t, err)
+	assert.False(t, readOnly)
+
+	_, err = enforceUserEmail(context.Background(), UserEmailCheckEnforce, newClient(), "admin@example.com", logger)
+	require.Error(t, err)
+
+	// The zero value enforces the check
+	_, err = enforceUserEmail(context.Background(), "", newClient(), "admin@example.com", logger)
+	require.Error(t, err)
+
+	readOnly, err = enforceUserEmail(context.Background(), UserEmailCheckReadOnly, newClient(), "admin@example.com", logger)
+	require.NoError(t, err)
+	assert.True(t, readOnly)
+
+	// The check is skipped without calling the API
+	readOnly, err = enforceUserEmail(context.Background(), UserEmailCheckOff, gogithub.NewClient(mock.NewMockedHTTPClient()), "admin@example.com", logger)
+	require.NoError(t, err)
+	assert.False(t, readOnly)
+}
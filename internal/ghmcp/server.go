@@ -12,12 +12,20 @@ import (
 	"os/signal"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/github/github-mcp-server/pkg/access"
+	"github.com/github/github-mcp-server/pkg/audit"
 	"github.com/github/github-mcp-server/pkg/errors"
 	"github.com/github/github-mcp-server/pkg/github"
+	"github.com/github/github-mcp-server/pkg/githubapp"
+	"github.com/github/github-mcp-server/pkg/httpcache"
 	mcplog "github.com/github/github-mcp-server/pkg/log"
+	"github.com/github/github-mcp-server/pkg/oauth"
+	"github.com/github/github-mcp-server/pkg/ratelimit"
 	"github.com/github/github-mcp-server/pkg/raw"
+	"github.com/github/github-mcp-server/pkg/toolsets"
+	"github.com/github/github-mcp-server/pkg/tracing"
 	"github.com/github/github-mcp-server/pkg/translations"
 	gogithub "github.com/google/go-github/v74/github"
 	"github.com/mark3labs/mcp-go/mcp"
@@ -35,9 +43,58 @@ type MCPServerConfig struct {
 	// GitHub Token to authenticate with the GitHub API
 	Token string
 
+	// Endpoints overrides the API endpoints derived from Host
+	Endpoints APIEndpoints
+
+	// App authenticates with the installation tokens of a GitHub App instead of Token
+	App githubapp.Config
+
+	// Login authenticates with the token stored by the login command instead of Token
+	Login *oauth.TokenSource
+
 	// User Email for repository access validation
 	UserEmail string
 
+	// AccessProvider selects the source of repository access (resource-map, policy-file or chain)
+	AccessProvider string
+
+	// AccessPolicyFile is the path to a local access policy file
+	AccessPolicyFile string
+
+	// AccessOverrideFile is the path to a file of allow and deny rules layered on the access provider
+	AccessOverrideFile string
+
+	// ResourceMap configures the connection to the resource-map service
+	ResourceMap access.ResourceMapConfig
+
+	// AccessValidator overrides the validator built from UserEmail and AccessProvider.
+	// It is started by NewMCPServer if it has not been already.
+	AccessValidator *access.Validator
+
+	// AccessStartup controls retries and the failure mode of the initial access load.
+	// The zero value makes a single attempt and fails closed.
+	AccessStartup access.StartupConfig
+
+	// AuditLogger records every tool call and its access decision, nil disables auditing
+	AuditLogger *audit.Logger
+
+	// UserEmailCheck decides what happens if UserEmail is not a verified email address of the
+	// token owner. The zero value refuses to start.
+	UserEmailCheck UserEmailCheck
+
+	// Logger receives warnings raised while building the server and rate limit waits, nil discards them
+	Logger *slog.Logger
+
+	// RateLimitBudget is how long a tool call may wait for GitHub rate limits and retries of
+	// transient errors, zero returns them to the model right away
+	RateLimitBudget time.Duration
+
+	// RESTCache caches REST responses and revalidates them with conditional requests, nil disables caching
+	RESTCache *httpcache.Cache
+
+	// ContentCache keeps file content and trees read at a full commit SHA, nil disables caching
+	ContentCache *raw.Cache
+
 	// EnabledToolsets is a list of toolsets to enable
 	// See: https://github.com/github/github-mcp-server?tab=readme-ov-file#tool-configuration
 	EnabledToolsets []string
@@ -59,30 +116,81 @@ type MCPServerConfig struct {
 const stdioServerLogPrefix = "stdioserver"
 
 func NewMCPServer(cfg MCPServerConfig) (*server.MCPServer, error) {
-	apiHost, err := parseAPIHost(cfg.Host)
+	apiHost, err := parseAPIHost(cfg.Host, cfg.Endpoints)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse API host: %w", err)
 	}
 
-	// Construct our REST client
-	restClient := gogithub.NewClient(nil).WithAuthToken(cfg.Token)
-	restClient.UserAgent = fmt.Sprintf("github-mcp-server/%s", cfg.Version)
-	restClient.BaseURL = apiHost.baseRESTURL
-	restClient.UploadURL = apiHost.uploadURL
+	logger := cfg.Logger
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+
+	// Wait out rate limits and transient errors within the budget of each tool call
+	var transport http.RoundTripper = ratelimit.NewTransport(http.DefaultTransport, cfg.RateLimitBudget, logger)
+	toolMiddleware := []toolsets.ToolMiddleware{tracing.Middleware(), ratelimit.Middleware(cfg.RateLimitBudget)}
+
+	// Record GitHub status codes in the audit log when it is enabled
+	if cfg.AuditLogger != nil {
+		transport = &audit.Transport{Transport: transport}
+		toolMiddleware = append(toolMiddleware, audit.Middleware(cfg.AuditLogger, cfg.UserEmail))
+	}
+
+	// Record each GitHub request, including its retries, as a span of the tool call
+	transport = tracing.NewTransport(transport, apiHost.apiName)
+
+	if err := checkAppUserEmail(cfg.UserEmailCheck, cfg.App); err != nil {
+		return nil, err
+	}
+
+	// Authenticate with the personal access token, the stored login or the refreshed tokens of the GitHub App
+	tokens, err := newTokenSource(context.Background(), cfg.Token, cfg.App, cfg.Login, apiHost)
+	if err != nil {
+		return nil, err
+	}
+
+	// Construct our REST client, revalidating cached responses if caching is enabled
+	restTransport := transport
+	if cfg.RESTCache != nil {
+		restTransport = cfg.RESTCache.Transport(transport)
+	}
+	restClient := newRESTClient(apiHost, tokens, cfg.Version, restTransport)
+
+	// Only enforce the repository access of the user the token belongs to
+	emailReadOnly, err := enforceUserEmail(context.Background(), cfg.UserEmailCheck, restClient, cfg.UserEmail, logger)
+	if err != nil {
+		return nil, err
+	}
+	if emailReadOnly {
+		cfg.ReadOnly = true
+	}
 
 	// Create and initialize the Access Validator (blocking operation)
-	validator := access.NewValidator(cfg.UserEmail)
-	if err := validator.Initialize(); err != nil {
+	validator := cfg.AccessValidator
+	if validator == nil {
+		validator, err = newAccessValidator(cfg.UserEmail, cfg.Host, cfg.AccessProvider, cfg.AccessPolicyFile, cfg.AccessOverrideFile, cfg.ResourceMap)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := validator.Start(context.Background(), cfg.AccessStartup); err != nil {
 		return nil, fmt.Errorf("failed to initialize access validator: %w", err)
 	}
 
+	// Without the accessible repositories only read-only tools are offered in degraded mode,
+	// for the lifetime of the server
+	if validator.Degraded() {
+		logger.Warn("access failure mode is degraded, only read-only tools are offered until the server is restarted")
+		cfg.ReadOnly = true
+	}
+
 	// Construct our GraphQL client
 	// We're using NewEnterpriseClient here unconditionally as opposed to NewClient because we already
 	// did the necessary API host parsing so that github.com will return the correct URL anyway.
 	gqlHTTPClient := &http.Client{
 		Transport: &bearerAuthTransport{
-			transport: http.DefaultTransport,
-			token:     cfg.Token,
+			transport: transport,
+			tokens:    tokens,
 		},
 	} // We're going to wrap the Transport later in beforeInit
 	gqlClient := githubv4.NewEnterpriseClient(apiHost.graphqlURL.String(), gqlHTTPClient)
@@ -104,16 +212,42 @@ func NewMCPServer(cfg MCPServerConfig) (*server.MCPServer, error) {
 		}
 	}
 
+	getClient := func(_ context.Context) (*gogithub.Client, error) {
+		return restClient, nil // closing over client
+	}
+
+	getGQLClient := func(_ context.Context) (*githubv4.Client, error) {
+		return gqlClient, nil // closing over client
+	}
+
+	getRawClient := func(ctx context.Context) (*raw.Client, error) {
+		client, err := getClient(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get GitHub client: %w", err)
+		}
+		rawClient := raw.NewClient(client, apiHost.rawURL) // closing over client
+		if cfg.ContentCache != nil {
+			// Every read of the server uses the same token owner
+			rawClient = rawClient.WithCache(cfg.ContentCache, "")
+		}
+		return rawClient, nil
+	}
+
 	hooks := &server.Hooks{
 		OnBeforeInitialize: []server.OnBeforeInitializeFunc{beforeInit},
-		OnBeforeAny: []server.BeforeAnyHookFunc{
-			func(ctx context.Context, _ any, _ mcp.MCPMethod, _ any) {
-				// Ensure the context is cleared of any previous errors
-				// as context isn't propagated through middleware
-				errors.ContextWithGitHubErrors(ctx)
-			},
-		},
 	}
+	return newToolsetServer(cfg, hooks, getClient, getGQLClient, getRawClient, github.StaticValidator(validator), toolMiddleware)
+}
+
+// newToolsetServer creates the MCP server offering the toolsets enabled in cfg. The tools get
+// their clients and access validator from the given functions, on every call.
+func newToolsetServer(cfg MCPServerConfig, hooks *server.Hooks, getClient github.GetClientFn, getGQLClient github.GetGQLClientFn, getRawClient raw.GetRawClientFn, getValidator github.GetValidatorFn, toolMiddleware []toolsets.ToolMiddleware) (*server.MCPServer, error) {
+	hooks.AddBeforeAny(func(ctx context.Context, _ any, _ mcp.MCPMethod, _ any) {
+		// Ensure the context is cleared of any previous errors
+		// as context isn't propagated through middleware
+		errors.ContextWithGitHubErrors(ctx)
+	})
+	tracing.AddHooks(hooks)
 
 	ghServer := github.NewServer(cfg.Version, server.WithHooks(hooks))
 
@@ -128,25 +262,9 @@ func NewMCPServer(cfg MCPServerConfig) (*server.MCPServer, error) {
 		}
 	}
 
-	getClient := func(_ context.Context) (*gogithub.Client, error) {
-		return restClient, nil // closing over client
-	}
-
-	getGQLClient := func(_ context.Context) (*githubv4.Client, error) {
-		return gqlClient, nil // closing over client
-	}
-
-	getRawClient := func(ctx context.Context) (*raw.Client, error) {
-		client, err := getClient(ctx)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get GitHub client: %w", err)
-		}
-		return raw.NewClient(client, apiHost.rawURL), nil // closing over client
-	}
-
 	// Create default toolsets
-	tsg := github.DefaultToolsetGroup(cfg.ReadOnly, getClient, getGQLClient, getRawClient, cfg.Translator, cfg.ContentWindowSize, validator)
-	err = tsg.EnableToolsets(enabledToolsets)
+	tsg := github.DefaultToolsetGroup(cfg.ReadOnly, getClient, getGQLClient, getRawClient, cfg.Translator, cfg.ContentWindowSize, getValidator, toolMiddleware...)
+	err := tsg.EnableToolsets(enabledToolsets)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to enable toolsets: %w", err)
@@ -163,6 +281,31 @@ func NewMCPServer(cfg MCPServerConfig) (*server.MCPServer, error) {
 	return ghServer, nil
 }
 
+// newRESTClient creates a REST client for the API host, authenticated with the tokens of the source
+func newRESTClient(apiHost apiHost, tokens tokenSource, version string, transport http.RoundTripper) *gogithub.Client {
+	restClient := gogithub.NewClient(&http.Client{Transport: &bearerAuthTransport{transport: transport, tokens: tokens}})
+	restClient.UserAgent = fmt.Sprintf("github-mcp-server/%s", version)
+	restClient.BaseURL = apiHost.baseRESTURL
+	restClient.UploadURL = apiHost.uploadURL
+	return restClient
+}
+
+// newAccessValidator creates an uninitialized access validator backed by the named provider and
+// the optional override file, keying repositories on the GitHub host the server talks to
+func newAccessValidator(userEmail, host, providerName, policyFile, overrideFile string, resourceMap access.ResourceMapConfig) (*access.Validator, error) {
+	accessProvider, err := access.NewProvider(providerName, policyFile, resourceMap)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create access provider: %w", err)
+	}
+	validator := access.NewValidatorWithProvider(userEmail, accessProvider)
+	if err := validator.SetHost(host); err != nil {
+		_ = validator.Close()
+		return nil, fmt.Errorf("failed to configure access validator: %w", err)
+	}
+	validator.SetOverrideFile(overrideFile)
+	return validator, nil
+}
+
 type StdioServerConfig struct {
 	// Version of the server
 	Version string
@@ -173,9 +316,69 @@ type StdioServerConfig struct {
 	// GitHub Token to authenticate with the GitHub API
 	Token string
 
+	// Endpoints overrides the API endpoints derived from Host
+	Endpoints APIEndpoints
+
+	// App authenticates with the installation tokens of a GitHub App instead of Token
+	App githubapp.Config
+
+	// Login authenticates with the token stored by the login command instead of Token
+	Login *oauth.TokenSource
+
 	// User Email for repository access validation
 	UserEmail string
 
+	// AccessProvider selects the source of repository access (resource-map, policy-file or chain)
+	AccessProvider string
+
+	// AccessPolicyFile is the path to a local access policy file
+	AccessPolicyFile string
+
+	// AccessOverrideFile is the path to a file of allow and deny rules layered on the access provider
+	AccessOverrideFile string
+
+	// ResourceMap configures the connection to the resource-map service
+	ResourceMap access.ResourceMapConfig
+
+	// AccessRefreshInterval is how often the accessible repositories are refetched, zero disables it
+	AccessRefreshInterval time.Duration
+
+	// AccessStartup controls retries, the startup deadline and the failure mode of the initial access load
+	AccessStartup access.StartupConfig
+
+	// AccessCacheFile persists the accessible repositories across restarts, empty disables the cache
+	AccessCacheFile string
+
+	// AccessCacheMaxAge is how old the cached repositories may be to start without fetching them
+	AccessCacheMaxAge time.Duration
+
+	// AuditLog configures the audit log, an empty path disables it.
+	// Stdout is refused because it carries the MCP protocol.
+	AuditLog audit.Config
+
+	// Tracing selects where the spans of MCP requests and GitHub requests are exported. The
+	// stdout exporter is refused because stdout carries the MCP protocol.
+	Tracing tracing.Config
+
+	// AccessDefaultPermission is the permission (read, triage, write or admin) assumed for
+	// repositories whose provider does not report one, defaults to read
+	AccessDefaultPermission string
+
+	// RateLimitBudget is how long a tool call may wait for GitHub rate limits and retries of
+	// transient errors, zero returns them to the model right away
+	RateLimitBudget time.Duration
+
+	// RESTCache sizes the cache of REST responses and its optional disk backing, a zero size disables it
+	RESTCache httpcache.Config
+
+	// ContentCache sizes the cache of content read at a full commit SHA and its optional disk
+	// backing, a zero size disables it
+	ContentCache raw.CacheConfig
+
+	// UserEmailCheck decides what happens if UserEmail is not a verified email address of the
+	// token owner: refuse to start (the default), start read-only or skip the check
+	UserEmailCheck UserEmailCheck
+
 	// EnabledToolsets is a list of toolsets to enable
 	// See: https://github.com/github/github-mcp-server?tab=readme-ov-file#tool-configuration
 	EnabledToolsets []string
@@ -209,14 +412,113 @@ func RunStdioServer(cfg StdioServerConfig) error {
 
 	t, dumpTranslations := translations.TranslationHelper()
 
+	var slogHandler slog.Handler
+	var logOutput io.Writer
+	if cfg.LogFilePath != "" {
+		file, err := os.OpenFile(cfg.LogFilePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			return fmt.Errorf("failed to open log file: %w", err)
+		}
+		logOutput = file
+		slogHandler = slog.NewTextHandler(logOutput, &slog.HandlerOptions{Level: slog.LevelDebug})
+	} else {
+		logOutput = os.Stderr
+		slogHandler = slog.NewTextHandler(logOutput, &slog.HandlerOptions{Level: slog.LevelInfo})
+	}
+	logger := slog.New(slogHandler)
+
+	if cfg.Tracing.Exporter == tracing.ExporterStdout {
+		return fmt.Errorf("traces cannot be written to stdout by the stdio server, use the %s exporter", tracing.ExporterFile)
+	}
+	stopTracing, err := startTracing(ctx, cfg.Tracing, cfg.Version, logger)
+	if err != nil {
+		return err
+	}
+	defer stopTracing()
+
+	validator, err := newAccessValidator(cfg.UserEmail, cfg.Host, cfg.AccessProvider, cfg.AccessPolicyFile, cfg.AccessOverrideFile, cfg.ResourceMap)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = validator.Close() }()
+	validator.SetRefreshInterval(cfg.AccessRefreshInterval)
+	validator.SetCache(cfg.AccessCacheFile, cfg.AccessCacheMaxAge)
+	if cfg.AccessDefaultPermission != "" {
+		defaultPermission, err := access.ParsePermission(cfg.AccessDefaultPermission)
+		if err != nil {
+			return fmt.Errorf("invalid default access permission: %w", err)
+		}
+		validator.SetDefaultPermission(defaultPermission)
+	}
+
+	var auditLogger *audit.Logger
+	if cfg.AuditLog.Path != "" {
+		if cfg.AuditLog.Path == audit.Stdout {
+			return fmt.Errorf("the audit log cannot be written to stdout by the stdio server, use a file or %s", audit.Stderr)
+		}
+		auditLogger, err = audit.Open(cfg.AuditLog)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = auditLogger.Close() }()
+		auditLogger.SetErrorLogger(logger)
+	}
+
+	restCache, err := newRESTCache(cfg.RESTCache)
+	if err != nil {
+		return err
+	}
+	defer logRESTCacheStats(restCache, logger)
+
+	contentCache, err := newContentCache(cfg.ContentCache)
+	if err != nil {
+		return err
+	}
+	defer logContentCacheStats(contentCache, logger)
+
+	// Check who the token belongs to before loading anyone's repository access
+	apiHost, err := parseAPIHost(cfg.Host, cfg.Endpoints)
+	if err != nil {
+		return fmt.Errorf("failed to parse API host: %w", err)
+	}
+	if err := checkAppUserEmail(cfg.UserEmailCheck, cfg.App); err != nil {
+		return err
+	}
+	emailTokens := staticToken(cfg.Token)
+	if cfg.Login != nil {
+		cfg.Login.SetLogger(logger)
+		emailTokens = cfg.Login.Token
+	}
+	emailReadOnly, err := enforceUserEmail(ctx, cfg.UserEmailCheck, newRESTClient(apiHost, emailTokens, cfg.Version, http.DefaultTransport), cfg.UserEmail, logger)
+	if err != nil {
+		return err
+	}
+
+	// Load the accessible repositories, retrying until the startup deadline
+	validator.SetLogger(logger)
+	if err := validator.Start(ctx, cfg.AccessStartup); err != nil {
+		return fmt.Errorf("failed to initialize access validator: %w", err)
+	}
+	readOnly := cfg.ReadOnly || emailReadOnly || validator.Degraded()
+
 	ghServer, err := NewMCPServer(MCPServerConfig{
 		Version:           cfg.Version,
 		Host:              cfg.Host,
+		Endpoints:         cfg.Endpoints,
 		Token:             cfg.Token,
+		App:               cfg.App,
+		Login:             cfg.Login,
 		UserEmail:         cfg.UserEmail,
+		AccessValidator:   validator,
+		AuditLogger:       auditLogger,
+		UserEmailCheck:    UserEmailCheckOff, // checked above
+		Logger:            logger,
+		RateLimitBudget:   cfg.RateLimitBudget,
+		RESTCache:         restCache,
+		ContentCache:      contentCache,
 		EnabledToolsets:   cfg.EnabledToolsets,
 		DynamicToolsets:   cfg.DynamicToolsets,
-		ReadOnly:          cfg.ReadOnly,
+		ReadOnly:          readOnly,
 		Translator:        t,
 		ContentWindowSize: cfg.ContentWindowSize,
 	})
@@ -226,24 +528,15 @@ func RunStdioServer(cfg StdioServerConfig) error {
 
 	stdioServer := server.NewStdioServer(ghServer)
 
-	var slogHandler slog.Handler
-	var logOutput io.Writer
-	if cfg.LogFilePath != "" {
-		file, err := os.OpenFile(cfg.LogFilePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
-		if err != nil {
-			return fmt.Errorf("failed to open log file: %w", err)
-		}
-		logOutput = file
-		slogHandler = slog.NewTextHandler(logOutput, &slog.HandlerOptions{Level: slog.LevelDebug})
-	} else {
-		logOutput = os.Stderr
-		slogHandler = slog.NewTextHandler(logOutput, &slog.HandlerOptions{Level: slog.LevelInfo})
-	}
-	logger := slog.New(slogHandler)
-	logger.Info("starting server", "version", cfg.Version, "host", cfg.Host, "dynamicToolsets", cfg.DynamicToolsets, "readOnly", cfg.ReadOnly)
+	logger.Info("starting server", "version", cfg.Version, "host", cfg.Host, "dynamicToolsets", cfg.DynamicToolsets, "readOnly", readOnly)
 	stdLogger := log.New(logOutput, stdioServerLogPrefix, 0)
 	stdioServer.SetErrorLogger(stdLogger)
 
+	// Keep the accessible repositories current for long running sessions,
+	// and let operators force a refresh with SIGHUP
+	validator.StartRefresher(ctx)
+	watchRefreshSignal(ctx, validator.RefreshAsync, logger)
+
 	if cfg.ExportTranslations {
 		// Once server is initialized, all translations are loaded
 		dumpTranslations()
@@ -280,6 +573,84 @@ func RunStdioServer(cfg StdioServerConfig) error {
 	return nil
 }
 
+// startTracing starts exporting spans, and returns the function that flushes the remaining
+// spans when the server stops
+func startTracing(ctx context.Context, config tracing.Config, version string, logger *slog.Logger) (func(), error) {
+	shutdown, err := tracing.Start(ctx, config, version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start tracing: %w", err)
+	}
+	return func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdown(shutdownCtx); err != nil {
+			logger.Error("failed to flush traces", "error", err)
+		}
+	}, nil
+}
+
+// newRESTCache opens the cache of REST responses, or returns nil if it is disabled
+func newRESTCache(config httpcache.Config) (*httpcache.Cache, error) {
+	if config.MaxBytes <= 0 {
+		return nil, nil
+	}
+	cache, err := httpcache.New(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open REST cache: %w", err)
+	}
+	return cache, nil
+}
+
+// logRESTCacheStats logs how the REST cache answered requests, e.g. when the server stops
+func logRESTCacheStats(cache *httpcache.Cache, logger *slog.Logger) {
+	if cache == nil {
+		return
+	}
+	stats := cache.Stats()
+	logger.Info("REST cache stats", "hits", stats.Hits, "misses", stats.Misses, "evictions", stats.Evictions, "entries", stats.Entries, "bytes", stats.Bytes)
+}
+
+// newContentCache opens the cache of content read at a full commit SHA, or returns nil if it
+// is disabled
+func newContentCache(config raw.CacheConfig) (*raw.Cache, error) {
+	if config.MaxBytes <= 0 {
+		return nil, nil
+	}
+	cache, err := raw.NewCache(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open content cache: %w", err)
+	}
+	return cache, nil
+}
+
+// logContentCacheStats logs how the content cache answered reads, e.g. when the server stops
+func logContentCacheStats(cache *raw.Cache, logger *slog.Logger) {
+	if cache == nil {
+		return
+	}
+	stats := cache.Stats()
+	logger.Info("content cache stats", "hits", stats.Hits, "misses", stats.Misses, "evictions", stats.Evictions, "entries", stats.Entries, "bytes", stats.Bytes)
+}
+
+// watchRefreshSignal refreshes the accessible repositories whenever the process receives
+// SIGHUP, by calling refresh, which must not block
+func watchRefreshSignal(ctx context.Context, refresh func(), logger *slog.Logger) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(hup)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				logger.Info("refreshing accessible repositories", "signal", "SIGHUP")
+				refresh()
+			}
+		}
+	}()
+}
+
 type apiHost struct {
 	baseRESTURL *url.URL
 	graphqlURL  *url.URL
@@ -287,6 +658,21 @@ type apiHost struct {
 	rawURL      *url.URL
 }
 
+// apiName names the API of the host a request goes to, for its trace span
+func (h apiHost) apiName(req *http.Request) string {
+	u := req.URL.String()
+	switch {
+	case strings.HasPrefix(u, h.graphqlURL.String()):
+		return "graphql"
+	case strings.HasPrefix(u, h.rawURL.String()):
+		return "raw"
+	case strings.HasPrefix(u, h.uploadURL.String()):
+		return "upload"
+	default:
+		return "rest"
+	}
+}
+
 func newDotcomHost() (apiHost, error) {
 	baseRestURL, err := url.Parse("https://api.github.com/")
 	if err != nil {
@@ -361,21 +747,22 @@ func newGHESHost(hostname string) (apiHost, error) {
 		return apiHost{}, fmt.Errorf("failed to parse GHES URL: %w", err)
 	}
 
-	restURL, err := url.Parse(fmt.Sprintf("%s://%s/api/v3/", u.Scheme, u.Hostname()))
+	// u.Host keeps the port, e.g. for GHES on a non-standard port or a local stand-in
+	restURL, err := url.Parse(fmt.Sprintf("%s://%s/api/v3/", u.Scheme, u.Host))
 	if err != nil {
 		return apiHost{}, fmt.Errorf("failed to parse GHES REST URL: %w", err)
 	}
 
-	gqlURL, err := url.Parse(fmt.Sprintf("%s://%s/api/graphql", u.Scheme, u.Hostname()))
+	gqlURL, err := url.Parse(fmt.Sprintf("%s://%s/api/graphql", u.Scheme, u.Host))
 	if err != nil {
 		return apiHost{}, fmt.Errorf("failed to parse GHES GraphQL URL: %w", err)
 	}
 
-	uploadURL, err := url.Parse(fmt.Sprintf("%s://%s/api/uploads/", u.Scheme, u.Hostname()))
+	uploadURL, err := url.Parse(fmt.Sprintf("%s://%s/api/uploads/", u.Scheme, u.Host))
 	if err != nil {
 		return apiHost{}, fmt.Errorf("failed to parse GHES Upload URL: %w", err)
 	}
-	rawURL, err := url.Parse(fmt.Sprintf("%s://%s/raw/", u.Scheme, u.Hostname()))
+	rawURL, err := url.Parse(fmt.Sprintf("%s://%s/raw/", u.Scheme, u.Host))
 	if err != nil {
 		return apiHost{}, fmt.Errorf("failed to parse GHES Raw URL: %w", err)
 	}
@@ -388,8 +775,64 @@ func newGHESHost(hostname string) (apiHost, error) {
 	}, nil
 }
 
-// Note that this does not handle ports yet, so development environments are out.
-func parseAPIHost(s string) (apiHost, error) {
+// APIEndpoints overrides the endpoints derived from the GitHub host, e.g. to target a local
+// mock of the API. Empty fields keep the derived endpoint.
+type APIEndpoints struct {
+	// RESTURL is the root of the REST API, e.g. https://github.example.com/api/v3/
+	RESTURL string
+
+	// GraphQLURL is the GraphQL endpoint, e.g. https://github.example.com/api/graphql
+	GraphQLURL string
+
+	// UploadURL is the root of the uploads API, e.g. https://github.example.com/api/uploads/
+	UploadURL string
+
+	// RawURL is the root of raw file contents, e.g. https://github.example.com/raw/
+	RawURL string
+}
+
+// parseAPIHost derives the API endpoints of the GitHub host and applies the endpoint overrides.
+// github.com and its subdomains mean dotcom, subdomains of ghe.com are GHEC tenants and any
+// other host, with or without a port, is GHES.
+func parseAPIHost(s string, endpoints APIEndpoints) (apiHost, error) {
+	host, err := parseHost(s)
+	if err != nil {
+		return apiHost{}, err
+	}
+
+	overrides := []struct {
+		name   string
+		value  string
+		target **url.URL
+		// dir is set for roots that need a trailing slash to resolve paths against
+		dir bool
+	}{
+		{"REST", endpoints.RESTURL, &host.baseRESTURL, true},
+		{"GraphQL", endpoints.GraphQLURL, &host.graphqlURL, false},
+		{"Upload", endpoints.UploadURL, &host.uploadURL, true},
+		{"Raw", endpoints.RawURL, &host.rawURL, true},
+	}
+	for _, o := range overrides {
+		if o.value == "" {
+			continue
+		}
+		u, err := url.Parse(o.value)
+		if err != nil {
+			return apiHost{}, fmt.Errorf("could not parse %s URL: %s", o.name, o.value)
+		}
+		if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return apiHost{}, fmt.Errorf("%s URL must be an absolute http or https URL: %s", o.name, o.value)
+		}
+		if o.dir && !strings.HasSuffix(u.Path, "/") {
+			u.Path += "/"
+		}
+		*o.target = u
+	}
+	return host, nil
+}
+
+// parseHost derives the API endpoints of the GitHub host
+func parseHost(s string) (apiHost, error) {
 	if s == "" {
 		return newDotcomHost()
 	}
@@ -399,15 +842,16 @@ func parseAPIHost(s string) (apiHost, error) {
 		return apiHost{}, fmt.Errorf("could not parse host as URL: %s", s)
 	}
 
-	if u.Scheme == "" {
+	if u.Scheme == "" || u.Host == "" {
 		return apiHost{}, fmt.Errorf("host must have a scheme (http or https): %s", s)
 	}
 
-	if strings.HasSuffix(u.Hostname(), "github.com") {
+	hostname := u.Hostname()
+	if hostname == "github.com" || strings.HasSuffix(hostname, ".github.com") {
 		return newDotcomHost()
 	}
 
-	if strings.HasSuffix(u.Hostname(), "ghe.com") {
+	if strings.HasSuffix(hostname, ".ghe.com") {
 		return newGHECHost(s)
 	}
 
@@ -425,13 +869,60 @@ func (t *userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error
 	return t.transport.RoundTrip(req)
 }
 
+// tokenSource returns the token to authenticate a GitHub API request with
+type tokenSource func(ctx context.Context) (string, error)
+
+// staticToken returns a token source that always returns token, e.g. a personal access token
+func staticToken(token string) tokenSource {
+	return func(context.Context) (string, error) {
+		return token, nil
+	}
+}
+
+// newTokenSource returns the token stored by the login command if there is one, the
+// installation tokens of the GitHub App if one is configured, or the personal access token
+// otherwise. The first token is obtained right away so a misconfiguration is reported at startup.
+func newTokenSource(ctx context.Context, token string, app githubapp.Config, login *oauth.TokenSource, apiHost apiHost) (tokenSource, error) {
+	if login != nil {
+		if _, err := login.Token(ctx); err != nil {
+			return nil, fmt.Errorf("failed to authenticate with the stored login: %w", err)
+		}
+		return login.Token, nil
+	}
+	if !app.Enabled() {
+		return staticToken(token), nil
+	}
+	source, err := githubapp.NewTokenSource(app, apiHost.baseRESTURL, http.DefaultTransport)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := source.Token(ctx); err != nil {
+		return nil, fmt.Errorf("failed to authenticate as GitHub App: %w", err)
+	}
+	return source.Token, nil
+}
+
+// checkAppUserEmail refuses a GitHub App with any user email check but off. Installation
+// tokens do not belong to a user, so there is no email address to verify them against, and
+// the operator has to turn the check off knowing any user email is then trusted.
+func checkAppUserEmail(check UserEmailCheck, app githubapp.Config) error {
+	if !app.Enabled() || check == UserEmailCheckOff {
+		return nil
+	}
+	return fmt.Errorf("the user email check cannot verify GitHub App installation tokens, set --user-email-check=%s to authenticate as a GitHub App", UserEmailCheckOff)
+}
+
 type bearerAuthTransport struct {
 	transport http.RoundTripper
-	token     string
+	tokens    tokenSource
 }
 
 func (t *bearerAuthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.tokens(req.Context())
+	if err != nil {
+		return nil, err
+	}
 	req = req.Clone(req.Context())
-	req.Header.Set("Authorization", "Bearer "+t.token)
+	req.Header.Set("Authorization", "Bearer "+token)
 	return t.transport.RoundTrip(req)
 }
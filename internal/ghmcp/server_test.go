@@ -0,0 +1,213 @@
+package ghmcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/github/github-mcp-server/pkg/access"
+	"github.com/github/github-mcp-server/pkg/githubapp"
+	"github.com/github/github-mcp-server/pkg/translations"
+	"github.com/shurcooL/githubv4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClients_UseCurrentToken(t *testing.T) {
+	var mu sync.Mutex
+	var seen []string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		seen = append(seen, r.Header.Get("Authorization"))
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/graphql" {
+			_, _ = w.Write([]byte(`{"data":{"viewer":{"login":"app[bot]"}}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"login":"app[bot]"}`))
+	}))
+	defer api.Close()
+	apiURL, err := url.Parse(api.URL + "/")
+	require.NoError(t, err)
+
+	// Every call returns a new token, as after a refresh
+	calls := 0
+	tokens := func(context.Context) (string, error) {
+		calls++
+		return fmt.Sprintf("ghs_token%d", calls), nil
+	}
+
+	restClient := newRESTClient(apiHost{baseRESTURL: apiURL}, tokens, "test", http.DefaultTransport)
+	for i := 0; i < 2; i++ {
+		_, _, err := restClient.Users.Get(context.Background(), "")
+		require.NoError(t, err)
+	}
+
+	gqlClient := githubv4.NewEnterpriseClient(api.URL+"/graphql", &http.Client{
+		Transport: &bearerAuthTransport{transport: http.DefaultTransport, tokens: tokens},
+	})
+	var query struct {
+		Viewer struct {
+			Login githubv4.String
+		}
+	}
+	require.NoError(t, gqlClient.Query(context.Background(), &query, nil))
+
+	assert.Equal(t, []string{"Bearer ghs_token1", "Bearer ghs_token2", "Bearer ghs_token3"}, seen)
+
+	// Requests fail if no token can be obtained
+	failing := newRESTClient(apiHost{baseRESTURL: apiURL}, func(context.Context) (string, error) {
+		return "", fmt.Errorf("installation suspended")
+	}, "test", http.DefaultTransport)
+	_, _, err = failing.Users.Get(context.Background(), "")
+	assert.ErrorContains(t, err, "installation suspended")
+}
+
+func TestCheckAppUserEmail(t *testing.T) {
+	app := githubapp.Config{AppID: 1, InstallationID: 2, PrivateKeyFile: "app.pem"}
+
+	assert.NoError(t, checkAppUserEmail(UserEmailCheckEnforce, githubapp.Config{}))
+	assert.NoError(t, checkAppUserEmail(UserEmailCheckOff, app))
+	assert.ErrorContains(t, checkAppUserEmail(UserEmailCheckEnforce, app), "--user-email-check=off")
+	assert.ErrorContains(t, checkAppUserEmail(UserEmailCheckReadOnly, app), "--user-email-check=off")
+}
+
+func TestParseAPIHost(t *testing.T) {
+	tests := []struct {
+		name      string
+		host      string
+		endpoints APIEndpoints
+		rest      string
+		graphql   string
+		upload    string
+		raw       string
+	}{
+		{
+			name:    "dotcom by default",
+			rest:    "https://api.github.com/",
+			graphql: "https://api.github.com/graphql",
+			upload:  "https://uploads.github.com",
+			raw:     "https://raw.githubusercontent.com/",
+		},
+		{
+			name:    "dotcom subdomain",
+			host:    "https://api.github.com",
+			rest:    "https://api.github.com/",
+			graphql: "https://api.github.com/graphql",
+			upload:  "https://uploads.github.com",
+			raw:     "https://raw.githubusercontent.com/",
+		},
+		{
+			name:    "GHEC tenant",
+			host:    "https://tenant.ghe.com",
+			rest:    "https://api.tenant.ghe.com/",
+			graphql: "https://api.tenant.ghe.com/graphql",
+			upload:  "https://uploads.tenant.ghe.com",
+			raw:     "https://raw.tenant.ghe.com/",
+		},
+		{
+			name:    "GHES on a host merely ending in github.com",
+			host:    "https://notgithub.com",
+			rest:    "https://notgithub.com/api/v3/",
+			graphql: "https://notgithub.com/api/graphql",
+			upload:  "https://notgithub.com/api/uploads/",
+			raw:     "https://notgithub.com/raw/",
+		},
+		{
+			name:    "GHES on a non-standard port",
+			host:    "http://localhost:8080",
+			rest:    "http://localhost:8080/api/v3/",
+			graphql: "http://localhost:8080/api/graphql",
+			upload:  "http://localhost:8080/api/uploads/",
+			raw:     "http://localhost:8080/raw/",
+		},
+		{
+			name: "explicit endpoints",
+			host: "https://github.example.com:8443",
+			endpoints: APIEndpoints{
+				RESTURL:    "http://127.0.0.1:9000",
+				GraphQLURL: "http://127.0.0.1:9000/graphql",
+				RawURL:     "http://127.0.0.1:9001/raw",
+			},
+			rest:    "http://127.0.0.1:9000/",
+			graphql: "http://127.0.0.1:9000/graphql",
+			upload:  "https://github.example.com:8443/api/uploads/",
+			raw:     "http://127.0.0.1:9001/raw/",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			host, err := parseAPIHost(tc.host, tc.endpoints)
+			require.NoError(t, err)
+			assert.Equal(t, tc.rest, host.baseRESTURL.String())
+			assert.Equal(t, tc.graphql, host.graphqlURL.String())
+			assert.Equal(t, tc.upload, host.uploadURL.String())
+			assert.Equal(t, tc.raw, host.rawURL.String())
+		})
+	}
+
+	_, err := parseAPIHost("localhost:8080", APIEndpoints{})
+	assert.ErrorContains(t, err, "host must have a scheme")
+
+	_, err = parseAPIHost("", APIEndpoints{GraphQLURL: "/graphql"})
+	assert.ErrorContains(t, err, "GraphQL URL must be an absolute http or https URL")
+}
+
+func TestNewMCPServer_LocalAPI(t *testing.T) {
+	// A local stand-in for the GitHub API, reached only through the endpoint overrides
+	var mu sync.Mutex
+	var paths []string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths = append(paths, r.URL.Path)
+		mu.Unlock()
+		assert.Equal(t, "Bearer local-token", r.Header.Get("Authorization"))
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/rest/repos/octo-org/hello/issues/1":
+			_, _ = w.Write([]byte(`{"number":1,"title":"Served offline"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer api.Close()
+
+	validator := access.NewValidatorWithProvider("dev@example.com", access.NewStaticProvider("octo-org/hello"))
+	defer func() { _ = validator.Close() }()
+
+	ghServer, err := NewMCPServer(MCPServerConfig{
+		Version:           "test",
+		Host:              "https://github.example.com:8443",
+		Endpoints:         APIEndpoints{RESTURL: api.URL + "/rest", GraphQLURL: api.URL + "/graphql", RawURL: api.URL + "/raw"},
+		Token:             "local-token",
+		UserEmail:         "dev@example.com",
+		AccessValidator:   validator,
+		UserEmailCheck:    UserEmailCheckOff,
+		EnabledToolsets:   []string{"issues"},
+		Translator:        translations.NullTranslationHelper,
+		ContentWindowSize: 5000,
+	})
+	require.NoError(t, err)
+
+	request, err := json.Marshal(map[string]any{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  "tools/call",
+		"params": map[string]any{
+			"name":      "get_issue",
+			"arguments": map[string]any{"owner": "octo-org", "repo": "hello", "issue_number": 1},
+		},
+	})
+	require.NoError(t, err)
+	response, err := json.Marshal(ghServer.HandleMessage(context.Background(), request))
+	require.NoError(t, err)
+
+	assert.Contains(t, string(response), "Served offline")
+	assert.Equal(t, []string{"/rest/repos/octo-org/hello/issues/1"}, paths)
+}
@@ -0,0 +1,249 @@
+// Package resourcemaptest provides an in-process fake of the resource-map SyncResourceMap
+// gRPC service, answering traversals from a programmable graph of nodes over a bufconn
+// listener, so the resource-map access provider can be tested without the mesh.
+package resourcemaptest
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"path"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	pb "github.com/Zomato/resource-map-service-client-golang/proto/resource-map-service"
+)
+
+// Node types and relations traversed by the resource-map access provider
+const (
+	MemberNodeType     = "zomato/member"
+	TeamNodeType       = "github/team"
+	RepositoryNodeType = "github/repository"
+
+	MemberOf = "memberOf"
+	AccessTo = "accessTo"
+)
+
+// Node is a node of the fake resource map
+type Node struct {
+	id         int
+	Type       string
+	Properties map[string]string
+}
+
+// edge is a relation between two nodes. Like the service, traversals only report the nodes
+// they reach, so edges carry nothing but their relation.
+type edge struct {
+	from, to int
+	relation string
+}
+
+// Server is a fake SyncResourceMap server listening on an in-memory bufconn listener.
+// It is safe for concurrent use, so the graph and failures can be changed between traversals.
+type Server struct {
+	listener *bufconn.Listener
+	server   *grpc.Server
+
+	mu       sync.Mutex
+	nodes    []*Node
+	edges    []edge
+	requests []*pb.TraverseRequest
+	err      error
+	delay    time.Duration
+}
+
+// NewServer starts a fake server with an empty graph, which is stopped when the test ends
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+
+	s := &Server{listener: bufconn.Listen(1 << 20)}
+	s.server = grpc.NewServer(grpc.UnknownServiceHandler(s.handle))
+	go func() { _ = s.server.Serve(s.listener) }()
+	t.Cleanup(s.Stop)
+	return s
+}
+
+// Target returns the endpoint to dial the server at together with DialOptions
+func (s *Server) Target() string {
+	return "passthrough:///resource-map"
+}
+
+// DialOptions returns the options connecting a client to the server instead of the network
+func (s *Server) DialOptions() []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return s.listener.DialContext(ctx)
+		}),
+	}
+}
+
+// Stop stops the server, so later traversals fail as if the service was unreachable
+func (s *Server) Stop() {
+	s.server.Stop()
+}
+
+// AddNode adds a node of the given type and properties to the graph
+func (s *Server) AddNode(nodeType string, properties map[string]string) *Node {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	node := &Node{id: len(s.nodes), Type: nodeType, Properties: properties}
+	s.nodes = append(s.nodes, node)
+	return node
+}
+
+// Relate adds a relation from one node to another
+func (s *Server) Relate(from *Node, relation string, to *Node) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.edges = append(s.edges, edge{from: from.id, to: to.id, relation: relation})
+}
+
+// AddMember adds a member node for the given email
+func (s *Server) AddMember(email string) *Node {
+	return s.AddNode(MemberNodeType, map[string]string{"name": email})
+}
+
+// AddTeam adds a GitHub team node
+func (s *Server) AddTeam(org, name string) *Node {
+	return s.AddNode(TeamNodeType, map[string]string{"org": org, "name": name})
+}
+
+// AddRepository adds a GitHub repository node
+func (s *Server) AddRepository(org, name string) *Node {
+	return s.AddNode(RepositoryNodeType, map[string]string{"org": org, "name": name})
+}
+
+// Join makes the member a member of the team
+func (s *Server) Join(member, team *Node) {
+	s.Relate(member, MemberOf, team)
+}
+
+// Grant gives the team access to the repository
+func (s *Server) Grant(team, repository *Node) {
+	s.Relate(team, AccessTo, repository)
+}
+
+// SetError makes every traversal fail with err, e.g. status.Error(codes.Unavailable, "down").
+// A nil error makes traversals succeed again.
+func (s *Server) SetError(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err
+}
+
+// SetDelay delays every traversal, or until the client gives up
+func (s *Server) SetDelay(delay time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.delay = delay
+}
+
+// Requests returns every traversal request received so far
+func (s *Server) Requests() []*pb.TraverseRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*pb.TraverseRequest{}, s.requests...)
+}
+
+// handle serves every method of the service, only Traverse is implemented
+func (s *Server) handle(_ any, stream grpc.ServerStream) error {
+	method, _ := grpc.MethodFromServerStream(stream)
+	if path.Base(method) != "Traverse" {
+		return status.Errorf(codes.Unimplemented, "method %s not implemented", method)
+	}
+
+	request := &pb.TraverseRequest{}
+	if err := stream.RecvMsg(request); err != nil {
+		return err
+	}
+	response, err := s.traverse(stream.Context(), request)
+	if err != nil {
+		return err
+	}
+	return stream.SendMsg(response)
+}
+
+// traverse walks the relations of the request from every node matching its source, in order,
+// and returns the nodes reached of the output type
+func (s *Server) traverse(ctx context.Context, request *pb.TraverseRequest) (*pb.TraverseResponse, error) {
+	s.mu.Lock()
+	s.requests = append(s.requests, request)
+	delay, err := s.delay, s.err
+	s.mu.Unlock()
+
+	if delay > 0 {
+		select {
+		case <-ctx.Done():
+			return nil, status.FromContextError(ctx.Err()).Err()
+		case <-time.After(delay):
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// The ids of the reached nodes
+	reached := make(map[int]bool)
+	for _, node := range s.nodes {
+		if node.Type == request.SourceNodeType && hasProperties(node, request.SourceNodeProperties) {
+			reached[node.id] = true
+		}
+	}
+
+	for _, relation := range request.Relations {
+		next := make(map[int]bool)
+		for _, edge := range s.edges {
+			if !reached[edge.from] || edge.relation != relation.RelationType {
+				continue
+			}
+			target := s.nodes[edge.to]
+			if relation.TargetNodeType != "" && target.Type != relation.TargetNodeType {
+				continue
+			}
+			next[target.id] = true
+		}
+		reached = next
+	}
+
+	ids := make([]int, 0, len(reached))
+	for id := range reached {
+		if request.OutputType == "" || s.nodes[id].Type == request.OutputType {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+
+	response := &pb.TraverseResponse{}
+	for _, id := range ids {
+		response.Output = append(response.Output, &pb.TraverseOutput{
+			Node: &pb.Node{Type: s.nodes[id].Type, Properties: s.nodes[id].Properties},
+		})
+	}
+	return response, nil
+}
+
+// hasProperties reports whether the node has every given property
+func hasProperties(node *Node, properties map[string]string) bool {
+	for key, value := range properties {
+		if node.Properties[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// String describes the node, e.g. in test failures
+func (n *Node) String() string {
+	return fmt.Sprintf("%s %v", n.Type, n.Properties)
+}
@@ -0,0 +1,96 @@
+package resourcemaptest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/Zomato/resource-map-service-client-golang/proto/resource-map-service"
+)
+
+func newClient(t *testing.T, server *Server) pb.SyncResourceMapClient {
+	t.Helper()
+	opts := append(server.DialOptions(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := grpc.NewClient(server.Target(), opts...)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	client, err := pb.NewSyncClient(conn)
+	require.NoError(t, err)
+	return client
+}
+
+// outputProperties returns the properties of every output node of a traversal
+func outputProperties(res *pb.TraverseResponse) []map[string]string {
+	var properties []map[string]string
+	for _, output := range res.GetOutput() {
+		properties = append(properties, output.GetNode().GetProperties())
+	}
+	return properties
+}
+
+func TestServer_Traverse(t *testing.T) {
+	server := NewServer(t)
+	member := server.AddMember("test@example.com")
+	team := server.AddTeam("octo-org", "backend")
+	api := server.AddRepository("octo-org", "api")
+	server.Join(member, team)
+	server.Grant(team, api)
+	server.Grant(team, server.AddRepository("octo-org", "docs"))
+
+	client := newClient(t, server)
+
+	t.Run("single hop reports the target nodes", func(t *testing.T) {
+		res, err := client.Traverse(context.Background(), &pb.TraverseRequest{
+			SourceNodeType:       TeamNodeType,
+			SourceNodeProperties: map[string]string{"org": "octo-org", "name": "backend"},
+			Relations:            []*pb.TraverseRelation{{RelationType: AccessTo, TargetNodeType: RepositoryNodeType}},
+			OutputType:           RepositoryNodeType,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []map[string]string{
+			{"org": "octo-org", "name": "api"},
+			{"org": "octo-org", "name": "docs"},
+		}, outputProperties(res))
+	})
+
+	t.Run("relations are followed in order", func(t *testing.T) {
+		res, err := client.Traverse(context.Background(), &pb.TraverseRequest{
+			SourceNodeType:       MemberNodeType,
+			SourceNodeProperties: map[string]string{"name": "test@example.com"},
+			Relations: []*pb.TraverseRelation{
+				{RelationType: MemberOf, TargetNodeType: TeamNodeType},
+				{RelationType: AccessTo, TargetNodeType: RepositoryNodeType},
+			},
+			OutputType: RepositoryNodeType,
+		})
+		require.NoError(t, err)
+		assert.Len(t, res.GetOutput(), 2)
+	})
+
+	t.Run("output type filters the reached nodes", func(t *testing.T) {
+		res, err := client.Traverse(context.Background(), &pb.TraverseRequest{
+			SourceNodeType:       MemberNodeType,
+			SourceNodeProperties: map[string]string{"name": "test@example.com"},
+			Relations:            []*pb.TraverseRelation{{RelationType: MemberOf}},
+			OutputType:           RepositoryNodeType,
+		})
+		require.NoError(t, err)
+		assert.Empty(t, res.GetOutput())
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		server.SetError(status.Error(codes.PermissionDenied, "denied"))
+		_, err := client.Traverse(context.Background(), &pb.TraverseRequest{SourceNodeType: MemberNodeType})
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+		server.SetError(nil)
+	})
+
+	assert.Len(t, server.Requests(), 4)
+}
@@ -0,0 +1,168 @@
+package access
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DefaultCacheMaxAge is how old a cached repository set may be to skip the startup fetch
+const DefaultCacheMaxAge = time.Hour
+
+// repositoryCache is the on-disk format of the last successfully fetched repository set
+type repositoryCache struct {
+	UserEmail    string                `json:"user_email"`
+	Host         string                `json:"host"`
+	FetchedAt    time.Time             `json:"fetched_at"`
+	Repositories map[string]Permission `json:"repositories"`
+	Teams        map[string][]Team     `json:"teams,omitempty"`
+	// Grants and Denies are the wildcard grants and the deny rules in effect
+	Grants map[string]Permission `json:"grants,omitempty"`
+	Denies []string              `json:"denies,omitempty"`
+}
+
+// DefaultCachePath returns the cache file for the user under the user config directory,
+// e.g. ~/.config/github-mcp-server/access-cache/<hash>.json on Linux. The email is hashed
+// so the file name does not reveal it.
+func DefaultCachePath(userEmail string) (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find user config directory: %w", err)
+	}
+	sum := sha256.Sum256([]byte(strings.ToLower(userEmail)))
+	return filepath.Join(configDir, "github-mcp-server", "access-cache", hex.EncodeToString(sum[:8])+".json"), nil
+}
+
+// SetCache persists every successfully fetched repository set to path, and lets Start
+// use it instead of fetching when it is younger than maxAge or the provider is down.
+// An empty path disables the cache.
+func (v *Validator) SetCache(path string, maxAge time.Duration) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.cachePath = path
+	v.cacheMaxAge = maxAge
+}
+
+// loadCache reads the cached repository set. It fails if the cache belongs to another user
+// or was fetched for another GitHub host.
+func loadCache(path, userEmail, host string) (*repositoryCache, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cache repositoryCache
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return nil, fmt.Errorf("failed to parse access cache %s: %w", path, err)
+	}
+	if !strings.EqualFold(cache.UserEmail, userEmail) {
+		return nil, fmt.Errorf("access cache %s belongs to another user", path)
+	}
+	if cache.Host != host {
+		return nil, fmt.Errorf("access cache %s was fetched for another GitHub host", path)
+	}
+	return &cache, nil
+}
+
+// writeCache atomically replaces the cache file, readable only by the current user
+func writeCache(path string, cache *repositoryCache) error {
+	data, err := json.Marshal(cache)
+	if err != nil {
+		return fmt.Errorf("failed to encode access cache: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create access cache directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write access cache: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write access cache: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write access cache: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write access cache: %w", err)
+	}
+	return nil
+}
+
+// loadFromCache replaces the cached set with the one on disk, keeping its fetch time so
+// the set is refreshed as soon as it is stale. It returns the age of the loaded set.
+func (v *Validator) loadFromCache() (time.Duration, error) {
+	v.mu.RLock()
+	path := v.cachePath
+	host := v.host
+	v.mu.RUnlock()
+
+	if path == "" {
+		return 0, fmt.Errorf("access cache disabled")
+	}
+	cache, err := loadCache(path, v.userEmail, host)
+	if err != nil {
+		return 0, err
+	}
+	rules := newAccessRules()
+	for pattern, permission := range cache.Grants {
+		if err := rules.grant(pattern, permission); err != nil {
+			return 0, fmt.Errorf("invalid access cache %s: %w", path, err)
+		}
+	}
+	for _, repoURL := range cache.Denies {
+		if err := rules.deny(repoURL); err != nil {
+			return 0, fmt.Errorf("invalid access cache %s: %w", path, err)
+		}
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.accessibleRepos = cache.Repositories
+	if v.accessibleRepos == nil {
+		v.accessibleRepos = make(map[string]Permission)
+	}
+	v.grantingTeams = cache.Teams
+	if v.grantingTeams == nil {
+		v.grantingTeams = make(map[string][]Team)
+	}
+	v.rules = rules
+	v.lastRefresh = cache.FetchedAt
+	v.initialized = true
+	return time.Since(cache.FetchedAt), nil
+}
+
+// saveToCache persists the current set, logging rather than returning failures since
+// the cache is only an optimization
+func (v *Validator) saveToCache() {
+	v.mu.RLock()
+	path := v.cachePath
+	cache := &repositoryCache{
+		UserEmail:    v.userEmail,
+		Host:         v.host,
+		FetchedAt:    v.lastRefresh,
+		Repositories: v.accessibleRepos,
+		Teams:        v.grantingTeams,
+		Grants:       v.rules.grantPatterns(),
+		Denies:       v.rules.denyRules(),
+	}
+	logger := v.logger
+	// The maps are replaced rather than modified on refresh, so they can be encoded after unlocking
+	v.mu.RUnlock()
+
+	if path == "" {
+		return
+	}
+	if err := writeCache(path, cache); err != nil {
+		logger.Warn("failed to save access cache", "path", path, "error", err)
+	}
+}
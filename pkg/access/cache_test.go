@@ -0,0 +1,138 @@
+package access
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultCachePath(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	path, err := DefaultCachePath("Test@Example.com")
+	require.NoError(t, err)
+	assert.NotContains(t, path, "example.com")
+
+	lower, err := DefaultCachePath("test@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, path, lower)
+
+	other, err := DefaultCachePath("other@example.com")
+	require.NoError(t, err)
+	assert.NotEqual(t, path, other)
+}
+
+func TestValidator_RefreshWritesCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "cache.json")
+	validator := NewValidatorWithProvider("test@example.com", repositoriesProvider{
+		{Org: "org", Repo: "repo", Permission: PermissionWrite, Teams: []Team{{Org: "org", Name: "team"}}},
+	})
+	validator.SetCache(path, time.Hour)
+
+	require.NoError(t, validator.Refresh(context.Background()))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	cache, err := loadCache(path, "TEST@example.com", DefaultHost)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Permission{"github.com/org/repo": PermissionWrite}, cache.Repositories)
+	assert.Equal(t, map[string][]Team{"github.com/org/repo": {{Org: "org", Name: "team"}}}, cache.Teams)
+	assert.WithinDuration(t, time.Now(), cache.FetchedAt, time.Minute)
+
+	_, err = loadCache(path, "other@example.com", DefaultHost)
+	require.Error(t, err)
+}
+
+func TestValidator_StartFromFreshCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.json")
+	require.NoError(t, writeCache(path, &repositoryCache{
+		UserEmail:    "test@example.com",
+		Host:         DefaultHost,
+		FetchedAt:    time.Now().Add(-time.Minute),
+		Repositories: map[string]Permission{"github.com/org/cached": PermissionRead},
+		Teams:        map[string][]Team{"github.com/org/cached": {{Org: "org", Name: "team"}}},
+	}))
+
+	// The provider never answers in time, so a successful start must come from the cache
+	provider := &flakyProvider{failures: 1000}
+	validator := NewValidatorWithProvider("test@example.com", provider)
+	validator.SetCache(path, time.Hour)
+
+	require.NoError(t, validator.Start(context.Background(), fastRetries(0, FailClosed)))
+
+	accessible, err := validator.IsRepositoryAccessible("org/cached")
+	require.NoError(t, err)
+	assert.True(t, accessible)
+	teams, err := validator.GrantingTeams("org/cached")
+	require.NoError(t, err)
+	assert.Equal(t, []Team{{Org: "org", Name: "team"}}, teams)
+
+	// The cached set is revalidated in the background
+	assert.Eventually(t, func() bool { return provider.callCount() > 0 }, time.Second, time.Millisecond)
+}
+
+func TestValidator_StartFromStaleCacheWhenProviderIsDown(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.json")
+	fetchedAt := time.Now().Add(-48 * time.Hour)
+	require.NoError(t, writeCache(path, &repositoryCache{
+		UserEmail:    "test@example.com",
+		Host:         DefaultHost,
+		FetchedAt:    fetchedAt,
+		Repositories: map[string]Permission{"github.com/org/cached": PermissionRead},
+	}))
+
+	validator := NewValidatorWithProvider("test@example.com", &flakyProvider{failures: 1000})
+	validator.SetCache(path, time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, validator.Start(ctx, fastRetries(20*time.Millisecond, FailClosed)))
+
+	accessible, err := validator.IsRepositoryAccessible("org/cached")
+	require.NoError(t, err)
+	assert.True(t, accessible)
+	assert.WithinDuration(t, fetchedAt, validator.Status().LastRefresh, time.Second)
+}
+
+func TestValidator_StartRefreshesStaleCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.json")
+	require.NoError(t, writeCache(path, &repositoryCache{
+		UserEmail:    "test@example.com",
+		Host:         DefaultHost,
+		FetchedAt:    time.Now().Add(-48 * time.Hour),
+		Repositories: map[string]Permission{"github.com/org/cached": PermissionRead},
+	}))
+
+	validator := NewValidatorWithProvider("test@example.com", NewStaticProvider("org/live"))
+	validator.SetCache(path, time.Hour)
+
+	require.NoError(t, validator.Start(context.Background(), fastRetries(0, FailClosed)))
+
+	assert.ElementsMatch(t, []string{"github.com/org/live"}, validator.GetAccessibleRepositories())
+
+	cache, err := loadCache(path, "test@example.com", DefaultHost)
+	require.NoError(t, err)
+	assert.Contains(t, cache.Repositories, "github.com/org/live")
+}
+
+func TestValidator_StartIgnoresCacheOfAnotherUser(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.json")
+	require.NoError(t, writeCache(path, &repositoryCache{
+		UserEmail:    "other@example.com",
+		Host:         DefaultHost,
+		FetchedAt:    time.Now(),
+		Repositories: map[string]Permission{"github.com/org/cached": PermissionRead},
+	}))
+
+	validator := NewValidatorWithProvider("test@example.com", &flakyProvider{failures: 1000})
+	validator.SetCache(path, time.Hour)
+
+	require.Error(t, validator.Start(context.Background(), fastRetries(0, FailClosed)))
+}
@@ -5,101 +5,342 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
 
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
 
 	pb "github.com/Zomato/resource-map-service-client-golang/proto/resource-map-service"
 )
 
-type repository struct {
-	repo string
-	org  string
+// Repository identifies a GitHub repository by its organization and name, along with
+// the permission the user has on it. A PermissionNone permission means the provider did
+// not say, in which case the validator falls back to its default permission.
+type Repository struct {
+	Org        string
+	Repo       string
+	Permission Permission
+	// Teams is the chain of teams granting access, starting with the team the user is a
+	// member of. It is empty when access is not granted through a team.
+	Teams []Team
 }
 
-func (r *repository) GetOrg() string {
+func (r *Repository) GetOrg() string {
 	if r == nil {
 		return ""
 	}
 
-	return r.org
+	return r.Org
 }
 
-func (r *repository) GetRepo() string {
+func (r *Repository) GetRepo() string {
 	if r == nil {
 		return ""
 	}
 
-	return r.repo
+	return r.Repo
 }
 
-func newConnection(authority, host string, tlsEnabled bool) (*grpc.ClientConn, error) {
-	conn, err := grpc.NewClient(host, grpcDialOptions(authority, tlsEnabled)...)
+func (r *Repository) GetPermission() Permission {
+	if r == nil {
+		return PermissionNone
+	}
+
+	return r.Permission
+}
+
+func (r *Repository) GetTeams() []Team {
+	if r == nil {
+		return nil
+	}
+
+	return r.Teams
+}
+
+// Team identifies a GitHub team by its organization and name
+type Team struct {
+	Org  string `json:"org"`
+	Name string `json:"name"`
+}
+
+// String returns the team as "team NAME (org ORG)"
+func (t Team) String() string {
+	return fmt.Sprintf("team %s (org %s)", t.Name, t.Org)
+}
+
+// DescribeGrant explains how access was granted, e.g. "allowed via team X (org Y)".
+// Longer chains list every team in order. It returns an empty string for an empty chain.
+func DescribeGrant(teams []Team) string {
+	if len(teams) == 0 {
+		return ""
+	}
+	names := make([]string, len(teams))
+	for i, team := range teams {
+		names[i] = team.String()
+	}
+	return "allowed via " + strings.Join(names, " > ")
+}
+
+// Defaults for the resource-map service connection
+const (
+	DefaultResourceMapEndpoint       = "es-resource-map-service-v2.mesh:80"
+	DefaultResourceMapAuthority      = "es-resource-map-service-v2"
+	DefaultResourceMapDialTimeout    = 10 * time.Second
+	DefaultResourceMapRequestTimeout = 30 * time.Second
+)
+
+// ResourceMapConfig configures the connection to the resource-map gRPC service
+type ResourceMapConfig struct {
+	// Endpoint is the host:port of the service
+	Endpoint string
+	// Authority overrides the :authority header, e.g. for routing through a service mesh
+	Authority string
+	// TLS enables transport security, verified against the system roots unless CAFile is set
+	TLS bool
+	// CAFile is a PEM bundle of root CAs used instead of the system roots
+	CAFile string
+	// CertFile and KeyFile are a PEM client certificate and key for mutual TLS
+	CertFile string
+	KeyFile  string
+	// DialTimeout bounds each attempt to establish the connection
+	DialTimeout time.Duration
+	// RequestTimeout bounds each Traverse call, zero means no timeout
+	RequestTimeout time.Duration
+	// DialOptions are applied after the options built from the fields above, e.g. to dial an
+	// in-process server such as the one of package resourcemaptest
+	DialOptions []grpc.DialOption
+}
+
+// DefaultResourceMapConfig returns the configuration for the in-mesh resource-map service
+func DefaultResourceMapConfig() ResourceMapConfig {
+	return ResourceMapConfig{
+		Endpoint:       DefaultResourceMapEndpoint,
+		Authority:      DefaultResourceMapAuthority,
+		DialTimeout:    DefaultResourceMapDialTimeout,
+		RequestTimeout: DefaultResourceMapRequestTimeout,
+	}
+}
+
+// ResourceMapProvider is the AccessProvider backed by the resource-map gRPC service.
+// It keeps a single connection to the service that is created on first use and shared
+// by every traversal, and the raw requests and responses of its last traversal for debugging.
+type ResourceMapProvider struct {
+	config ResourceMapConfig
+	logger *slog.Logger
+
+	connMu sync.Mutex
+	conn   *grpc.ClientConn
+	client pb.SyncResourceMapClient
+
+	mu       sync.Mutex
+	request  string
+	response string
+}
+
+// NewResourceMapProvider creates a provider that queries the in-mesh resource-map service
+func NewResourceMapProvider() *ResourceMapProvider {
+	return NewResourceMapProviderWithConfig(DefaultResourceMapConfig())
+}
+
+// NewResourceMapProviderWithConfig creates a provider that queries the resource-map service
+// described by config. Empty fields fall back to DefaultResourceMapConfig, except that the
+// mesh authority only applies to the default endpoint: a custom endpoint is addressed, and
+// verified over TLS, by its own host unless Authority is set.
+func NewResourceMapProviderWithConfig(config ResourceMapConfig) *ResourceMapProvider {
+	defaults := DefaultResourceMapConfig()
+	if config.Endpoint == "" {
+		config.Endpoint = defaults.Endpoint
+	}
+	if config.Endpoint == defaults.Endpoint && config.Authority == "" {
+		config.Authority = defaults.Authority
+	}
+	if config.DialTimeout <= 0 {
+		config.DialTimeout = defaults.DialTimeout
+	}
+	return &ResourceMapProvider{config: config, logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+}
+
+// SetLogger sets the logger used to report team nodes that cannot be traversed
+func (p *ResourceMapProvider) SetLogger(logger *slog.Logger) {
+	if logger == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.logger = logger
+}
+
+// AccessibleRepositories traverses the resource map from the member node to the repositories it can access
+func (p *ResourceMapProvider) AccessibleRepositories(ctx context.Context, userEmail string) ([]Repository, error) {
+	repos, requestJSON, responseJSON, err := p.traverse(ctx, userEmail)
+
+	p.mu.Lock()
+	p.request = requestJSON
+	p.response = responseJSON
+	p.mu.Unlock()
+
 	if err != nil {
-		return nil, fmt.Errorf("failed to create gRPC connection: %v", err)
+		return nil, err
 	}
-	return conn, nil
+	return repos, nil
+}
+
+// LastExchange returns the requests and responses of the last traversal, each encoded as a
+// JSON array with the lookup of the member's teams first and then one entry per team
+func (p *ResourceMapProvider) LastExchange() (string, string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.request, p.response
+}
+
+// Close closes the connection to the resource-map service, if one was opened
+func (p *ResourceMapProvider) Close() error {
+	p.connMu.Lock()
+	defer p.connMu.Unlock()
+
+	if p.conn == nil {
+		return nil
+	}
+	err := p.conn.Close()
+	p.conn, p.client = nil, nil
+	return err
+}
+
+// resourceMapClient returns the shared resource-map client, connecting on first use
+func (p *ResourceMapProvider) resourceMapClient() (pb.SyncResourceMapClient, error) {
+	p.connMu.Lock()
+	defer p.connMu.Unlock()
+
+	if p.conn != nil {
+		return p.client, nil
+	}
+
+	conn, err := newConnection(p.config)
+	if err != nil {
+		return nil, err
+	}
+	client, err := pb.NewSyncClient(conn)
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("failed to create resource map client: %w", err)
+	}
+
+	p.conn, p.client = conn, client
+	return client, nil
 }
 
-func loadSystemRootCAs() (*x509.CertPool, error) {
-	pool, err := x509.SystemCertPool()
+func newConnection(config ResourceMapConfig) (*grpc.ClientConn, error) {
+	opts, err := grpcDialOptions(config)
 	if err != nil {
 		return nil, err
 	}
-	return pool, nil
+	conn, err := grpc.NewClient(config.Endpoint, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gRPC connection: %w", err)
+	}
+	return conn, nil
 }
 
-func grpcDialOptions(authority string, tlsEnabled bool) []grpc.DialOption {
+func grpcDialOptions(config ResourceMapConfig) ([]grpc.DialOption, error) {
 	var opts []grpc.DialOption
 
-	if authority != "" {
-		opts = append(opts, grpc.WithAuthority(authority))
+	if config.Authority != "" {
+		opts = append(opts, grpc.WithAuthority(config.Authority))
+	}
+
+	if config.DialTimeout > 0 {
+		opts = append(opts, grpc.WithConnectParams(grpc.ConnectParams{
+			Backoff:           backoff.DefaultConfig,
+			MinConnectTimeout: config.DialTimeout,
+		}))
 	}
 
-	if !tlsEnabled {
+	if config.TLS {
+		tlsConfig, err := resourceMapTLSConfig(config)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
+	} else {
 		opts = append(opts, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	}
+
+	// Traversals become child spans of the calls that make them when tracing is enabled
+	opts = append(opts, grpc.WithStatsHandler(otelgrpc.NewClientHandler()))
+
+	return append(opts, config.DialOptions...), nil
+}
+
+// resourceMapTLSConfig builds the TLS configuration from the CA bundle and client certificate files
+func resourceMapTLSConfig(config ResourceMapConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if config.CAFile != "" {
+		pem, err := os.ReadFile(config.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		rootCAs := x509.NewCertPool()
+		if !rootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", config.CAFile)
+		}
+		tlsConfig.RootCAs = rootCAs
 	} else {
-		rootCAs, err := loadSystemRootCAs()
+		rootCAs, err := x509.SystemCertPool()
 		if err != nil {
-			panic(fmt.Errorf("failed to load root CAs: %w", err))
+			return nil, fmt.Errorf("failed to load root CAs: %w", err)
 		}
+		tlsConfig.RootCAs = rootCAs
+	}
 
-		tlsConfig := &tls.Config{
-			RootCAs: rootCAs,
+	if config.CertFile != "" || config.KeyFile != "" {
+		if config.CertFile == "" || config.KeyFile == "" {
+			return nil, errors.New("both a client certificate and key are required for mutual TLS")
 		}
-
-		creds := credentials.NewTLS(tlsConfig)
-		opts = append(opts, grpc.WithTransportCredentials(creds))
+		cert, err := tls.LoadX509KeyPair(config.CertFile, config.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
 	}
 
-	return opts
+	return tlsConfig, nil
 }
 
-func initialise(authority, endpoint string, tlsEnabled bool) (pb.SyncResourceMapClient, error) {
-	conn, err := newConnection(authority, endpoint, tlsEnabled)
-	if err != nil {
-		log.Fatalf("Error creating connection: %v\n", err)
-		return nil, err
-	}
-	rmapClient, err := pb.NewSyncClient(conn)
-	if err != nil {
-		log.Fatalf("Error creating resource map client: %v", err)
-		return nil, err
-	}
-	return rmapClient, nil
+// GetAllAccessibleRepos queries the in-mesh resource-map service over a one-off connection.
+// Long running callers should use a ResourceMapProvider, which reuses its connection.
+func GetAllAccessibleRepos(email string) ([]Repository, string, string, error) {
+	provider := NewResourceMapProvider()
+	defer func() { _ = provider.Close() }()
+	return provider.traverse(context.Background(), email)
 }
 
-func GetAllAccessibleRepos(email string) ([]repository, string, string, error) {
-	client, err := initialise("es-resource-map-service-v2", "es-resource-map-service-v2.mesh:80", false)
+// traverse walks memberOf edges from the member node to its teams, then accessTo edges from
+// each team to the repositories it can access, so every repository records the team that
+// grants it. It returns the repositories along with the JSON encoded requests and responses.
+func (p *ResourceMapProvider) traverse(ctx context.Context, email string) ([]Repository, string, string, error) {
+	client, err := p.resourceMapClient()
 	if err != nil {
 		return nil, "", "", err
 	}
 
-	// Create the request
-	request := &pb.TraverseRequest{
+	// The timeout applies to each call, so members of many teams are not cut short
+	exchange := &traverseExchange{timeout: p.config.RequestTimeout}
+
+	// Find the teams the member belongs to
+	teamsRequest := &pb.TraverseRequest{
 		SourceNodeType: "zomato/member",
 		SourceNodeLabels: []string{
 			"zomato/member",
@@ -113,37 +354,111 @@ func GetAllAccessibleRepos(email string) ([]repository, string, string, error) {
 				TargetNodeType:   "github/team",
 				TargetNodeLabels: []string{"github/team"},
 			},
-			{
-				RelationType:     "accessTo",
-				TargetNodeType:   "github/repository",
-				TargetNodeLabels: []string{"github/repository"},
-			},
 		},
-		OutputType: "github/repository",
+		OutputType: "github/team",
 	}
-
-	// Serialize request
-	requestBytes, err := json.Marshal(request)
+	teamsResponse, err := exchange.traverse(ctx, client, teamsRequest)
 	if err != nil {
-		return nil, "", "", fmt.Errorf("failed to serialize request: %w", err)
+		return nil, exchange.requestJSON(), exchange.responseJSON(), err
 	}
-	requestJSON := string(requestBytes)
 
-	// Make the gRPC call
-	res, err := client.Traverse(context.Background(), request)
+	// Find the repositories each team has access to. Teams missing the properties to look them
+	// up by are reported, as a member whose teams all lack them would silently get no access.
+	accessibleRepos := []Repository{}
+	skipped := 0
+	for _, output := range teamsResponse.GetOutput() {
+		properties := output.GetNode().GetProperties()
+		team := Team{Org: properties["org"], Name: properties["name"]}
+		if team.Org == "" || team.Name == "" {
+			skipped++
+			continue
+		}
+
+		reposRequest := &pb.TraverseRequest{
+			SourceNodeType: "github/team",
+			SourceNodeLabels: []string{
+				"github/team",
+			},
+			SourceNodeProperties: map[string]string{
+				"org":  team.Org,
+				"name": team.Name,
+			},
+			Relations: []*pb.TraverseRelation{
+				{
+					RelationType:     "accessTo",
+					TargetNodeType:   "github/repository",
+					TargetNodeLabels: []string{"github/repository"},
+				},
+			},
+			OutputType: "github/repository",
+		}
+		reposResponse, err := exchange.traverse(ctx, client, reposRequest)
+		if err != nil {
+			return nil, exchange.requestJSON(), exchange.responseJSON(), err
+		}
+		accessibleRepos = append(accessibleRepos, repositoriesFromResponse(reposResponse, []Team{team})...)
+	}
+	if skipped > 0 {
+		p.mu.Lock()
+		logger := p.logger
+		p.mu.Unlock()
+		logger.Warn("skipped resource-map teams without an org or name property", "user", email, "skipped", skipped, "teams", len(teamsResponse.GetOutput()))
+	}
+
+	return accessibleRepos, exchange.requestJSON(), exchange.responseJSON(), nil
+}
+
+// traverseExchange makes the calls of a traversal, each bounded by timeout unless it is zero,
+// and records their requests and responses
+type traverseExchange struct {
+	timeout   time.Duration
+	requests  []*pb.TraverseRequest
+	responses []*pb.TraverseResponse
+}
+
+func (e *traverseExchange) traverse(ctx context.Context, client pb.SyncResourceMapClient, request *pb.TraverseRequest) (*pb.TraverseResponse, error) {
+	e.requests = append(e.requests, request)
+	if e.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.timeout)
+		defer cancel()
+	}
+	response, err := client.Traverse(ctx, request)
 	if err != nil {
-		return nil, requestJSON, "", err
+		return nil, err
 	}
+	e.responses = append(e.responses, response)
+	return response, nil
+}
+
+// requestJSON returns the requests as a JSON array
+func (e *traverseExchange) requestJSON() string {
+	return exchangeJSON(e.requests)
+}
 
-	// Serialize response
-	responseBytes, err := json.Marshal(res)
+// responseJSON returns the responses as a JSON array, or an empty string if there were none
+func (e *traverseExchange) responseJSON() string {
+	if len(e.responses) == 0 {
+		return ""
+	}
+	return exchangeJSON(e.responses)
+}
+
+func exchangeJSON[T any](messages []T) string {
+	data, err := json.Marshal(messages)
 	if err != nil {
-		return nil, requestJSON, "", fmt.Errorf("failed to serialize response: %w", err)
+		return ""
 	}
-	responseJSON := string(responseBytes)
+	return string(data)
+}
 
-	accessibleRepos := []repository{}
-	for _, output := range res.Output {
+// repositoriesFromResponse returns the repository nodes of a traversal, granted through teams.
+// The response only carries the properties of the output nodes, which every team shares, and
+// not those of the accessTo edges, so no permission is reported: the validator's default
+// permission applies, and finer levels come from the policy or override file.
+func repositoriesFromResponse(res *pb.TraverseResponse, teams []Team) []Repository {
+	repos := []Repository{}
+	for _, output := range res.GetOutput() {
 		properties := output.GetNode().GetProperties()
 
 		var repo, org string
@@ -155,12 +470,12 @@ func GetAllAccessibleRepos(email string) ([]repository, string, string, error) {
 		}
 
 		if repo != "" && org != "" {
-			accessibleRepos = append(accessibleRepos, repository{
-				repo: repo,
-				org:  org,
+			repos = append(repos, Repository{
+				Repo:  repo,
+				Org:   org,
+				Teams: teams,
 			})
 		}
 	}
-
-	return accessibleRepos, requestJSON, responseJSON, nil
-}
\ No newline at end of file
+	return repos
+}
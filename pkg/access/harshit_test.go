@@ -0,0 +1,237 @@
+package access
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"log/slog"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/github/github-mcp-server/internal/resourcemaptest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newBufconnProvider returns a provider connected to the in-process fake resource-map server
+func newBufconnProvider(t *testing.T, server *resourcemaptest.Server, requestTimeout time.Duration) *ResourceMapProvider {
+	t.Helper()
+	provider := NewResourceMapProviderWithConfig(ResourceMapConfig{
+		Endpoint:       server.Target(),
+		RequestTimeout: requestTimeout,
+		DialOptions:    server.DialOptions(),
+	})
+	t.Cleanup(func() { _ = provider.Close() })
+	return provider
+}
+
+// writeTestCertificate writes a self-signed certificate and its key as PEM files and returns their paths
+func writeTestCertificate(t *testing.T) (string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "resource-map-test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+	return certFile, keyFile
+}
+
+func TestNewResourceMapProviderWithConfig(t *testing.T) {
+	t.Run("empty config uses the in-mesh defaults", func(t *testing.T) {
+		provider := NewResourceMapProviderWithConfig(ResourceMapConfig{})
+		assert.Equal(t, DefaultResourceMapEndpoint, provider.config.Endpoint)
+		assert.Equal(t, DefaultResourceMapAuthority, provider.config.Authority)
+		assert.Equal(t, DefaultResourceMapDialTimeout, provider.config.DialTimeout)
+		assert.Zero(t, provider.config.RequestTimeout)
+	})
+
+	t.Run("custom endpoint does not inherit the default authority", func(t *testing.T) {
+		provider := NewResourceMapProviderWithConfig(ResourceMapConfig{Endpoint: "localhost:9090", RequestTimeout: time.Second})
+		assert.Equal(t, "localhost:9090", provider.config.Endpoint)
+		assert.Empty(t, provider.config.Authority)
+		assert.Equal(t, time.Second, provider.config.RequestTimeout)
+	})
+
+	t.Run("default endpoint uses the mesh authority", func(t *testing.T) {
+		provider := NewResourceMapProviderWithConfig(ResourceMapConfig{Endpoint: DefaultResourceMapEndpoint})
+		assert.Equal(t, DefaultResourceMapAuthority, provider.config.Authority)
+	})
+
+	t.Run("overriding only the endpoint flag drops the mesh authority", func(t *testing.T) {
+		// The flags default to the in-mesh endpoint and an empty authority, so a custom TLS
+		// endpoint is verified against its own host rather than the mesh service name
+		config := DefaultResourceMapConfig()
+		config.Endpoint, config.Authority, config.TLS = "resource-map.example.com:443", "", true
+		provider := NewResourceMapProviderWithConfig(config)
+		assert.Equal(t, "resource-map.example.com:443", provider.config.Endpoint)
+		assert.Empty(t, provider.config.Authority)
+	})
+}
+
+func TestResourceMapProvider_ReusesConnection(t *testing.T) {
+	provider := NewResourceMapProviderWithConfig(ResourceMapConfig{Endpoint: "localhost:0"})
+
+	_, err := provider.resourceMapClient()
+	require.NoError(t, err)
+	conn := provider.conn
+	require.NotNil(t, conn)
+
+	_, err = provider.resourceMapClient()
+	require.NoError(t, err)
+	assert.Same(t, conn, provider.conn)
+
+	require.NoError(t, provider.Close())
+	assert.Nil(t, provider.conn)
+	require.NoError(t, provider.Close())
+}
+
+func TestResourceMapTLSConfig(t *testing.T) {
+	certFile, keyFile := writeTestCertificate(t)
+	emptyFile := filepath.Join(t.TempDir(), "empty.pem")
+	require.NoError(t, os.WriteFile(emptyFile, nil, 0600))
+
+	t.Run("custom CA bundle", func(t *testing.T) {
+		tlsConfig, err := resourceMapTLSConfig(ResourceMapConfig{TLS: true, CAFile: certFile})
+		require.NoError(t, err)
+		require.NotNil(t, tlsConfig.RootCAs)
+		assert.Empty(t, tlsConfig.Certificates)
+	})
+
+	t.Run("client certificate", func(t *testing.T) {
+		tlsConfig, err := resourceMapTLSConfig(ResourceMapConfig{TLS: true, CAFile: certFile, CertFile: certFile, KeyFile: keyFile})
+		require.NoError(t, err)
+		assert.Len(t, tlsConfig.Certificates, 1)
+	})
+
+	t.Run("missing CA file", func(t *testing.T) {
+		_, err := resourceMapTLSConfig(ResourceMapConfig{TLS: true, CAFile: filepath.Join(t.TempDir(), "missing.pem")})
+		require.ErrorContains(t, err, "failed to read CA file")
+	})
+
+	t.Run("CA file without certificates", func(t *testing.T) {
+		_, err := resourceMapTLSConfig(ResourceMapConfig{TLS: true, CAFile: emptyFile})
+		require.ErrorContains(t, err, "no certificates found")
+	})
+
+	t.Run("client certificate without key", func(t *testing.T) {
+		_, err := resourceMapTLSConfig(ResourceMapConfig{TLS: true, CertFile: certFile})
+		require.ErrorContains(t, err, "both a client certificate and key are required")
+	})
+
+	t.Run("dial options report TLS errors instead of panicking", func(t *testing.T) {
+		_, err := grpcDialOptions(ResourceMapConfig{TLS: true, CAFile: emptyFile})
+		require.Error(t, err)
+	})
+}
+
+func TestDescribeGrant(t *testing.T) {
+	assert.Empty(t, DescribeGrant(nil))
+	assert.Equal(t, "allowed via team backend (org octo-org)", DescribeGrant([]Team{{Org: "octo-org", Name: "backend"}}))
+	assert.Equal(t, "allowed via team backend (org octo-org) > team engineering (org octo-org)", DescribeGrant([]Team{
+		{Org: "octo-org", Name: "backend"},
+		{Org: "octo-org", Name: "engineering"},
+	}))
+}
+
+func TestResourceMapProvider_Traverse(t *testing.T) {
+	server := resourcemaptest.NewServer(t)
+	member := server.AddMember("test@example.com")
+	other := server.AddMember("other@example.com")
+	backend := server.AddTeam("octo-org", "backend")
+	platform := server.AddTeam("octo-org", "platform")
+	secret := server.AddTeam("octo-org", "secret")
+	api := server.AddRepository("octo-org", "api")
+	infra := server.AddRepository("octo-org", "infra")
+	docs := server.AddRepository("octo-org", "docs")
+	vault := server.AddRepository("octo-org", "vault")
+	server.Join(member, backend)
+	server.Join(member, platform)
+	server.Join(member, server.AddNode(resourcemaptest.TeamNodeType, map[string]string{"name": "team without org"}))
+	server.Join(other, secret)
+	server.Grant(backend, api)
+	server.Grant(backend, docs)
+	server.Grant(platform, infra)
+	server.Grant(platform, api)
+	server.Grant(secret, vault)
+
+	provider := newBufconnProvider(t, server, time.Second)
+	var logs bytes.Buffer
+	provider.SetLogger(slog.New(slog.NewTextHandler(&logs, nil)))
+	repos, err := provider.AccessibleRepositories(context.Background(), "test@example.com")
+	require.NoError(t, err)
+	assert.Contains(t, logs.String(), "skipped resource-map teams without an org or name property")
+	assert.Contains(t, logs.String(), "skipped=1 teams=3")
+	assert.ElementsMatch(t, []Repository{
+		{Org: "octo-org", Repo: "api", Teams: []Team{{Org: "octo-org", Name: "backend"}}},
+		{Org: "octo-org", Repo: "docs", Teams: []Team{{Org: "octo-org", Name: "backend"}}},
+		{Org: "octo-org", Repo: "infra", Teams: []Team{{Org: "octo-org", Name: "platform"}}},
+		{Org: "octo-org", Repo: "api", Teams: []Team{{Org: "octo-org", Name: "platform"}}},
+	}, repos)
+
+	// One traversal from the member to its teams, then one per team, all of them recorded
+	requests := server.Requests()
+	require.Len(t, requests, 3)
+	request, response := provider.LastExchange()
+	var recordedRequests, recordedResponses []any
+	require.NoError(t, json.Unmarshal([]byte(request), &recordedRequests))
+	require.NoError(t, json.Unmarshal([]byte(response), &recordedResponses))
+	assert.Len(t, recordedRequests, 3)
+	assert.Len(t, recordedResponses, 3)
+	assert.Equal(t, resourcemaptest.MemberNodeType, requests[0].SourceNodeType)
+	assert.Equal(t, map[string]string{"name": "test@example.com"}, requests[0].SourceNodeProperties)
+	assert.Equal(t, resourcemaptest.MemberOf, requests[0].Relations[0].RelationType)
+	for _, request := range requests[1:] {
+		assert.Equal(t, resourcemaptest.TeamNodeType, request.SourceNodeType)
+		assert.Equal(t, resourcemaptest.AccessTo, request.Relations[0].RelationType)
+		assert.Equal(t, resourcemaptest.RepositoryNodeType, request.OutputType)
+	}
+
+	// Unknown members have no repositories
+	repos, err = provider.AccessibleRepositories(context.Background(), "unknown@example.com")
+	require.NoError(t, err)
+	assert.Empty(t, repos)
+}
+
+func TestResourceMapProvider_RequestTimeoutPerCall(t *testing.T) {
+	server := resourcemaptest.NewServer(t)
+	member := server.AddMember("test@example.com")
+	for _, name := range []string{"backend", "platform", "docs"} {
+		team := server.AddTeam("octo-org", name)
+		server.Join(member, team)
+		server.Grant(team, server.AddRepository("octo-org", name))
+	}
+
+	// Each call fits in the timeout, although the four of them together do not
+	server.SetDelay(40 * time.Millisecond)
+	provider := newBufconnProvider(t, server, 100*time.Millisecond)
+	repos, err := provider.AccessibleRepositories(context.Background(), "test@example.com")
+	require.NoError(t, err)
+	assert.Len(t, repos, 3)
+}
@@ -0,0 +1,74 @@
+package access
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Permission is the level of access a user has to a repository.
+// Levels are ordered, so a higher level implies every lower one.
+type Permission int
+
+const (
+	// PermissionNone means the repository is not accessible at all
+	PermissionNone Permission = iota
+	// PermissionRead allows reading code, issues and pull requests
+	PermissionRead
+	// PermissionTriage additionally allows managing issues and pull requests
+	PermissionTriage
+	// PermissionWrite additionally allows pushing, creating branches and running workflows
+	PermissionWrite
+	// PermissionAdmin grants full control of the repository
+	PermissionAdmin
+)
+
+// String returns the lowercase name of the permission level
+func (p Permission) String() string {
+	switch p {
+	case PermissionRead:
+		return "read"
+	case PermissionTriage:
+		return "triage"
+	case PermissionWrite:
+		return "write"
+	case PermissionAdmin:
+		return "admin"
+	default:
+		return "none"
+	}
+}
+
+// ParsePermission parses a permission level. Besides read, triage, write and admin it accepts
+// the GitHub role names pull, push and maintain, which map to read, write and write respectively.
+// Matching is case insensitive.
+func ParsePermission(s string) (Permission, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "none":
+		return PermissionNone, nil
+	case "read", "pull":
+		return PermissionRead, nil
+	case "triage":
+		return PermissionTriage, nil
+	case "write", "push", "maintain":
+		return PermissionWrite, nil
+	case "admin":
+		return PermissionAdmin, nil
+	default:
+		return PermissionNone, fmt.Errorf("unknown permission %q (expected one of read, triage, write, admin)", s)
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (p Permission) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (p *Permission) UnmarshalText(text []byte) error {
+	parsed, err := ParsePermission(string(text))
+	if err != nil {
+		return err
+	}
+	*p = parsed
+	return nil
+}
@@ -0,0 +1,130 @@
+package access
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// repositoriesProvider serves a fixed list of repositories, including their permissions
+type repositoriesProvider []Repository
+
+func (p repositoriesProvider) AccessibleRepositories(_ context.Context, _ string) ([]Repository, error) {
+	return p, nil
+}
+
+func TestParsePermission(t *testing.T) {
+	tests := []struct {
+		input       string
+		expected    Permission
+		expectError bool
+	}{
+		{input: "read", expected: PermissionRead},
+		{input: "pull", expected: PermissionRead},
+		{input: "Triage", expected: PermissionTriage},
+		{input: "write", expected: PermissionWrite},
+		{input: "push", expected: PermissionWrite},
+		{input: "maintain", expected: PermissionWrite},
+		{input: " ADMIN ", expected: PermissionAdmin},
+		{input: "owner", expectError: true},
+		{input: "", expectError: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			permission, err := ParsePermission(tc.input)
+			if tc.expectError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, permission)
+		})
+	}
+}
+
+func TestPermission_Text(t *testing.T) {
+	for _, permission := range []Permission{PermissionRead, PermissionTriage, PermissionWrite, PermissionAdmin} {
+		text, err := permission.MarshalText()
+		require.NoError(t, err)
+
+		var parsed Permission
+		require.NoError(t, parsed.UnmarshalText(text))
+		assert.Equal(t, permission, parsed)
+	}
+}
+
+func TestValidator_RepositoryPermission(t *testing.T) {
+	provider := repositoriesProvider{
+		{Org: "org", Repo: "reader"},
+		{Org: "org", Repo: "writer", Permission: PermissionWrite},
+		{Org: "Org", Repo: "Admin", Permission: PermissionAdmin},
+		// A repository granted twice keeps the highest permission
+		{Org: "org", Repo: "shared", Permission: PermissionTriage},
+		{Org: "org", Repo: "shared", Permission: PermissionWrite},
+		{Org: "org", Repo: "shared"},
+	}
+
+	validator := NewValidatorWithProvider("test@example.com", provider)
+	require.NoError(t, validator.Initialize())
+
+	tests := []struct {
+		repoURL  string
+		expected Permission
+	}{
+		{repoURL: "org/reader", expected: PermissionRead},
+		{repoURL: "org/writer", expected: PermissionWrite},
+		{repoURL: "https://github.com/org/admin", expected: PermissionAdmin},
+		{repoURL: "org/shared", expected: PermissionWrite},
+		{repoURL: "org/unknown", expected: PermissionNone},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.repoURL, func(t *testing.T) {
+			permission, err := validator.RepositoryPermission(tc.repoURL)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, permission)
+		})
+	}
+
+	hasWrite, err := validator.HasRepositoryPermission("org/writer", PermissionWrite)
+	require.NoError(t, err)
+	assert.True(t, hasWrite)
+
+	hasWrite, err = validator.HasRepositoryPermission("org/reader", PermissionWrite)
+	require.NoError(t, err)
+	assert.False(t, hasWrite)
+
+	accessible, err := validator.IsRepositoryAccessible("org/reader")
+	require.NoError(t, err)
+	assert.True(t, accessible)
+
+	assert.Equal(t, map[string]Permission{
+		"github.com/org/reader": PermissionRead,
+		"github.com/org/writer": PermissionWrite,
+		"github.com/org/admin":  PermissionAdmin,
+		"github.com/org/shared": PermissionWrite,
+	}, validator.GetRepositoryPermissions())
+}
+
+func TestValidator_DefaultPermission(t *testing.T) {
+	provider := repositoriesProvider{
+		{Org: "org", Repo: "unspecified"},
+		{Org: "org", Repo: "reader", Permission: PermissionRead},
+	}
+
+	validator := NewValidatorWithProvider("test@example.com", provider)
+	validator.SetDefaultPermission(PermissionWrite)
+	require.NoError(t, validator.Initialize())
+
+	permission, err := validator.RepositoryPermission("org/unspecified")
+	require.NoError(t, err)
+	assert.Equal(t, PermissionWrite, permission)
+
+	// An explicit permission is not raised to the default
+	permission, err = validator.RepositoryPermission("org/reader")
+	require.NoError(t, err)
+	assert.Equal(t, PermissionRead, permission)
+}
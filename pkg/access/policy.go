@@ -0,0 +1,141 @@
+package access
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// policyFile is the on-disk format of a local access policy.
+//
+// Example (YAML):
+//
+//	repositories:        # granted to every user
+//	  - github/github-mcp-server
+//	users:
+//	  someone@example.com:
+//	    - https://github.com/org/service
+//	    - repository: org/deployments
+//	      permission: write
+//
+// Entries given as plain strings get the validator's default permission.
+type policyFile struct {
+	Repositories []policyEntry            `json:"repositories" yaml:"repositories"`
+	Users        map[string][]policyEntry `json:"users" yaml:"users"`
+}
+
+// policyEntry is a repository in a policy file, written either as a plain string or as
+// an object with a repository and a permission level
+type policyEntry struct {
+	Repository string     `json:"repository" yaml:"repository"`
+	Permission Permission `json:"permission" yaml:"permission"`
+}
+
+// UnmarshalJSON accepts both the string and the object form
+func (e *policyEntry) UnmarshalJSON(data []byte) error {
+	var repository string
+	if err := json.Unmarshal(data, &repository); err == nil {
+		*e = policyEntry{Repository: repository}
+		return nil
+	}
+
+	type entry policyEntry
+	var decoded entry
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*e = policyEntry(decoded)
+	return nil
+}
+
+// UnmarshalYAML accepts both the string and the object form
+func (e *policyEntry) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*e = policyEntry{Repository: value.Value}
+		return nil
+	}
+
+	type entry policyEntry
+	var decoded entry
+	if err := value.Decode(&decoded); err != nil {
+		return err
+	}
+	*e = policyEntry(decoded)
+	return nil
+}
+
+// PolicyFileProvider reads accessible repositories from a local YAML or JSON policy file.
+// The file is re-read on every fetch so edits are picked up without a restart.
+type PolicyFileProvider struct {
+	path string
+
+	mu   sync.Mutex
+	host string
+}
+
+// NewPolicyFileProvider creates a provider backed by the policy file at path
+func NewPolicyFileProvider(path string) *PolicyFileProvider {
+	return &PolicyFileProvider{path: path}
+}
+
+// setHost accepts host in host/owner/repo entries even if it has no dot
+func (p *PolicyFileProvider) setHost(host string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.host = host
+}
+
+// AccessibleRepositories returns the shared repositories plus those listed for the user
+func (p *PolicyFileProvider) AccessibleRepositories(_ context.Context, userEmail string) ([]Repository, error) {
+	p.mu.Lock()
+	host := p.host
+	p.mu.Unlock()
+
+	policy, err := loadPolicyFile(p.path)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := append([]policyEntry{}, policy.Repositories...)
+	for email, repos := range policy.Users {
+		if strings.EqualFold(email, userEmail) {
+			entries = append(entries, repos...)
+		}
+	}
+
+	repos := make([]Repository, 0, len(entries))
+	for _, entry := range entries {
+		repo, err := parseRepository(entry.Repository, host)
+		if err != nil {
+			return nil, fmt.Errorf("invalid repository %q in policy file %s: %w", entry.Repository, p.path, err)
+		}
+		repo.Permission = entry.Permission
+		repos = append(repos, repo)
+	}
+	return repos, nil
+}
+
+func loadPolicyFile(path string) (*policyFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy file: %w", err)
+	}
+
+	var policy policyFile
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(data, &policy)
+	default:
+		err = yaml.Unmarshal(data, &policy)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse policy file %s: %w", path, err)
+	}
+	return &policy, nil
+}
@@ -0,0 +1,182 @@
+package access
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// Provider names accepted by NewProvider
+const (
+	ProviderResourceMap = "resource-map"
+	ProviderPolicyFile  = "policy-file"
+	ProviderChain       = "chain"
+)
+
+// AccessProvider supplies the repositories a user is allowed to access.
+// Implementations must be safe for concurrent use.
+type AccessProvider interface {
+	// AccessibleRepositories returns every repository the given user may access
+	AccessibleRepositories(ctx context.Context, userEmail string) ([]Repository, error)
+}
+
+// exchangeRecorder is implemented by providers that can expose the raw request
+// and response of their last fetch, such as the resource-map provider.
+type exchangeRecorder interface {
+	LastExchange() (request string, response string)
+}
+
+// hostSetter is implemented by providers that parse repository URLs, so they accept the
+// validator's host in the host/owner/repo form even if it has no dot, such as localhost.
+type hostSetter interface {
+	setHost(host string)
+}
+
+// loggerSetter is implemented by providers that log problems with the data they fetch,
+// such as the resource-map provider
+type loggerSetter interface {
+	SetLogger(logger *slog.Logger)
+}
+
+// NewProvider builds the provider selected by name.
+// The policy file is required for the policy-file and chain providers, and resourceMap
+// configures the service connection of the resource-map and chain providers.
+func NewProvider(name, policyFile string, resourceMap ResourceMapConfig) (AccessProvider, error) {
+	switch name {
+	case "", ProviderResourceMap:
+		return NewResourceMapProviderWithConfig(resourceMap), nil
+	case ProviderPolicyFile:
+		if policyFile == "" {
+			return nil, fmt.Errorf("access provider %q requires a policy file", name)
+		}
+		return NewPolicyFileProvider(policyFile), nil
+	case ProviderChain:
+		if policyFile == "" {
+			return nil, fmt.Errorf("access provider %q requires a policy file", name)
+		}
+		return NewChainProvider(NewResourceMapProviderWithConfig(resourceMap), NewPolicyFileProvider(policyFile)), nil
+	default:
+		return nil, fmt.Errorf("unknown access provider %q (expected one of %s)", name,
+			strings.Join([]string{ProviderResourceMap, ProviderPolicyFile, ProviderChain}, ", "))
+	}
+}
+
+// StaticProvider grants the same fixed set of repositories to every user.
+// It is mostly useful in tests.
+type StaticProvider struct {
+	repos []Repository
+}
+
+// NewStaticProvider creates a provider from repositories in any format accepted by
+// the validator, e.g. owner/repo or https://github.com/owner/repo.
+// Entries that cannot be parsed are skipped.
+func NewStaticProvider(repoURLs ...string) *StaticProvider {
+	repos := make([]Repository, 0, len(repoURLs))
+	for _, repoURL := range repoURLs {
+		repo, err := parseRepository(repoURL, "")
+		if err != nil {
+			continue
+		}
+		repos = append(repos, repo)
+	}
+	return &StaticProvider{repos: repos}
+}
+
+// AccessibleRepositories returns the fixed repository set
+func (p *StaticProvider) AccessibleRepositories(_ context.Context, _ string) ([]Repository, error) {
+	repos := make([]Repository, len(p.repos))
+	copy(repos, p.repos)
+	return repos, nil
+}
+
+// ChainProvider merges the repositories of several providers.
+// A failing provider is skipped as long as at least one other provider succeeds,
+// so a local policy file can stand in when the resource-map service is unreachable.
+type ChainProvider struct {
+	providers []AccessProvider
+}
+
+// NewChainProvider creates a provider that queries the given providers in order
+func NewChainProvider(providers ...AccessProvider) *ChainProvider {
+	return &ChainProvider{providers: providers}
+}
+
+// AccessibleRepositories returns the union of the repositories of every provider that succeeded
+func (p *ChainProvider) AccessibleRepositories(ctx context.Context, userEmail string) ([]Repository, error) {
+	if len(p.providers) == 0 {
+		return nil, errors.New("no access providers configured")
+	}
+
+	var (
+		repos     []Repository
+		errs      []error
+		succeeded bool
+	)
+	for _, provider := range p.providers {
+		result, err := provider.AccessibleRepositories(ctx, userEmail)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		succeeded = true
+		repos = append(repos, result...)
+	}
+
+	if !succeeded {
+		return nil, fmt.Errorf("all access providers failed: %w", errors.Join(errs...))
+	}
+	return repos, nil
+}
+
+// Close closes every provider in the chain that holds resources
+func (p *ChainProvider) Close() error {
+	var errs []error
+	for _, provider := range p.providers {
+		if closer, ok := provider.(io.Closer); ok {
+			errs = append(errs, closer.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// SetLogger passes the logger on to every provider in the chain that logs
+func (p *ChainProvider) SetLogger(logger *slog.Logger) {
+	for _, provider := range p.providers {
+		if setter, ok := provider.(loggerSetter); ok {
+			setter.SetLogger(logger)
+		}
+	}
+}
+
+// setHost passes the validator's host on to every provider in the chain that parses repository URLs
+func (p *ChainProvider) setHost(host string) {
+	for _, provider := range p.providers {
+		if setter, ok := provider.(hostSetter); ok {
+			setter.setHost(host)
+		}
+	}
+}
+
+// LastExchange returns the last exchange of the first provider in the chain that records one
+func (p *ChainProvider) LastExchange() (string, string) {
+	for _, provider := range p.providers {
+		if recorder, ok := provider.(exchangeRecorder); ok {
+			return recorder.LastExchange()
+		}
+	}
+	return "", ""
+}
+
+// parseRepository converts a repository URL into a Repository. The host is not part of
+// a Repository, so URLs on any host are accepted and keyed on the validator's host.
+// knownHost is also accepted without a dot in the host/owner/repo form, and may be empty.
+func parseRepository(repoURL, knownHost string) (Repository, error) {
+	_, owner, repo, err := splitRepositoryURL(repoURL, knownHost)
+	if err != nil {
+		return Repository{}, err
+	}
+	return Repository{Org: strings.ToLower(owner), Repo: strings.ToLower(repo)}, nil
+}
@@ -0,0 +1,291 @@
+package access
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type failingProvider struct {
+	err error
+}
+
+func (p *failingProvider) AccessibleRepositories(_ context.Context, _ string) ([]Repository, error) {
+	return nil, p.err
+}
+
+func writePolicyFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func TestNewProvider(t *testing.T) {
+	tests := []struct {
+		name         string
+		provider     string
+		policyFile   string
+		expectedType AccessProvider
+		expectError  bool
+	}{
+		{
+			name:         "default is resource map",
+			provider:     "",
+			expectedType: &ResourceMapProvider{},
+		},
+		{
+			name:         "resource map",
+			provider:     ProviderResourceMap,
+			expectedType: &ResourceMapProvider{},
+		},
+		{
+			name:         "policy file",
+			provider:     ProviderPolicyFile,
+			policyFile:   "policy.yaml",
+			expectedType: &PolicyFileProvider{},
+		},
+		{
+			name:         "chain",
+			provider:     ProviderChain,
+			policyFile:   "policy.yaml",
+			expectedType: &ChainProvider{},
+		},
+		{
+			name:        "policy file without path",
+			provider:    ProviderPolicyFile,
+			expectError: true,
+		},
+		{
+			name:        "chain without path",
+			provider:    ProviderChain,
+			expectError: true,
+		},
+		{
+			name:        "unknown provider",
+			provider:    "ldap",
+			expectError: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			provider, err := NewProvider(tc.provider, tc.policyFile, DefaultResourceMapConfig())
+			if tc.expectError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.IsType(t, tc.expectedType, provider)
+		})
+	}
+}
+
+func TestPolicyFileProvider(t *testing.T) {
+	yamlPolicy := `
+repositories:
+  - github/github-mcp-server
+users:
+  Someone@Example.com:
+    - https://github.com/Org/Service.git
+  other@example.com:
+    - org/secret
+`
+	jsonPolicy := `{
+  "repositories": ["github/github-mcp-server"],
+  "users": {"someone@example.com": ["github.com/org/service"]}
+}`
+
+	tests := []struct {
+		name          string
+		fileName      string
+		content       string
+		userEmail     string
+		expectedRepos []Repository
+		expectError   bool
+	}{
+		{
+			name:      "yaml policy with user entries",
+			fileName:  "policy.yaml",
+			content:   yamlPolicy,
+			userEmail: "someone@example.com",
+			expectedRepos: []Repository{
+				{Org: "github", Repo: "github-mcp-server"},
+				{Org: "org", Repo: "service"},
+			},
+		},
+		{
+			name:      "yaml policy for unknown user only grants shared entries",
+			fileName:  "policy.yml",
+			content:   yamlPolicy,
+			userEmail: "nobody@example.com",
+			expectedRepos: []Repository{
+				{Org: "github", Repo: "github-mcp-server"},
+			},
+		},
+		{
+			name:      "json policy",
+			fileName:  "policy.json",
+			content:   jsonPolicy,
+			userEmail: "someone@example.com",
+			expectedRepos: []Repository{
+				{Org: "github", Repo: "github-mcp-server"},
+				{Org: "org", Repo: "service"},
+			},
+		},
+		{
+			name:     "yaml policy with permissions",
+			fileName: "policy.yaml",
+			content: `
+repositories:
+  - github/github-mcp-server
+  - repository: org/deployments
+    permission: write
+users:
+  someone@example.com:
+    - repository: org/service
+      permission: Admin
+`,
+			userEmail: "someone@example.com",
+			expectedRepos: []Repository{
+				{Org: "github", Repo: "github-mcp-server"},
+				{Org: "org", Repo: "deployments", Permission: PermissionWrite},
+				{Org: "org", Repo: "service", Permission: PermissionAdmin},
+			},
+		},
+		{
+			name:      "json policy with permissions",
+			fileName:  "policy.json",
+			content:   `{"repositories": ["github/github-mcp-server", {"repository": "org/service", "permission": "triage"}]}`,
+			userEmail: "someone@example.com",
+			expectedRepos: []Repository{
+				{Org: "github", Repo: "github-mcp-server"},
+				{Org: "org", Repo: "service", Permission: PermissionTriage},
+			},
+		},
+		{
+			name:        "unknown permission",
+			fileName:    "policy.yaml",
+			content:     "repositories:\n  - repository: org/service\n    permission: owner\n",
+			userEmail:   "someone@example.com",
+			expectError: true,
+		},
+		{
+			name:        "invalid repository entry",
+			fileName:    "policy.yaml",
+			content:     "repositories:\n  - not-a-repo\n",
+			userEmail:   "someone@example.com",
+			expectError: true,
+		},
+		{
+			name:        "malformed file",
+			fileName:    "policy.json",
+			content:     "{",
+			userEmail:   "someone@example.com",
+			expectError: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			provider := NewPolicyFileProvider(writePolicyFile(t, tc.fileName, tc.content))
+
+			repos, err := provider.AccessibleRepositories(context.Background(), tc.userEmail)
+			if tc.expectError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.ElementsMatch(t, tc.expectedRepos, repos)
+		})
+	}
+
+	t.Run("missing file", func(t *testing.T) {
+		provider := NewPolicyFileProvider(filepath.Join(t.TempDir(), "missing.yaml"))
+		_, err := provider.AccessibleRepositories(context.Background(), "someone@example.com")
+		require.Error(t, err)
+	})
+}
+
+func TestChainProvider(t *testing.T) {
+	unavailable := &failingProvider{err: errors.New("resource map unavailable")}
+
+	tests := []struct {
+		name          string
+		providers     []AccessProvider
+		expectedRepos []Repository
+		expectError   bool
+	}{
+		{
+			name: "merges all providers",
+			providers: []AccessProvider{
+				NewStaticProvider("org/one"),
+				NewStaticProvider("org/two"),
+			},
+			expectedRepos: []Repository{
+				{Org: "org", Repo: "one"},
+				{Org: "org", Repo: "two"},
+			},
+		},
+		{
+			name: "skips failing providers",
+			providers: []AccessProvider{
+				unavailable,
+				NewStaticProvider("org/one"),
+			},
+			expectedRepos: []Repository{
+				{Org: "org", Repo: "one"},
+			},
+		},
+		{
+			name:        "fails when every provider fails",
+			providers:   []AccessProvider{unavailable, unavailable},
+			expectError: true,
+		},
+		{
+			name:        "fails without providers",
+			expectError: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			repos, err := NewChainProvider(tc.providers...).AccessibleRepositories(context.Background(), "someone@example.com")
+			if tc.expectError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.ElementsMatch(t, tc.expectedRepos, repos)
+		})
+	}
+}
+
+func TestValidator_WithPolicyFileProvider(t *testing.T) {
+	path := writePolicyFile(t, "policy.yaml", "users:\n  someone@example.com:\n    - org/service\n")
+	validator := NewValidatorWithProvider("someone@example.com", NewPolicyFileProvider(path))
+
+	require.NoError(t, validator.Initialize())
+
+	accessible, err := validator.IsRepositoryAccessible("https://github.com/org/service")
+	require.NoError(t, err)
+	assert.True(t, accessible)
+
+	accessible, err = validator.IsRepositoryAccessible("org/other")
+	require.NoError(t, err)
+	assert.False(t, accessible)
+}
+
+func TestValidator_InitializeProviderError(t *testing.T) {
+	validator := NewValidatorWithProvider("someone@example.com", &failingProvider{err: errors.New("boom")})
+
+	err := validator.Initialize()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "boom")
+	assert.False(t, validator.initialized)
+}
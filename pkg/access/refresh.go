@@ -0,0 +1,150 @@
+package access
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// RefreshStatus describes the state of the accessible repositories cache
+type RefreshStatus struct {
+	// LastRefresh is the time of the last successful fetch
+	LastRefresh time.Time
+	// LastAttempt is the time of the last fetch, successful or not
+	LastAttempt time.Time
+	// LastError is the error of the last fetch, nil if it succeeded
+	LastError error
+	// Stale reports whether the cached set is older than the refresh interval
+	Stale bool
+	// Repositories is the number of cached accessible repositories
+	Repositories int
+}
+
+// SetRefreshInterval sets how long the cached repository set stays fresh.
+// Once it expires, lookups keep being served from the stale set while a refresh runs
+// in the background. A zero or negative interval disables refreshing.
+func (v *Validator) SetRefreshInterval(interval time.Duration) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.refreshInterval = interval
+}
+
+// SetLogger sets the logger used to report refresh outcomes, and passes it on to the provider
+func (v *Validator) SetLogger(logger *slog.Logger) {
+	if logger == nil {
+		return
+	}
+	if setter, ok := v.provider.(loggerSetter); ok {
+		setter.SetLogger(logger)
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.logger = logger
+}
+
+// Refresh fetches the accessible repositories and replaces the cached set.
+// Lookups are served from the previous set while the fetch is running, and the
+// previous set is kept if the fetch fails.
+func (v *Validator) Refresh(ctx context.Context) error {
+	v.refreshMu.Lock()
+	defer v.refreshMu.Unlock()
+
+	repos, teams, rules, err := v.fetchAccessibleRepositories(ctx)
+
+	v.mu.Lock()
+	now := time.Now()
+	v.lastAttempt = now
+	v.lastRefreshErr = err
+	if err != nil {
+		v.logger.Warn("failed to refresh accessible repositories", "user", v.userEmail, "error", err, "lastRefresh", v.lastRefresh)
+		v.mu.Unlock()
+		return err
+	}
+
+	// The tools were chosen when the server started, so a degraded server stays read-only
+	if v.failureMode == FailDegraded && !v.initialized {
+		v.logger.Warn("loaded accessible repositories after a degraded start, write tools stay disabled until the server is restarted", "user", v.userEmail)
+	}
+
+	// Replace the existing data with the fresh results
+	v.accessibleRepos = repos
+	v.grantingTeams = teams
+	v.rules = rules
+	v.lastRefresh = now
+	v.initialized = true
+
+	v.logger.Info("refreshed accessible repositories", "user", v.userEmail, "repositories", len(v.accessibleRepos))
+	v.mu.Unlock()
+
+	v.saveToCache()
+	return nil
+}
+
+// RefreshAsync starts a background refresh unless one is already running
+func (v *Validator) RefreshAsync() {
+	if !v.refreshing.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		defer v.refreshing.Store(false)
+		_ = v.Refresh(context.Background())
+	}()
+}
+
+// StartRefresher refreshes the cached set every refresh interval until ctx is done.
+// It returns immediately if refreshing is disabled.
+func (v *Validator) StartRefresher(ctx context.Context) {
+	v.mu.RLock()
+	interval := v.refreshInterval
+	v.mu.RUnlock()
+
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				v.RefreshAsync()
+			}
+		}
+	}()
+}
+
+// Status reports when the cached set was last refreshed and whether that failed
+func (v *Validator) Status() RefreshStatus {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	return RefreshStatus{
+		LastRefresh:  v.lastRefresh,
+		LastAttempt:  v.lastAttempt,
+		LastError:    v.lastRefreshErr,
+		Stale:        v.isStaleLocked(),
+		Repositories: len(v.accessibleRepos),
+	}
+}
+
+// isStaleLocked reports whether the cached set has outlived the refresh interval.
+// The caller must hold v.mu.
+func (v *Validator) isStaleLocked() bool {
+	if v.refreshInterval <= 0 || !v.initialized {
+		return false
+	}
+	return time.Since(v.lastRefresh) > v.refreshInterval
+}
+
+// needsRefreshLocked reports whether a lookup should trigger a background refresh.
+// It is based on the last attempt so a failing provider is retried once per interval
+// rather than on every lookup. The caller must hold v.mu.
+func (v *Validator) needsRefreshLocked() bool {
+	if v.refreshInterval <= 0 || !v.initialized {
+		return false
+	}
+	return time.Since(v.lastAttempt) > v.refreshInterval
+}
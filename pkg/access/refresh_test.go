@@ -0,0 +1,139 @@
+package access
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mutableProvider serves a repository set that tests can swap or make fail
+type mutableProvider struct {
+	mu    sync.Mutex
+	repos []string
+	err   error
+	calls int
+}
+
+func (p *mutableProvider) set(err error, repos ...string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.repos = repos
+	p.err = err
+}
+
+func (p *mutableProvider) callCount() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.calls
+}
+
+func (p *mutableProvider) AccessibleRepositories(ctx context.Context, userEmail string) ([]Repository, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls++
+	if p.err != nil {
+		return nil, p.err
+	}
+	return NewStaticProvider(p.repos...).AccessibleRepositories(ctx, userEmail)
+}
+
+func TestValidator_Refresh(t *testing.T) {
+	provider := &mutableProvider{}
+	provider.set(nil, "org/one")
+	validator := NewValidatorWithProvider("test@example.com", provider)
+
+	require.NoError(t, validator.Initialize())
+	status := validator.Status()
+	assert.False(t, status.LastRefresh.IsZero())
+	assert.NoError(t, status.LastError)
+	assert.Equal(t, 1, status.Repositories)
+
+	// A membership change is picked up by the next refresh
+	provider.set(nil, "org/two")
+	require.NoError(t, validator.Refresh(context.Background()))
+
+	accessible, err := validator.IsRepositoryAccessible("org/one")
+	require.NoError(t, err)
+	assert.False(t, accessible)
+	accessible, err = validator.IsRepositoryAccessible("org/two")
+	require.NoError(t, err)
+	assert.True(t, accessible)
+
+	// A failed refresh keeps serving the previous set and reports the error
+	lastRefresh := validator.Status().LastRefresh
+	provider.set(errors.New("resource map unavailable"))
+	require.Error(t, validator.Refresh(context.Background()))
+
+	status = validator.Status()
+	assert.Error(t, status.LastError)
+	assert.Equal(t, lastRefresh, status.LastRefresh)
+	assert.True(t, status.LastAttempt.After(lastRefresh) || status.LastAttempt.Equal(lastRefresh))
+
+	accessible, err = validator.IsRepositoryAccessible("org/two")
+	require.NoError(t, err)
+	assert.True(t, accessible)
+}
+
+func TestValidator_StaleWhileRevalidate(t *testing.T) {
+	provider := &mutableProvider{}
+	provider.set(nil, "org/one")
+	validator := NewValidatorWithProvider("test@example.com", provider)
+	validator.SetRefreshInterval(time.Millisecond)
+
+	require.NoError(t, validator.Initialize())
+	provider.set(nil, "org/two")
+	time.Sleep(5 * time.Millisecond)
+	assert.True(t, validator.Status().Stale)
+
+	// The stale answer is served immediately while a refresh runs in the background
+	accessible, err := validator.IsRepositoryAccessible("org/one")
+	require.NoError(t, err)
+	assert.True(t, accessible)
+
+	require.Eventually(t, func() bool {
+		accessible, err := validator.IsRepositoryAccessible("org/two")
+		return err == nil && accessible
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestValidator_RefreshDisabled(t *testing.T) {
+	provider := &mutableProvider{}
+	provider.set(nil, "org/one")
+	validator := NewValidatorWithProvider("test@example.com", provider)
+
+	require.NoError(t, validator.Initialize())
+	for i := 0; i < 10; i++ {
+		_, err := validator.IsRepositoryAccessible("org/one")
+		require.NoError(t, err)
+	}
+
+	assert.False(t, validator.Status().Stale)
+	assert.Equal(t, 1, provider.callCount())
+}
+
+func TestValidator_StartRefresher(t *testing.T) {
+	provider := &mutableProvider{}
+	provider.set(nil, "org/one")
+	validator := NewValidatorWithProvider("test@example.com", provider)
+	validator.SetRefreshInterval(5 * time.Millisecond)
+	require.NoError(t, validator.Initialize())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	validator.StartRefresher(ctx)
+
+	require.Eventually(t, func() bool {
+		return provider.callCount() >= 3
+	}, time.Second, 5*time.Millisecond)
+
+	cancel()
+	// Let any in-flight refresh settle before checking that the refresher stopped
+	time.Sleep(20 * time.Millisecond)
+	calls := provider.callCount()
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, calls, provider.callCount())
+}
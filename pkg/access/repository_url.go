@@ -0,0 +1,140 @@
+package access
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// DefaultHost is the host repositories are keyed on unless a GitHub Enterprise host is configured
+const DefaultHost = "github.com"
+
+// scpLikeURL matches SSH remotes in the scp form, e.g. git@ghe.example.com:owner/repo.git
+var scpLikeURL = regexp.MustCompile(`^(?:[^@/:]+@)?([^@/:]+):([^/].*)$`)
+
+// ParseHost returns the hostname repositories are keyed on for a GitHub host given as a URL
+// or hostname, in the forms accepted by --gh-host (e.g. https://ghe.example.com for GHES or
+// https://mycorp.ghe.com for GHEC). Empty values and github.com hosts yield DefaultHost.
+// The port is dropped, so instances on different ports of one hostname share keys.
+func ParseHost(s string) (string, error) {
+	if s == "" {
+		return DefaultHost, nil
+	}
+	if !strings.Contains(s, "://") {
+		s = "https://" + s
+	}
+
+	u, err := url.Parse(s)
+	if err != nil || u.Hostname() == "" {
+		return "", fmt.Errorf("invalid GitHub host: %s", s)
+	}
+
+	host := strings.ToLower(u.Hostname())
+	if host == DefaultHost || strings.HasSuffix(host, "."+DefaultHost) {
+		return DefaultHost, nil
+	}
+	return host, nil
+}
+
+// NormalizeRepositoryURL converts a repository URL in any format accepted by the validator
+// to the lowercase host/owner/repo form it reports repositories in
+func (v *Validator) NormalizeRepositoryURL(repoURL string) (string, error) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return normalizeRepositoryURL(repoURL, v.host)
+}
+
+// repositoryKey returns the normalized URL of owner/repo on host
+func repositoryKey(host, owner, repo string) string {
+	return fmt.Sprintf("%s/%s/%s", host, strings.ToLower(owner), strings.ToLower(repo))
+}
+
+// normalizeRepositoryURL converts various GitHub URL formats to host/owner/repo
+// Handles: https://host/owner/repo, host/owner/repo, owner/repo, and the SSH remotes
+// git@host:owner/repo.git and ssh://git@host/owner/repo.git
+// URLs on any host other than the given one are rejected. Owner and repo are lowercased
+// for case insensitive matching.
+func normalizeRepositoryURL(repoURL, host string) (string, error) {
+	urlHost, owner, repo, err := splitRepositoryURL(repoURL, host)
+	if err != nil {
+		return "", err
+	}
+
+	if urlHost != "" && urlHost != host {
+		return "", fmt.Errorf("repository URL %s is not on the configured GitHub host %s", repoURL, host)
+	}
+	return repositoryKey(host, owner, repo), nil
+}
+
+// splitRepositoryURL extracts the host, owner and repo from a repository URL.
+// The host is lowercase and empty for the owner/repo format. In the host/owner/repo format the
+// host must look like a hostname or be knownHost, so dotless hosts such as localhost can be
+// given when they are the configured one; knownHost may be empty.
+func splitRepositoryURL(repoURL, knownHost string) (string, string, string, error) {
+	if repoURL == "" {
+		return "", "", "", fmt.Errorf("repository URL cannot be empty")
+	}
+
+	var host, path string
+	switch {
+	// Handle full URLs, including ssh:// remotes
+	case strings.Contains(repoURL, "://"):
+		parsedURL, err := url.Parse(repoURL)
+		if err != nil {
+			return "", "", "", fmt.Errorf("invalid URL format: %w", err)
+		}
+		switch strings.ToLower(parsedURL.Scheme) {
+		case "http", "https", "ssh", "git+ssh":
+		default:
+			return "", "", "", fmt.Errorf("unsupported repository URL format: %s", repoURL)
+		}
+		host = parsedURL.Hostname()
+		path = strings.TrimPrefix(parsedURL.Path, "/")
+
+	// Handle SSH remotes in the scp form, e.g. git@host:owner/repo.git
+	case scpLikeURL.MatchString(repoURL):
+		matches := scpLikeURL.FindStringSubmatch(repoURL)
+		host, path = matches[1], matches[2]
+
+	// Handle host/owner/repo format, the host must look like a hostname
+	case strings.Count(repoURL, "/") == 2:
+		host, path, _ = strings.Cut(repoURL, "/")
+		if !strings.Contains(host, ".") && !strings.EqualFold(host, knownHost) {
+			return "", "", "", fmt.Errorf("unsupported repository URL format: %s", repoURL)
+		}
+
+	// Handle owner/repo format
+	case strings.Count(repoURL, "/") == 1:
+		path = repoURL
+
+	default:
+		return "", "", "", fmt.Errorf("unsupported repository URL format: %s", repoURL)
+	}
+
+	// Remove .git suffix if present
+	path = strings.TrimSuffix(path, ".git")
+
+	// Validate path format (should be owner/repo)
+	if !isValidRepoPath(path) {
+		return "", "", "", fmt.Errorf("unsupported repository URL format: %s", repoURL)
+	}
+
+	owner, repo, _ := strings.Cut(path, "/")
+	return strings.ToLower(host), owner, repo, nil
+}
+
+// isValidRepoPath validates that a path is in the format owner/repo
+func isValidRepoPath(path string) bool {
+	if path == "" {
+		return false
+	}
+
+	parts := strings.Split(path, "/")
+	if len(parts) != 2 {
+		return false
+	}
+
+	// Both owner and repo name should be non-empty
+	return parts[0] != "" && parts[1] != ""
+}
@@ -0,0 +1,193 @@
+package access
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseHost(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{input: "", expected: "github.com"},
+		{input: "https://github.com", expected: "github.com"},
+		{input: "https://api.github.com/", expected: "github.com"},
+		{input: "github.com", expected: "github.com"},
+		{input: "https://GHE.example.com", expected: "ghe.example.com"},
+		{input: "http://ghe.example.com:8080/", expected: "ghe.example.com"},
+		{input: "ghe.example.com", expected: "ghe.example.com"},
+		{input: "https://mycorp.ghe.com", expected: "mycorp.ghe.com"},
+		// Look-alike hosts are not github.com
+		{input: "https://notgithub.com", expected: "notgithub.com"},
+		{input: "evilgithub.com", expected: "evilgithub.com"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			host, err := ParseHost(tc.input)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, host)
+		})
+	}
+
+	_, err := ParseHost("https://")
+	require.Error(t, err)
+
+	// Repositories of a look-alike host do not collide with those of github.com
+	host, err := ParseHost("https://evilgithub.com")
+	require.NoError(t, err)
+	_, err = normalizeRepositoryURL("https://github.com/owner/repo", host)
+	assert.Error(t, err)
+}
+
+func TestNormalizeRepositoryURL_EnterpriseHost(t *testing.T) {
+	const host = "ghe.example.com"
+
+	tests := []struct {
+		name        string
+		input       string
+		expected    string
+		expectError bool
+	}{
+		{
+			name:     "https URL",
+			input:    "https://ghe.example.com/Org/Repo",
+			expected: "ghe.example.com/org/repo",
+		},
+		{
+			name:     "https URL with port and .git suffix",
+			input:    "https://GHE.example.com:8443/org/repo.git",
+			expected: "ghe.example.com/org/repo",
+		},
+		{
+			name:     "host/owner/repo format",
+			input:    "ghe.example.com/org/repo",
+			expected: "ghe.example.com/org/repo",
+		},
+		{
+			name:     "owner/repo format uses the configured host",
+			input:    "org/repo",
+			expected: "ghe.example.com/org/repo",
+		},
+		{
+			name:     "SSH scp form",
+			input:    "git@ghe.example.com:org/repo.git",
+			expected: "ghe.example.com/org/repo",
+		},
+		{
+			name:     "SSH scp form without user",
+			input:    "ghe.example.com:Org/Repo",
+			expected: "ghe.example.com/org/repo",
+		},
+		{
+			name:     "ssh URL",
+			input:    "ssh://git@ghe.example.com/org/repo.git",
+			expected: "ghe.example.com/org/repo",
+		},
+		{
+			name:     "ssh URL with port",
+			input:    "ssh://git@ghe.example.com:2222/org/repo",
+			expected: "ghe.example.com/org/repo",
+		},
+		{
+			name:        "github.com URL is on another host",
+			input:       "https://github.com/org/repo",
+			expectError: true,
+		},
+		{
+			name:        "SSH remote on another host",
+			input:       "git@github.com:org/repo.git",
+			expectError: true,
+		},
+		{
+			name:        "unsupported scheme",
+			input:       "ftp://ghe.example.com/org/repo",
+			expectError: true,
+		},
+		{
+			name:        "SSH scp form with extra path segment",
+			input:       "git@ghe.example.com:org/repo/extra",
+			expectError: true,
+		},
+		{
+			name:        "three segments without a hostname",
+			input:       "org/repo/extra",
+			expectError: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := normalizeRepositoryURL(tc.input, host)
+
+			if tc.expectError {
+				assert.Error(t, err)
+				assert.Empty(t, result)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestNormalizeRepositoryURL_SSHOnDotcom(t *testing.T) {
+	for _, input := range []string{"git@github.com:owner/repo.git", "ssh://git@github.com/owner/repo.git"} {
+		result, err := normalizeRepositoryURL(input, DefaultHost)
+		require.NoError(t, err, input)
+		assert.Equal(t, "github.com/owner/repo", result, input)
+	}
+}
+
+func TestValidator_EnterpriseHost(t *testing.T) {
+	validator := NewValidatorWithProvider("test@example.com", NewStaticProvider("org/repo", "https://github.com/org/other"))
+	require.NoError(t, validator.SetHost("https://ghe.example.com"))
+	require.NoError(t, validator.Initialize())
+
+	assert.ElementsMatch(t, []string{"ghe.example.com/org/repo", "ghe.example.com/org/other"}, validator.GetAccessibleRepositories())
+
+	for _, repoURL := range []string{"org/repo", "https://ghe.example.com/org/repo", "git@ghe.example.com:org/repo.git"} {
+		accessible, err := validator.IsRepositoryAccessible(repoURL)
+		require.NoError(t, err, repoURL)
+		assert.True(t, accessible, repoURL)
+	}
+
+	_, err := validator.IsRepositoryAccessible("https://github.com/org/repo")
+	require.Error(t, err)
+
+	require.Error(t, validator.SetHost("https://"))
+}
+
+func TestValidator_DotlessHost(t *testing.T) {
+	policy := writePolicyFile(t, "policy.yaml", `
+repositories:
+  - localhost/org/repo
+`)
+	overrides := writePolicyFile(t, "overrides.yaml", `
+deny:
+  - localhost/org/secrets
+`)
+	validator := NewValidatorWithProvider("test@example.com", NewChainProvider(NewPolicyFileProvider(policy)))
+	require.NoError(t, validator.SetHost("http://localhost:8080"))
+	validator.SetOverrideFile(overrides)
+	require.NoError(t, validator.Initialize())
+
+	// Normalized keys can be looked up again
+	key, err := validator.NormalizeRepositoryURL("http://localhost:8080/Org/Repo")
+	require.NoError(t, err)
+	assert.Equal(t, "localhost/org/repo", key)
+	accessible, err := validator.IsRepositoryAccessible(key)
+	require.NoError(t, err)
+	assert.True(t, accessible)
+
+	rule, err := validator.DenyingRule("localhost/org/secrets")
+	require.NoError(t, err)
+	assert.Equal(t, "localhost/org/secrets", rule)
+
+	// Three segments are still rejected unless the first is the configured host
+	_, err = validator.NormalizeRepositoryURL("org/repo/extra")
+	assert.Error(t, err)
+}
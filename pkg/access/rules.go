@@ -0,0 +1,252 @@
+package access
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// overrideFile is the on-disk format of an operator override file, layered on the repositories
+// returned by the access provider. Owners and repository names may contain the wildcards * (any
+// run of characters) and ? (any single character).
+//
+// Example (YAML):
+//
+//	allow:
+//	  - org/*                  # every repository of org
+//	  - repository: org/service-*
+//	    permission: write
+//	deny:                      # always wins over any grant
+//	  - org/production-secrets
+//	  - "*/*-secrets"
+//
+// Allow entries given as plain strings get the validator's default permission.
+type overrideFile struct {
+	Allow []policyEntry `json:"allow" yaml:"allow"`
+	Deny  []string      `json:"deny" yaml:"deny"`
+}
+
+// SetOverrideFile layers the allow and deny rules of the override file at path on every
+// fetched repository set. The file is re-read on every refresh, and a refresh fails if it
+// cannot be read. An empty path disables the override file.
+func (v *Validator) SetOverrideFile(path string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.overrideFile = path
+}
+
+// DenyingRule returns the deny rule that matches the given repository URL, or an empty
+// string if the repository is not denied
+func (v *Validator) DenyingRule(repoURL string) (string, error) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	normalizedURL, err := normalizeRepositoryURL(repoURL, v.host)
+	if err != nil {
+		return "", fmt.Errorf("failed to normalize repository URL: %w", err)
+	}
+	return v.rules.deniedBy(normalizedURL), nil
+}
+
+// loadOverrideFile returns the repositories allowed and denied by the override file at path,
+// accepting host in host/owner/repo entries even if it has no dot
+func loadOverrideFile(path, host string) ([]Repository, []Repository, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read override file: %w", err)
+	}
+
+	var overrides overrideFile
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(data, &overrides)
+	default:
+		err = yaml.Unmarshal(data, &overrides)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse override file %s: %w", path, err)
+	}
+
+	allow := make([]Repository, 0, len(overrides.Allow))
+	for _, entry := range overrides.Allow {
+		repo, err := parseRepository(entry.Repository, host)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid repository %q in override file %s: %w", entry.Repository, path, err)
+		}
+		repo.Permission = entry.Permission
+		allow = append(allow, repo)
+	}
+
+	deny := make([]Repository, 0, len(overrides.Deny))
+	for _, entry := range overrides.Deny {
+		repo, err := parseRepository(entry, host)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid repository %q in override file %s: %w", entry, path, err)
+		}
+		deny = append(deny, repo)
+	}
+	return allow, deny, nil
+}
+
+// isRepositoryPattern reports whether a normalized repository URL contains wildcards
+func isRepositoryPattern(repoURL string) bool {
+	return strings.ContainsAny(repoURL, "*?")
+}
+
+// repositoryPattern is a normalized repository URL with wildcards, compiled to a regular expression
+type repositoryPattern struct {
+	pattern    string
+	re         *regexp.Regexp
+	permission Permission
+}
+
+// compileRepositoryPattern compiles a normalized repository URL with wildcards. Wildcards never
+// match a slash, so org/* matches every repository of org but not other owners.
+func compileRepositoryPattern(pattern string, permission Permission) (repositoryPattern, error) {
+	var expr strings.Builder
+	expr.WriteString("^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			expr.WriteString("[^/]*")
+		case '?':
+			expr.WriteString("[^/]")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	expr.WriteString("$")
+
+	re, err := regexp.Compile(expr.String())
+	if err != nil {
+		return repositoryPattern{}, fmt.Errorf("invalid repository pattern %s: %w", pattern, err)
+	}
+	return repositoryPattern{pattern: pattern, re: re, permission: permission}, nil
+}
+
+// accessRules holds the rules that cannot be answered by an exact lookup in the accessible
+// repositories: wildcard grants, and denies which always win over any grant.
+// Like the accessible repositories it is replaced rather than modified on refresh.
+type accessRules struct {
+	grants       []repositoryPattern
+	denied       map[string]struct{} // exact denies
+	denyPatterns []repositoryPattern // wildcard denies
+}
+
+func newAccessRules() *accessRules {
+	return &accessRules{denied: make(map[string]struct{})}
+}
+
+// grant adds a wildcard grant, keeping the highest permission if the pattern is already granted
+func (r *accessRules) grant(pattern string, permission Permission) error {
+	for i, grant := range r.grants {
+		if grant.pattern == pattern {
+			r.grants[i].permission = max(grant.permission, permission)
+			return nil
+		}
+	}
+
+	compiled, err := compileRepositoryPattern(pattern, permission)
+	if err != nil {
+		return err
+	}
+	r.grants = append(r.grants, compiled)
+	return nil
+}
+
+// deny adds a deny rule for a normalized repository URL, with or without wildcards
+func (r *accessRules) deny(repoURL string) error {
+	if !isRepositoryPattern(repoURL) {
+		r.denied[repoURL] = struct{}{}
+		return nil
+	}
+	for _, deny := range r.denyPatterns {
+		if deny.pattern == repoURL {
+			return nil
+		}
+	}
+
+	compiled, err := compileRepositoryPattern(repoURL, PermissionNone)
+	if err != nil {
+		return err
+	}
+	r.denyPatterns = append(r.denyPatterns, compiled)
+	return nil
+}
+
+// deniedBy returns the deny rule matching a normalized repository URL, or an empty string
+func (r *accessRules) deniedBy(repoURL string) string {
+	if _, ok := r.denied[repoURL]; ok {
+		return repoURL
+	}
+	for _, deny := range r.denyPatterns {
+		if deny.re.MatchString(repoURL) {
+			return deny.pattern
+		}
+	}
+	return ""
+}
+
+// permission returns the permission the rules grant on a normalized repository URL,
+// PermissionNone if it is denied or matches no grant
+func (r *accessRules) permission(repoURL string) Permission {
+	if r.deniedBy(repoURL) != "" {
+		return PermissionNone
+	}
+
+	permission := PermissionNone
+	for _, grant := range r.grants {
+		if grant.re.MatchString(repoURL) {
+			permission = max(permission, grant.permission)
+		}
+	}
+	return permission
+}
+
+// apply resolves the rules against the exactly granted repositories once, so lookups of those
+// stay a map access: denied repositories are removed and wildcard grants raise permissions.
+// The granting team chain is dropped when a wildcard grant raises the permission.
+func (r *accessRules) apply(repos map[string]Permission, teams map[string][]Team) {
+	for repoURL, permission := range repos {
+		if r.deniedBy(repoURL) != "" {
+			delete(repos, repoURL)
+			delete(teams, repoURL)
+			continue
+		}
+		if granted := r.permission(repoURL); granted > permission {
+			repos[repoURL] = granted
+			delete(teams, repoURL)
+		}
+	}
+}
+
+// grantPatterns returns the permission of every wildcard grant, keyed on its pattern
+func (r *accessRules) grantPatterns() map[string]Permission {
+	if len(r.grants) == 0 {
+		return nil
+	}
+	grants := make(map[string]Permission, len(r.grants))
+	for _, grant := range r.grants {
+		grants[grant.pattern] = grant.permission
+	}
+	return grants
+}
+
+// denyRules returns every deny rule, sorted
+func (r *accessRules) denyRules() []string {
+	denies := make([]string, 0, len(r.denied)+len(r.denyPatterns))
+	for repoURL := range r.denied {
+		denies = append(denies, repoURL)
+	}
+	for _, deny := range r.denyPatterns {
+		denies = append(denies, deny.pattern)
+	}
+	sort.Strings(denies)
+	return denies
+}
@@ -0,0 +1,194 @@
+package access
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccessRules(t *testing.T) {
+	rules := newAccessRules()
+	require.NoError(t, rules.grant("github.com/org/*", PermissionRead))
+	require.NoError(t, rules.grant("github.com/org/service-*", PermissionWrite))
+	require.NoError(t, rules.grant("github.com/org/service-?", PermissionAdmin))
+	require.NoError(t, rules.grant("github.com/*/docs", PermissionTriage))
+	require.NoError(t, rules.deny("github.com/org/production-secrets"))
+	require.NoError(t, rules.deny("github.com/*/*-secrets"))
+
+	tests := []struct {
+		repoURL    string
+		permission Permission
+		deniedBy   string
+	}{
+		{repoURL: "github.com/org/api", permission: PermissionRead},
+		{repoURL: "github.com/org/service-api", permission: PermissionWrite},
+		{repoURL: "github.com/org/service-a", permission: PermissionAdmin},
+		{repoURL: "github.com/other/docs", permission: PermissionTriage},
+		{repoURL: "github.com/org/docs", permission: PermissionTriage},
+		{repoURL: "github.com/other/api", permission: PermissionNone},
+		// Wildcards never match a slash or another host
+		{repoURL: "github.com/org.other/api", permission: PermissionNone},
+		{repoURL: "ghe.example.com/org/api", permission: PermissionNone},
+		// Denies always win
+		{repoURL: "github.com/org/production-secrets", permission: PermissionNone, deniedBy: "github.com/org/production-secrets"},
+		{repoURL: "github.com/org/service-secrets", permission: PermissionNone, deniedBy: "github.com/*/*-secrets"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.repoURL, func(t *testing.T) {
+			assert.Equal(t, tc.permission, rules.permission(tc.repoURL))
+			assert.Equal(t, tc.deniedBy, rules.deniedBy(tc.repoURL))
+		})
+	}
+
+	// Regular expression metacharacters in names are matched literally
+	require.NoError(t, rules.grant("github.com/dots/a.b*", PermissionRead))
+	assert.Equal(t, PermissionRead, rules.permission("github.com/dots/a.bc"))
+	assert.Equal(t, PermissionNone, rules.permission("github.com/dots/axbc"))
+}
+
+func TestAccessRules_Apply(t *testing.T) {
+	rules := newAccessRules()
+	require.NoError(t, rules.grant("github.com/org/*", PermissionWrite))
+	require.NoError(t, rules.deny("github.com/org/secrets"))
+
+	team := []Team{{Org: "org", Name: "team"}}
+	repos := map[string]Permission{
+		"github.com/org/api":     PermissionRead,
+		"github.com/org/admin":   PermissionAdmin,
+		"github.com/org/secrets": PermissionAdmin,
+		"github.com/other/repo":  PermissionRead,
+	}
+	teams := map[string][]Team{
+		"github.com/org/api":     team,
+		"github.com/org/admin":   team,
+		"github.com/org/secrets": team,
+	}
+
+	rules.apply(repos, teams)
+
+	assert.Equal(t, map[string]Permission{
+		"github.com/org/api":    PermissionWrite,
+		"github.com/org/admin":  PermissionAdmin,
+		"github.com/other/repo": PermissionRead,
+	}, repos)
+	// The team no longer explains a permission raised by a wildcard grant
+	assert.Equal(t, map[string][]Team{"github.com/org/admin": team}, teams)
+}
+
+func TestValidator_OverrideFile(t *testing.T) {
+	path := writePolicyFile(t, "overrides.yaml", `
+allow:
+  - team-org/*
+  - repository: org/service-*
+    permission: write
+  - org/extra
+deny:
+  - org/production-secrets
+  - "*/*-secrets"
+`)
+	validator := NewValidatorWithProvider("test@example.com", NewStaticProvider(
+		"org/api",
+		"org/service-api",
+		"org/production-secrets",
+		"other/db-secrets",
+	))
+	validator.SetOverrideFile(path)
+	require.NoError(t, validator.Initialize())
+
+	tests := []struct {
+		repoURL    string
+		permission Permission
+		deniedBy   string
+	}{
+		{repoURL: "org/api", permission: PermissionRead},
+		{repoURL: "org/service-api", permission: PermissionWrite},
+		{repoURL: "https://github.com/org/service-web", permission: PermissionWrite},
+		{repoURL: "org/extra", permission: PermissionRead},
+		{repoURL: "team-org/anything", permission: PermissionRead},
+		{repoURL: "other/api", permission: PermissionNone},
+		{repoURL: "org/production-secrets", permission: PermissionNone, deniedBy: "github.com/org/production-secrets"},
+		{repoURL: "Other/DB-Secrets", permission: PermissionNone, deniedBy: "github.com/*/*-secrets"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.repoURL, func(t *testing.T) {
+			permission, err := validator.RepositoryPermission(tc.repoURL)
+			require.NoError(t, err)
+			assert.Equal(t, tc.permission, permission)
+
+			rule, err := validator.DenyingRule(tc.repoURL)
+			require.NoError(t, err)
+			assert.Equal(t, tc.deniedBy, rule)
+		})
+	}
+
+	// Listings only hold exact entries, denied repositories are left out
+	assert.ElementsMatch(t, []string{"github.com/org/api", "github.com/org/service-api", "github.com/org/extra"},
+		validator.GetAccessibleRepositories())
+}
+
+func TestValidator_WildcardFromProvider(t *testing.T) {
+	validator := NewValidatorWithProvider("test@example.com", NewStaticProvider("org/*"))
+	require.NoError(t, validator.Initialize())
+
+	accessible, err := validator.IsRepositoryAccessible("org/any-repo")
+	require.NoError(t, err)
+	assert.True(t, accessible)
+
+	accessible, err = validator.IsRepositoryAccessible("other/any-repo")
+	require.NoError(t, err)
+	assert.False(t, accessible)
+}
+
+func TestValidator_InvalidOverrideFileKeepsPreviousSet(t *testing.T) {
+	dir := t.TempDir()
+	validator := NewValidatorWithProvider("test@example.com", NewStaticProvider("org/api"))
+	validator.SetOverrideFile(filepath.Join(dir, "missing.yaml"))
+
+	require.Error(t, validator.Refresh(context.Background()))
+	assert.True(t, validator.Status().LastRefresh.IsZero())
+
+	validator.SetOverrideFile(writePolicyFile(t, "overrides.json", `{"deny": ["org/api"]}`))
+	require.NoError(t, validator.Refresh(context.Background()))
+	accessible, err := validator.IsRepositoryAccessible("org/api")
+	require.NoError(t, err)
+	assert.False(t, accessible)
+
+	validator.SetOverrideFile(writePolicyFile(t, "broken.yaml", "deny: [not a repository"))
+	require.Error(t, validator.Refresh(context.Background()))
+	accessible, err = validator.IsRepositoryAccessible("org/api")
+	require.NoError(t, err)
+	assert.False(t, accessible, "the deny rule of the last successful refresh still applies")
+}
+
+func TestValidator_CacheKeepsRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.json")
+	overrides := writePolicyFile(t, "overrides.yaml", "allow: [org/*]\ndeny: [org/secrets]\n")
+
+	validator := NewValidatorWithProvider("test@example.com", NewStaticProvider("other/repo"))
+	validator.SetOverrideFile(overrides)
+	validator.SetCache(path, time.Hour)
+	require.NoError(t, validator.Refresh(context.Background()))
+
+	cache, err := loadCache(path, "test@example.com", DefaultHost)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Permission{"github.com/org/*": PermissionRead}, cache.Grants)
+	assert.Equal(t, []string{"github.com/org/secrets"}, cache.Denies)
+
+	cached := NewValidatorWithProvider("test@example.com", &failingProvider{})
+	cached.SetCache(path, time.Hour)
+	_, err = cached.loadFromCache()
+	require.NoError(t, err)
+
+	permission, err := cached.RepositoryPermission("org/api")
+	require.NoError(t, err)
+	assert.Equal(t, PermissionRead, permission)
+	rule, err := cached.DenyingRule("org/secrets")
+	require.NoError(t, err)
+	assert.Equal(t, "github.com/org/secrets", rule)
+}
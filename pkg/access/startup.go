@@ -0,0 +1,173 @@
+package access
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// FailureMode decides how the server behaves when the accessible repositories cannot be
+// loaded at startup
+type FailureMode string
+
+const (
+	// FailClosed refuses to start without the accessible repositories
+	FailClosed FailureMode = "fail-closed"
+	// FailOpen starts anyway and treats every repository as accessible until they load
+	FailOpen FailureMode = "fail-open"
+	// FailDegraded starts with read-only tools and treats every repository as readable until they
+	// load. The write tools stay disabled until the server is restarted, even once they load.
+	FailDegraded FailureMode = "degraded"
+)
+
+// Defaults for StartupConfig
+const (
+	DefaultStartupDeadline = 30 * time.Second
+	DefaultInitialBackoff  = 500 * time.Millisecond
+	DefaultMaxBackoff      = 10 * time.Second
+)
+
+// ParseFailureMode parses a failure mode, an empty string selects FailClosed
+func ParseFailureMode(s string) (FailureMode, error) {
+	switch mode := FailureMode(s); mode {
+	case "":
+		return FailClosed, nil
+	case FailClosed, FailOpen, FailDegraded:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown access failure mode %q (expected one of %s, %s, %s)", s, FailClosed, FailOpen, FailDegraded)
+	}
+}
+
+// StartupConfig controls how the accessible repositories are loaded at startup
+type StartupConfig struct {
+	// Deadline bounds the time spent retrying the initial load, zero makes a single attempt
+	Deadline time.Duration
+	// InitialBackoff is the delay before the first retry, doubled after every failed attempt
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between retries
+	MaxBackoff time.Duration
+	// Mode decides what happens if the load has not succeeded by the deadline
+	Mode FailureMode
+}
+
+// Start loads the accessible repositories, retrying with exponential backoff until the
+// startup deadline. If every attempt fails, FailClosed returns the last error, while
+// FailOpen and FailDegraded log it, return nil and keep retrying in the background
+// until ctx is done. Call Degraded to find out whether the server should run read-only.
+//
+// With a cache configured, a cached set younger than the cache max age is used without
+// waiting for the provider and refreshed in the background. An older cached set is only
+// used if every attempt fails, in any failure mode, and is then retried in the background.
+func (v *Validator) Start(ctx context.Context, config StartupConfig) error {
+	return v.StartInBackground(ctx, ctx, config)
+}
+
+// StartInBackground is Start for callers whose ctx ends before the validator does, such as
+// the first request of an HTTP session. The attempts before returning are bounded by ctx,
+// while the background retries run until background is done.
+func (v *Validator) StartInBackground(ctx, background context.Context, config StartupConfig) error {
+	if config.InitialBackoff <= 0 {
+		config.InitialBackoff = DefaultInitialBackoff
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = DefaultMaxBackoff
+	}
+	mode, err := ParseFailureMode(string(config.Mode))
+	if err != nil {
+		return err
+	}
+
+	v.mu.Lock()
+	started := v.failureMode != ""
+	if !started {
+		v.failureMode = mode
+	}
+	initialized := v.initialized
+	logger := v.logger
+	cacheMaxAge := v.cacheMaxAge
+	v.mu.Unlock()
+
+	// Only the first call loads, later calls keep the mode and retries already in place
+	if started || initialized {
+		return nil
+	}
+
+	if age, err := v.loadFromCache(); err == nil && age <= cacheMaxAge {
+		logger.Info("loaded accessible repositories from cache", "user", v.userEmail, "age", age)
+		v.RefreshAsync()
+		return nil
+	}
+
+	startupCtx := ctx
+	if config.Deadline > 0 {
+		var cancel context.CancelFunc
+		startupCtx, cancel = context.WithTimeout(ctx, config.Deadline)
+		defer cancel()
+	}
+
+	err = v.retry(startupCtx, config.Deadline > 0, config)
+	if err == nil {
+		return nil
+	}
+
+	v.mu.RLock()
+	cached := v.initialized
+	v.mu.RUnlock()
+	if cached {
+		logger.Warn("starting from stale access cache", "user", v.userEmail, "lastRefresh", v.Status().LastRefresh, "error", err)
+		go func() { _ = v.retry(background, true, config) }()
+		return nil
+	}
+	if mode == FailClosed {
+		return err
+	}
+
+	logger.Warn("starting without accessible repositories", "user", v.userEmail, "mode", mode, "error", err)
+	go func() { _ = v.retry(background, true, config) }()
+	return nil
+}
+
+// retry refreshes until it succeeds or ctx is done, sleeping with exponential backoff in
+// between. Without retries a single attempt is made.
+func (v *Validator) retry(ctx context.Context, retries bool, config StartupConfig) error {
+	backoff := config.InitialBackoff
+	for {
+		err := v.Refresh(ctx)
+		if err == nil || !retries {
+			return err
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("gave up loading accessible repositories: %w", err)
+		case <-timer.C:
+		}
+		backoff = min(backoff*2, config.MaxBackoff)
+	}
+}
+
+// Degraded reports whether the validator is running in FailDegraded mode without having
+// loaded the accessible repositories, in which case only read-only tools should be offered.
+// It turns false once they load, but a server that chose its tools by it stays read-only.
+func (v *Validator) Degraded() bool {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.failureMode == FailDegraded && !v.initialized
+}
+
+// fallbackPermissionLocked returns the permission granted to every repository while the
+// accessible repositories have not loaded, and false if lookups should fail instead.
+// The caller must hold v.mu.
+func (v *Validator) fallbackPermissionLocked() (Permission, bool) {
+	switch v.failureMode {
+	case FailOpen:
+		return PermissionAdmin, true
+	case FailDegraded:
+		return PermissionRead, true
+	default:
+		return PermissionNone, false
+	}
+}
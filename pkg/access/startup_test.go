@@ -0,0 +1,178 @@
+package access
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyProvider fails a fixed number of times before serving its repositories
+type flakyProvider struct {
+	mu       sync.Mutex
+	failures int
+	calls    int
+	repos    []string
+}
+
+func (p *flakyProvider) callCount() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.calls
+}
+
+func (p *flakyProvider) AccessibleRepositories(ctx context.Context, userEmail string) ([]Repository, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls++
+	if p.calls <= p.failures {
+		return nil, errors.New("service unavailable")
+	}
+	return NewStaticProvider(p.repos...).AccessibleRepositories(ctx, userEmail)
+}
+
+// fastRetries retries quickly so tests do not wait on the default backoff
+func fastRetries(deadline time.Duration, mode FailureMode) StartupConfig {
+	return StartupConfig{
+		Deadline:       deadline,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     5 * time.Millisecond,
+		Mode:           mode,
+	}
+}
+
+func TestParseFailureMode(t *testing.T) {
+	for _, input := range []string{"", "fail-closed", "fail-open", "degraded"} {
+		_, err := ParseFailureMode(input)
+		require.NoError(t, err, input)
+	}
+
+	mode, err := ParseFailureMode("")
+	require.NoError(t, err)
+	assert.Equal(t, FailClosed, mode)
+
+	_, err = ParseFailureMode("panic")
+	require.Error(t, err)
+}
+
+func TestValidator_StartRetries(t *testing.T) {
+	provider := &flakyProvider{failures: 3, repos: []string{"org/repo"}}
+	validator := NewValidatorWithProvider("test@example.com", provider)
+
+	require.NoError(t, validator.Start(context.Background(), fastRetries(5*time.Second, FailClosed)))
+	assert.Equal(t, 4, provider.callCount())
+
+	accessible, err := validator.IsRepositoryAccessible("org/repo")
+	require.NoError(t, err)
+	assert.True(t, accessible)
+	assert.False(t, validator.Degraded())
+}
+
+func TestValidator_StartWithoutDeadlineMakesOneAttempt(t *testing.T) {
+	provider := &flakyProvider{failures: 1, repos: []string{"org/repo"}}
+	validator := NewValidatorWithProvider("test@example.com", provider)
+
+	err := validator.Start(context.Background(), fastRetries(0, FailClosed))
+	require.Error(t, err)
+	assert.Equal(t, 1, provider.callCount())
+}
+
+func TestValidator_StartFailureModes(t *testing.T) {
+	tests := []struct {
+		name               string
+		mode               FailureMode
+		expectError        bool
+		expectDegraded     bool
+		expectedPermission Permission
+	}{
+		{
+			name:        "fail closed refuses to start",
+			mode:        FailClosed,
+			expectError: true,
+		},
+		{
+			name:               "fail open allows every repository",
+			mode:               FailOpen,
+			expectedPermission: PermissionAdmin,
+		},
+		{
+			name:               "degraded allows reading every repository",
+			mode:               FailDegraded,
+			expectDegraded:     true,
+			expectedPermission: PermissionRead,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			provider := &flakyProvider{failures: 1 << 30}
+			validator := NewValidatorWithProvider("test@example.com", provider)
+
+			err := validator.Start(ctx, fastRetries(20*time.Millisecond, tc.mode))
+			if tc.expectError {
+				require.ErrorContains(t, err, "gave up loading accessible repositories")
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectDegraded, validator.Degraded())
+
+			permission, err := validator.RepositoryPermission("any/repo")
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedPermission, permission)
+		})
+	}
+}
+
+func TestValidator_StartRecoversInBackground(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	provider := &flakyProvider{failures: 5, repos: []string{"org/repo"}}
+	validator := NewValidatorWithProvider("test@example.com", provider)
+	var logs bytes.Buffer
+	validator.SetLogger(slog.New(slog.NewTextHandler(&logs, nil)))
+
+	// The deadline passes after a single attempt, so the remaining ones happen in the background
+	config := fastRetries(time.Nanosecond, FailDegraded)
+	require.NoError(t, validator.Start(ctx, config))
+	assert.True(t, validator.Degraded())
+
+	require.Eventually(t, func() bool { return !validator.Degraded() }, 5*time.Second, time.Millisecond)
+	assert.Contains(t, logs.String(), "write tools stay disabled until the server is restarted")
+
+	// Once loaded, the real permissions apply again
+	permission, err := validator.RepositoryPermission("other/repo")
+	require.NoError(t, err)
+	assert.Equal(t, PermissionNone, permission)
+
+	// Starting again does not reload
+	calls := provider.callCount()
+	require.NoError(t, validator.Start(ctx, config))
+	assert.Equal(t, calls, provider.callCount())
+}
+
+func TestValidator_StartInBackgroundOutlivesTheRequest(t *testing.T) {
+	background, stop := context.WithCancel(context.Background())
+	defer stop()
+	request, done := context.WithCancel(context.Background())
+
+	provider := &flakyProvider{failures: 5, repos: []string{"org/repo"}}
+	validator := NewValidatorWithProvider("test@example.com", provider)
+	require.NoError(t, validator.StartInBackground(request, background, fastRetries(time.Nanosecond, FailOpen)))
+
+	// The request returning does not stop the retries
+	done()
+	require.Eventually(t, func() bool { return validator.Status().Repositories == 1 }, 5*time.Second, time.Millisecond)
+	permission, err := validator.RepositoryPermission("other/repo")
+	require.NoError(t, err)
+	assert.Equal(t, PermissionNone, permission)
+}
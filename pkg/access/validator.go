@@ -1,72 +1,169 @@
 package access
 
 import (
+	"context"
 	"fmt"
-	"net/url"
-	"strings"
+	"io"
+	"log/slog"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // Validator handles repository access validation for a specific user
 type Validator struct {
 	userEmail       string
-	accessibleRepos map[string]struct{} // Set implementation for efficient lookups
+	provider        AccessProvider
+	accessibleRepos map[string]Permission // Permission per normalized repository URL
+	grantingTeams   map[string][]Team     // Team chain per normalized repository URL, if known
+	rules           *accessRules          // Wildcard grants and denies, consulted when there is no exact hit
 	mu              sync.RWMutex
 	initialized     bool
-	request			string
-	response		string
+	request         string
+	response        string
+
+	// host is the GitHub host repositories are keyed on, e.g. github.com or ghe.example.com
+	host string
+	// defaultPermission is used for repositories whose provider did not report a permission
+	defaultPermission Permission
+	// failureMode decides how lookups are answered before the first successful fetch
+	failureMode FailureMode
+
+	// refreshInterval is how long the cached set stays fresh, zero disables refreshing
+	refreshInterval time.Duration
+	refreshMu       sync.Mutex  // serializes fetches so only one refresh runs at a time
+	refreshing      atomic.Bool // set while an asynchronous refresh is in flight
+	lastRefresh     time.Time   // time of the last successful fetch
+	lastAttempt     time.Time   // time of the last fetch, successful or not
+	lastRefreshErr  error
+	logger          *slog.Logger
+
+	// overrideFile holds allow and deny rules layered on the provider's repositories, empty disables it
+	overrideFile string
+
+	// cachePath persists the last fetched set across restarts, empty disables the cache
+	cachePath string
+	// cacheMaxAge is how old the cached set may be for Start to skip the initial fetch
+	cacheMaxAge time.Duration
 }
 
-// NewValidator creates a new access validator instance
+// NewValidator creates a new access validator instance backed by the resource map service
 func NewValidator(userEmail string) *Validator {
+	return NewValidatorWithProvider(userEmail, NewResourceMapProvider())
+}
+
+// NewValidatorWithProvider creates a new access validator instance backed by the given provider
+func NewValidatorWithProvider(userEmail string, provider AccessProvider) *Validator {
 	return &Validator{
-		userEmail:       userEmail,
-		accessibleRepos: make(map[string]struct{}),
+		userEmail:         userEmail,
+		provider:          provider,
+		accessibleRepos:   make(map[string]Permission),
+		grantingTeams:     make(map[string][]Team),
+		rules:             newAccessRules(),
+		host:              DefaultHost,
+		defaultPermission: PermissionRead,
+		logger:            slog.New(slog.NewTextHandler(io.Discard, nil)),
 	}
 }
 
 // Initialize fetches and caches the list of accessible repositories for the user
 // This is a blocking operation that must complete before the server can start
 func (v *Validator) Initialize() error {
-	v.mu.Lock()
-	defer v.mu.Unlock()
+	v.mu.RLock()
+	initialized := v.initialized
+	v.mu.RUnlock()
 
-	if v.initialized {
+	if initialized {
 		return nil
 	}
 
-	repos, err := v.fetchAccessibleRepositories()
+	return v.Refresh(context.Background())
+}
+
+// SetHost sets the GitHub host the validator keys repositories on and accepts repository URLs
+// for, given as a URL or hostname (see ParseHost). It applies from the next refresh on and
+// defaults to github.com.
+func (v *Validator) SetHost(host string) error {
+	parsed, err := ParseHost(host)
 	if err != nil {
-		return fmt.Errorf("failed to fetch accessible repositories: %w", err)
+		return err
 	}
 
-	// Clear any existing data and populate with fresh results
-	v.accessibleRepos = make(map[string]struct{})
-	for _, repo := range repos {
-		v.accessibleRepos[repo] = struct{}{}
+	if setter, ok := v.provider.(hostSetter); ok {
+		setter.setHost(parsed)
 	}
 
-	v.initialized = true
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.host = parsed
 	return nil
 }
 
+// SetDefaultPermission sets the permission assumed for repositories whose provider does
+// not report one. It applies from the next refresh on and defaults to PermissionRead.
+func (v *Validator) SetDefaultPermission(permission Permission) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.defaultPermission = permission
+}
+
 // IsRepositoryAccessible checks if the given repository URL is accessible to the user
 // Returns true if the repository is in the cached accessible list
 func (v *Validator) IsRepositoryAccessible(repoURL string) (bool, error) {
+	return v.HasRepositoryPermission(repoURL, PermissionRead)
+}
+
+// HasRepositoryPermission checks if the user has at least the required permission on the repository
+func (v *Validator) HasRepositoryPermission(repoURL string, required Permission) (bool, error) {
+	permission, err := v.RepositoryPermission(repoURL)
+	if err != nil {
+		return false, err
+	}
+	return permission >= required, nil
+}
+
+// RepositoryPermission returns the permission the user has on the given repository URL,
+// or PermissionNone if the repository is not in the cached accessible list or is denied.
+// Exact entries are a map lookup, other repositories are matched against the wildcard rules.
+func (v *Validator) RepositoryPermission(repoURL string) (Permission, error) {
 	v.mu.RLock()
 	defer v.mu.RUnlock()
 
 	if !v.initialized {
-		return false, fmt.Errorf("validator not initialized")
+		if permission, ok := v.fallbackPermissionLocked(); ok {
+			return permission, nil
+		}
+		return PermissionNone, fmt.Errorf("validator not initialized")
 	}
 
-	normalizedURL, err := normalizeRepositoryURL(repoURL)
+	normalizedURL, err := normalizeRepositoryURL(repoURL, v.host)
 	if err != nil {
-		return false, fmt.Errorf("failed to normalize repository URL: %w", err)
+		return PermissionNone, fmt.Errorf("failed to normalize repository URL: %w", err)
+	}
+
+	// Serve the cached answer even when it is stale and revalidate in the background
+	if v.needsRefreshLocked() {
+		v.RefreshAsync()
+	}
+
+	// Denied repositories were removed from the exact entries when the rules were applied
+	if permission, ok := v.accessibleRepos[normalizedURL]; ok {
+		return permission, nil
 	}
+	return v.rules.permission(normalizedURL), nil
+}
+
+// GrantingTeams returns the chain of teams that grants the user access to the given
+// repository URL, or nil if the repository is not accessible or was not granted through a team
+func (v *Validator) GrantingTeams(repoURL string) ([]Team, error) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
 
-	_, exists := v.accessibleRepos[normalizedURL]
-	return exists, nil
+	normalizedURL, err := normalizeRepositoryURL(repoURL, v.host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to normalize repository URL: %w", err)
+	}
+	return v.grantingTeams[normalizedURL], nil
 }
 
 // GetAccessibleRepositories returns a copy of the accessible repositories set
@@ -82,114 +179,104 @@ func (v *Validator) GetAccessibleRepositories() []string {
 	return repos
 }
 
-// fetchAccessibleRepositories fetches accessible repositories using the resource map service
-func (v *Validator) fetchAccessibleRepositories() ([]string, error) {
-	repos, requestJSON, responseJSON, err := GetAllAccessibleRepos(v.userEmail)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch accessible repositories: %w", err)
+// GetRepositoryPermissions returns a copy of the permission held on each accessible repository
+func (v *Validator) GetRepositoryPermissions() map[string]Permission {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	permissions := make(map[string]Permission, len(v.accessibleRepos))
+	for repo, permission := range v.accessibleRepos {
+		permissions[repo] = permission
 	}
+	return permissions
+}
 
-	// Store the actual request and response data
-	v.request = requestJSON
-	v.response = responseJSON
+// GetGrantPatterns returns a copy of the permission granted by each wildcard allow rule, keyed
+// on its pattern, e.g. github.com/org/*. Repositories matching a pattern are accessible
+// without being listed by GetRepositoryPermissions, unless a deny rule matches them too.
+func (v *Validator) GetGrantPatterns() map[string]Permission {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
 
-	// Convert repository structs to normalized URL format with lowercase owner/repo
-	repoURLs := make([]string, 0, len(repos))
-	for _, repo := range repos {
-		repoURL := fmt.Sprintf("github.com/%s/%s", strings.ToLower(repo.GetOrg()), strings.ToLower(repo.GetRepo()))
-		repoURLs = append(repoURLs, repoURL)
+	return v.rules.grantPatterns()
+}
+
+// fetchAccessibleRepositories fetches accessible repositories using the configured provider and
+// layers the override file on them. It returns the permission and granting team chain per
+// normalized repository URL, keeping the highest permission and the chain granting it when a
+// repository is reported more than once, and the rules for repositories with wildcards.
+func (v *Validator) fetchAccessibleRepositories(ctx context.Context) (map[string]Permission, map[string][]Team, *accessRules, error) {
+	repos, err := v.provider.AccessibleRepositories(ctx, v.userEmail)
+
+	// Store the actual request and response data when the provider records them
+	if recorder, ok := v.provider.(exchangeRecorder); ok {
+		request, response := recorder.LastExchange()
+		v.mu.Lock()
+		v.request, v.response = request, response
+		v.mu.Unlock()
 	}
 
-	return repoURLs, nil
-}
+	v.mu.RLock()
+	defaultPermission := v.defaultPermission
+	host := v.host
+	overrideFile := v.overrideFile
+	v.mu.RUnlock()
 
-// normalizeRepositoryURL converts various GitHub URL formats to a consistent format
-// Handles: https://github.com/owner/repo, github.com/owner/repo, owner/repo
-// Performs case insensitive matching for owner and repo parameters
-func normalizeRepositoryURL(repoURL string) (string, error) {
-	if repoURL == "" {
-		return "", fmt.Errorf("repository URL cannot be empty")
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to fetch accessible repositories: %w", err)
 	}
 
-	// Handle full URLs
-	if strings.HasPrefix(repoURL, "http://") || strings.HasPrefix(repoURL, "https://") {
-		parsedURL, err := url.Parse(repoURL)
+	var deny []Repository
+	if overrideFile != "" {
+		allow, denied, err := loadOverrideFile(overrideFile, host)
 		if err != nil {
-			return "", fmt.Errorf("invalid URL format: %w", err)
-		}
-		
-		// Validate that this is a GitHub URL (case insensitive)
-		if strings.ToLower(parsedURL.Host) != "github.com" {
-			return "", fmt.Errorf("unsupported repository URL format: %s", repoURL)
+			return nil, nil, nil, err
 		}
-		
-		// Extract the path and remove leading slash
-		path := strings.TrimPrefix(parsedURL.Path, "/")
-		// Remove .git suffix if present
-		path = strings.TrimSuffix(path, ".git")
-		
-		// Validate path format (should be owner/repo)
-		if !isValidRepoPath(path) {
-			return "", fmt.Errorf("unsupported repository URL format: %s", repoURL)
+		repos = append(repos, allow...)
+		deny = denied
+	}
+
+	// Convert repository structs to normalized URL format with lowercase owner/repo
+	repoURLs := make(map[string]Permission, len(repos))
+	teams := make(map[string][]Team)
+	rules := newAccessRules()
+	for _, repo := range repos {
+		repoURL := repositoryKey(host, repo.GetOrg(), repo.GetRepo())
+		permission := repo.GetPermission()
+		if permission == PermissionNone {
+			permission = defaultPermission
 		}
-		
-		// Convert owner/repo to lowercase for case insensitive matching
-		path = strings.ToLower(path)
-		
-		return fmt.Sprintf("github.com/%s", path), nil
-	}
-
-	// Handle github.com/owner/repo format
-	if strings.HasPrefix(strings.ToLower(repoURL), "github.com/") {
-		path := strings.TrimPrefix(repoURL, "github.com/")
-		if path == repoURL {
-			// Case where the prefix didn't match exactly, try case insensitive
-			path = repoURL[len("github.com/"):]
+		if isRepositoryPattern(repoURL) {
+			if err := rules.grant(repoURL, permission); err != nil {
+				return nil, nil, nil, err
+			}
+			continue
 		}
-		path = strings.TrimSuffix(path, ".git")
-		
-		// Validate path format (should be owner/repo)
-		if !isValidRepoPath(path) {
-			return "", fmt.Errorf("unsupported repository URL format: %s", repoURL)
+		if permission > repoURLs[repoURL] {
+			repoURLs[repoURL] = permission
+			if len(repo.GetTeams()) > 0 {
+				teams[repoURL] = repo.GetTeams()
+			} else {
+				delete(teams, repoURL)
+			}
 		}
-		
-		// Convert owner/repo to lowercase for case insensitive matching
-		path = strings.ToLower(path)
-		
-		return fmt.Sprintf("github.com/%s", path), nil
-	}
-
-	// Handle owner/repo format
-	if strings.Count(repoURL, "/") == 1 {
-		path := strings.TrimSuffix(repoURL, ".git")
-		
-		// Validate path format (should be owner/repo)
-		if !isValidRepoPath(path) {
-			return "", fmt.Errorf("unsupported repository URL format: %s", repoURL)
+	}
+	for _, repo := range deny {
+		if err := rules.deny(repositoryKey(host, repo.GetOrg(), repo.GetRepo())); err != nil {
+			return nil, nil, nil, err
 		}
-		
-		// Convert owner/repo to lowercase for case insensitive matching
-		path = strings.ToLower(path)
-		
-		return fmt.Sprintf("github.com/%s", path), nil
 	}
+	rules.apply(repoURLs, teams)
 
-	return "", fmt.Errorf("unsupported repository URL format: %s", repoURL)
+	return repoURLs, teams, rules, nil
 }
 
-// isValidRepoPath validates that a path is in the format owner/repo
-func isValidRepoPath(path string) bool {
-	if path == "" {
-		return false
-	}
-	
-	parts := strings.Split(path, "/")
-	if len(parts) != 2 {
-		return false
+// Close releases the resources held by the provider, such as its connection to the resource-map service
+func (v *Validator) Close() error {
+	if closer, ok := v.provider.(io.Closer); ok {
+		return closer.Close()
 	}
-	
-	// Both owner and repo name should be non-empty
-	return parts[0] != "" && parts[1] != ""
+	return nil
 }
 
 // GetStoredRequest returns the stored request data as JSON string
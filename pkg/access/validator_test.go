@@ -1,10 +1,15 @@
 package access
 
 import (
+	"context"
 	"testing"
+	"time"
 
+	"github.com/github/github-mcp-server/internal/resourcemaptest"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func TestNewValidator(t *testing.T) {
@@ -16,10 +21,21 @@ func TestNewValidator(t *testing.T) {
 	assert.Equal(t, userEmail, validator.userEmail)
 	assert.False(t, validator.initialized)
 	assert.NotNil(t, validator.accessibleRepos)
+	assert.IsType(t, &ResourceMapProvider{}, validator.provider)
+}
+
+// newDummyProvider returns a provider serving a fixed set of dummy repositories
+func newDummyProvider() AccessProvider {
+	return NewStaticProvider(
+		"user/repo1",
+		"user/repo2",
+		"org/public-repo",
+		"github/github-mcp-server",
+	)
 }
 
 func TestValidator_Initialize(t *testing.T) {
-	validator := NewValidator("test@example.com")
+	validator := NewValidatorWithProvider("test@example.com", newDummyProvider())
 
 	// First initialization should succeed
 	err := validator.Initialize()
@@ -35,7 +51,7 @@ func TestValidator_Initialize(t *testing.T) {
 }
 
 func TestValidator_IsRepositoryAccessible(t *testing.T) {
-	validator := NewValidator("test@example.com")
+	validator := NewValidatorWithProvider("test@example.com", newDummyProvider())
 
 	// Should fail when not initialized
 	accessible, err := validator.IsRepositoryAccessible("github.com/user/repo1")
@@ -49,87 +65,87 @@ func TestValidator_IsRepositoryAccessible(t *testing.T) {
 
 	// Test cases for accessible repositories (from dummy data)
 	testCases := []struct {
-		name        string
-		repoURL     string
+		name         string
+		repoURL      string
 		expectAccess bool
 		expectError  bool
 	}{
 		{
-			name:        "accessible repo - exact match",
-			repoURL:     "github.com/user/repo1",
+			name:         "accessible repo - exact match",
+			repoURL:      "github.com/user/repo1",
 			expectAccess: true,
 			expectError:  false,
 		},
 		{
-			name:        "accessible repo - https URL",
-			repoURL:     "https://github.com/user/repo1",
+			name:         "accessible repo - https URL",
+			repoURL:      "https://github.com/user/repo1",
 			expectAccess: true,
 			expectError:  false,
 		},
 		{
-			name:        "accessible repo - short format",
-			repoURL:     "user/repo1",
+			name:         "accessible repo - short format",
+			repoURL:      "user/repo1",
 			expectAccess: true,
 			expectError:  false,
 		},
 		{
-			name:        "accessible repo - with .git suffix",
-			repoURL:     "https://github.com/user/repo1.git",
+			name:         "accessible repo - with .git suffix",
+			repoURL:      "https://github.com/user/repo1.git",
 			expectAccess: true,
 			expectError:  false,
 		},
 		{
-			name:        "inaccessible repo",
-			repoURL:     "github.com/other/repo",
+			name:         "inaccessible repo",
+			repoURL:      "github.com/other/repo",
 			expectAccess: false,
 			expectError:  false,
 		},
 		{
-			name:        "empty URL",
-			repoURL:     "",
+			name:         "empty URL",
+			repoURL:      "",
 			expectAccess: false,
 			expectError:  true,
 		},
 		{
-			name:        "invalid URL format",
-			repoURL:     "invalid-url",
+			name:         "invalid URL format",
+			repoURL:      "invalid-url",
 			expectAccess: false,
 			expectError:  true,
 		},
 		// Case insensitive test cases
 		{
-			name:        "accessible repo - uppercase owner",
-			repoURL:     "github.com/USER/repo1",
+			name:         "accessible repo - uppercase owner",
+			repoURL:      "github.com/USER/repo1",
 			expectAccess: true,
 			expectError:  false,
 		},
 		{
-			name:        "accessible repo - uppercase repo name",
-			repoURL:     "github.com/user/REPO1",
+			name:         "accessible repo - uppercase repo name",
+			repoURL:      "github.com/user/REPO1",
 			expectAccess: true,
 			expectError:  false,
 		},
 		{
-			name:        "accessible repo - mixed case",
-			repoURL:     "github.com/UsEr/RePoSiToRy1",
+			name:         "accessible repo - mixed case",
+			repoURL:      "github.com/UsEr/RePoSiToRy1",
 			expectAccess: false, // This should be false since "RePoSiToRy1" != "repo1"
 			expectError:  false,
 		},
 		{
-			name:        "accessible repo - mixed case https",
-			repoURL:     "https://github.com/USER/REPO1",
+			name:         "accessible repo - mixed case https",
+			repoURL:      "https://github.com/USER/REPO1",
 			expectAccess: true,
 			expectError:  false,
 		},
 		{
-			name:        "accessible repo - mixed case short format",
-			repoURL:     "USER/REPO1",
+			name:         "accessible repo - mixed case short format",
+			repoURL:      "USER/REPO1",
 			expectAccess: true,
 			expectError:  false,
 		},
 		{
-			name:        "accessible repo - mixed case with .git",
-			repoURL:     "https://github.com/USER/REPO1.git",
+			name:         "accessible repo - mixed case with .git",
+			repoURL:      "https://github.com/USER/REPO1.git",
 			expectAccess: true,
 			expectError:  false,
 		},
@@ -138,7 +154,7 @@ func TestValidator_IsRepositoryAccessible(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			accessible, err := validator.IsRepositoryAccessible(tc.repoURL)
-			
+
 			if tc.expectError {
 				assert.Error(t, err)
 				assert.False(t, accessible)
@@ -151,7 +167,7 @@ func TestValidator_IsRepositoryAccessible(t *testing.T) {
 }
 
 func TestValidator_GetAccessibleRepositories(t *testing.T) {
-	validator := NewValidator("test@example.com")
+	validator := NewValidatorWithProvider("test@example.com", newDummyProvider())
 
 	// Before initialization
 	repos := validator.GetAccessibleRepositories()
@@ -163,7 +179,7 @@ func TestValidator_GetAccessibleRepositories(t *testing.T) {
 
 	repos = validator.GetAccessibleRepositories()
 	assert.NotEmpty(t, repos)
-	
+
 	// Should contain the dummy repositories
 	expectedRepos := []string{
 		"github.com/user/repo1",
@@ -171,7 +187,7 @@ func TestValidator_GetAccessibleRepositories(t *testing.T) {
 		"github.com/org/public-repo",
 		"github.com/github/github-mcp-server",
 	}
-	
+
 	assert.Len(t, repos, len(expectedRepos))
 	for _, expectedRepo := range expectedRepos {
 		assert.Contains(t, repos, expectedRepo)
@@ -340,8 +356,8 @@ func TestNormalizeRepositoryURL(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			result, err := normalizeRepositoryURL(tc.input)
-			
+			result, err := normalizeRepositoryURL(tc.input, DefaultHost)
+
 			if tc.expectError {
 				assert.Error(t, err)
 				assert.Empty(t, result)
@@ -354,7 +370,7 @@ func TestNormalizeRepositoryURL(t *testing.T) {
 }
 
 func TestValidator_ConcurrentAccess(t *testing.T) {
-	validator := NewValidator("test@example.com")
+	validator := NewValidatorWithProvider("test@example.com", newDummyProvider())
 
 	// Initialize the validator
 	err := validator.Initialize()
@@ -369,12 +385,12 @@ func TestValidator_ConcurrentAccess(t *testing.T) {
 	for i := 0; i < numGoroutines; i++ {
 		go func() {
 			defer func() { done <- true }()
-			
+
 			for j := 0; j < numCalls; j++ {
 				accessible, err := validator.IsRepositoryAccessible("github.com/user/repo1")
 				assert.NoError(t, err)
 				assert.True(t, accessible)
-				
+
 				repos := validator.GetAccessibleRepositories()
 				assert.NotEmpty(t, repos)
 			}
@@ -386,3 +402,157 @@ func TestValidator_ConcurrentAccess(t *testing.T) {
 		<-done
 	}
 }
+
+func TestValidator_GrantingTeams(t *testing.T) {
+	backend := []Team{{Org: "org", Name: "backend"}}
+	platform := []Team{{Org: "org", Name: "platform"}}
+	provider := repositoriesProvider{
+		{Org: "org", Repo: "api", Permission: PermissionRead, Teams: backend},
+		// The chain granting the highest permission wins
+		{Org: "org", Repo: "api", Permission: PermissionWrite, Teams: platform},
+		{Org: "org", Repo: "api", Permission: PermissionWrite, Teams: backend},
+		{Org: "org", Repo: "direct"},
+	}
+
+	validator := NewValidatorWithProvider("test@example.com", provider)
+	require.NoError(t, validator.Initialize())
+
+	teams, err := validator.GrantingTeams("https://github.com/Org/API")
+	require.NoError(t, err)
+	assert.Equal(t, platform, teams)
+
+	teams, err = validator.GrantingTeams("org/direct")
+	require.NoError(t, err)
+	assert.Empty(t, teams)
+
+	teams, err = validator.GrantingTeams("org/unknown")
+	require.NoError(t, err)
+	assert.Empty(t, teams)
+
+	_, err = validator.GrantingTeams("not a repository")
+	require.Error(t, err)
+}
+
+func TestValidator_ResourceMapProvider(t *testing.T) {
+	server := resourcemaptest.NewServer(t)
+	member := server.AddMember("test@example.com")
+	backend := server.AddTeam("octo-org", "backend")
+	platform := server.AddTeam("octo-org", "platform")
+	server.Join(member, backend)
+	server.Join(member, platform)
+	server.Grant(backend, server.AddRepository("octo-org", "api"))
+	server.Grant(platform, server.AddRepository("Octo-Org", "API"))
+	server.Grant(platform, server.AddRepository("octo-org", "docs"))
+	server.Grant(backend, server.AddRepository("octo-org", "admin"))
+
+	validator := NewValidatorWithProvider("test@example.com", newBufconnProvider(t, server, time.Second))
+	validator.SetDefaultPermission(PermissionTriage)
+	require.NoError(t, validator.Start(context.Background(), fastRetries(0, FailClosed)))
+
+	tests := []struct {
+		repoURL    string
+		permission Permission
+		teams      []Team
+	}{
+		// The resource map reports no permission, so every repository gets the default one,
+		// with the first team granting it
+		{repoURL: "octo-org/api", permission: PermissionTriage, teams: []Team{{Org: "octo-org", Name: "backend"}}},
+		{repoURL: "octo-org/docs", permission: PermissionTriage, teams: []Team{{Org: "octo-org", Name: "platform"}}},
+		{repoURL: "https://github.com/octo-org/admin", permission: PermissionTriage, teams: []Team{{Org: "octo-org", Name: "backend"}}},
+		{repoURL: "octo-org/other", permission: PermissionNone},
+	}
+	for _, tc := range tests {
+		t.Run(tc.repoURL, func(t *testing.T) {
+			permission, err := validator.RepositoryPermission(tc.repoURL)
+			require.NoError(t, err)
+			assert.Equal(t, tc.permission, permission)
+
+			teams, err := validator.GrantingTeams(tc.repoURL)
+			require.NoError(t, err)
+			assert.Equal(t, tc.teams, teams)
+		})
+	}
+
+	assert.NotEmpty(t, validator.GetStoredRequest())
+	assert.NotEmpty(t, validator.GetStoredResponse())
+}
+
+func TestValidator_ResourceMapProviderFailures(t *testing.T) {
+	t.Run("fail closed", func(t *testing.T) {
+		server := resourcemaptest.NewServer(t)
+		server.SetError(status.Error(codes.Unavailable, "resource map is down"))
+		validator := NewValidatorWithProvider("test@example.com", newBufconnProvider(t, server, time.Second))
+
+		err := validator.Start(context.Background(), fastRetries(50*time.Millisecond, FailClosed))
+		require.Error(t, err)
+		assert.Greater(t, len(server.Requests()), 1, "startup retries the traversal")
+
+		// The failed request is still recorded, without a response
+		assert.NotEmpty(t, validator.GetStoredRequest())
+		assert.Empty(t, validator.GetStoredResponse())
+	})
+
+	t.Run("request timeout", func(t *testing.T) {
+		server := resourcemaptest.NewServer(t)
+		server.SetDelay(time.Minute)
+		validator := NewValidatorWithProvider("test@example.com", newBufconnProvider(t, server, 50*time.Millisecond))
+
+		err := validator.Initialize()
+		require.Error(t, err)
+		assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+	})
+
+	t.Run("service unreachable", func(t *testing.T) {
+		server := resourcemaptest.NewServer(t)
+		server.Stop()
+		validator := NewValidatorWithProvider("test@example.com", newBufconnProvider(t, server, time.Second))
+		require.Error(t, validator.Initialize())
+	})
+
+	t.Run("fail open until the service recovers", func(t *testing.T) {
+		server := resourcemaptest.NewServer(t)
+		member := server.AddMember("test@example.com")
+		team := server.AddTeam("octo-org", "backend")
+		server.Join(member, team)
+		server.Grant(team, server.AddRepository("octo-org", "api"))
+		server.SetError(status.Error(codes.Unavailable, "resource map is down"))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		validator := NewValidatorWithProvider("test@example.com", newBufconnProvider(t, server, time.Second))
+		require.NoError(t, validator.Start(ctx, fastRetries(20*time.Millisecond, FailOpen)))
+
+		accessible, err := validator.IsRepositoryAccessible("octo-org/other")
+		require.NoError(t, err)
+		assert.True(t, accessible, "every repository is accessible until the list loads")
+
+		server.SetError(nil)
+		assert.Eventually(t, func() bool {
+			accessible, err := validator.IsRepositoryAccessible("octo-org/other")
+			return err == nil && !accessible
+		}, 5*time.Second, 10*time.Millisecond)
+
+		accessible, err = validator.IsRepositoryAccessible("octo-org/api")
+		require.NoError(t, err)
+		assert.True(t, accessible)
+	})
+
+	t.Run("failed refresh keeps the previous set", func(t *testing.T) {
+		server := resourcemaptest.NewServer(t)
+		member := server.AddMember("test@example.com")
+		team := server.AddTeam("octo-org", "backend")
+		server.Join(member, team)
+		server.Grant(team, server.AddRepository("octo-org", "api"))
+
+		validator := NewValidatorWithProvider("test@example.com", newBufconnProvider(t, server, time.Second))
+		require.NoError(t, validator.Initialize())
+
+		server.SetError(status.Error(codes.Internal, "traversal failed"))
+		require.Error(t, validator.Refresh(context.Background()))
+		assert.Error(t, validator.Status().LastError)
+
+		permission, err := validator.RepositoryPermission("octo-org/api")
+		require.NoError(t, err)
+		assert.Equal(t, PermissionRead, permission)
+	})
+}
@@ -0,0 +1,138 @@
+// Package audit records access decisions and tool invocations as JSON Lines.
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// Decision is the outcome of the repository access check for a tool call
+type Decision string
+
+const (
+	// DecisionAllowed means the repository was accessible with the required permission
+	DecisionAllowed Decision = "allowed"
+	// DecisionDenied means the repository was not accessible or the permission was too low
+	DecisionDenied Decision = "denied"
+	// DecisionError means access could not be validated, so the call was refused
+	DecisionError Decision = "error"
+)
+
+// Record is a single audit log entry
+type Record struct {
+	Timestamp  time.Time      `json:"timestamp"`
+	UserEmail  string         `json:"user_email"`
+	Tool       string         `json:"tool"`
+	Owner      string         `json:"owner,omitempty"`
+	Repo       string         `json:"repo,omitempty"`
+	Decision   Decision       `json:"decision,omitempty"`
+	Reason     string         `json:"reason,omitempty"`
+	StatusCode int            `json:"status_code,omitempty"`
+	ToolError  bool           `json:"tool_error,omitempty"`
+	DurationMS int64          `json:"duration_ms"`
+	Arguments  map[string]any `json:"arguments,omitempty"`
+}
+
+// Destinations accepted by Config.Path besides a file path
+const (
+	Stdout = "stdout"
+	Stderr = "stderr"
+)
+
+// Config configures where audit records are written
+type Config struct {
+	// Path is the log file, or Stdout / Stderr
+	Path string
+	// MaxSize is the size in bytes at which the file is rotated, zero disables rotation
+	MaxSize int64
+	// MaxBackups is the number of rotated files to keep
+	MaxBackups int
+}
+
+// Logger writes audit records as JSON Lines. It is safe for concurrent use.
+type Logger struct {
+	mu     sync.Mutex
+	writer io.Writer
+	closer io.Closer
+	// errorLogger reports records that could not be written
+	errorLogger *slog.Logger
+}
+
+// NewLogger creates a logger writing to w
+func NewLogger(w io.Writer) *Logger {
+	return &Logger{writer: w}
+}
+
+// SetErrorLogger sets the logger used to report records that could not be written
+func (l *Logger) SetErrorLogger(logger *slog.Logger) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.errorLogger = logger
+}
+
+// Open creates a logger for the destination in config. Files are created if needed,
+// appended to, and rotated once they reach MaxSize.
+func Open(config Config) (*Logger, error) {
+	switch config.Path {
+	case "":
+		return nil, fmt.Errorf("audit log path is required")
+	case Stdout:
+		return NewLogger(os.Stdout), nil
+	case Stderr:
+		return NewLogger(os.Stderr), nil
+	}
+
+	file, err := openRotatingFile(config.Path, config.MaxSize, config.MaxBackups)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	return &Logger{writer: file, closer: file}, nil
+}
+
+// Log writes a record as a single line. A zero timestamp is set to the current time.
+func (l *Logger) Log(record Record) error {
+	if record.Timestamp.IsZero() {
+		record.Timestamp = time.Now().UTC()
+	}
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit record: %w", err)
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.writer.Write(line); err != nil {
+		return fmt.Errorf("failed to write audit record: %w", err)
+	}
+	return nil
+}
+
+// logOrReport writes a record and reports a failure to the error logger instead of the caller,
+// so a broken audit log never fails a tool call
+func (l *Logger) logOrReport(record Record) {
+	if err := l.Log(record); err != nil {
+		l.mu.Lock()
+		errorLogger := l.errorLogger
+		l.mu.Unlock()
+		if errorLogger != nil {
+			errorLogger.Error("failed to write audit record", "tool", record.Tool, "error", err)
+		}
+	}
+}
+
+// Close closes the underlying file, if the logger opened one
+func (l *Logger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closer == nil {
+		return nil
+	}
+	return l.closer.Close()
+}
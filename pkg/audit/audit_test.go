@@ -0,0 +1,93 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogger_Log(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewLogger(&buf)
+
+	timestamp := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	require.NoError(t, logger.Log(Record{
+		Timestamp:  timestamp,
+		UserEmail:  "someone@example.com",
+		Tool:       "create_branch",
+		Owner:      "org",
+		Repo:       "repo",
+		Decision:   DecisionAllowed,
+		StatusCode: 201,
+	}))
+	require.NoError(t, logger.Log(Record{Tool: "get_me"}))
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	require.Len(t, lines, 2)
+
+	var first map[string]any
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &first))
+	assert.Equal(t, "2025-01-02T03:04:05Z", first["timestamp"])
+	assert.Equal(t, "someone@example.com", first["user_email"])
+	assert.Equal(t, "create_branch", first["tool"])
+	assert.Equal(t, "org", first["owner"])
+	assert.Equal(t, "repo", first["repo"])
+	assert.Equal(t, "allowed", first["decision"])
+	assert.Equal(t, float64(201), first["status_code"])
+
+	// A missing timestamp is filled in and empty fields are left out
+	var second map[string]any
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &second))
+	assert.NotEmpty(t, second["timestamp"])
+	assert.NotContains(t, second, "owner")
+	assert.NotContains(t, second, "decision")
+}
+
+func TestOpen(t *testing.T) {
+	t.Run("file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "audit.log")
+		logger, err := Open(Config{Path: path})
+		require.NoError(t, err)
+		require.NoError(t, logger.Log(Record{Tool: "get_me"}))
+		require.NoError(t, logger.Close())
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `"tool":"get_me"`)
+
+		// Reopening appends
+		logger, err = Open(Config{Path: path})
+		require.NoError(t, err)
+		require.NoError(t, logger.Log(Record{Tool: "list_branches"}))
+		require.NoError(t, logger.Close())
+
+		data, err = os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, 2, strings.Count(string(data), "\n"))
+	})
+
+	t.Run("standard streams", func(t *testing.T) {
+		for _, path := range []string{Stdout, Stderr} {
+			logger, err := Open(Config{Path: path})
+			require.NoError(t, err)
+			require.NoError(t, logger.Close())
+		}
+	})
+
+	t.Run("missing path", func(t *testing.T) {
+		_, err := Open(Config{})
+		require.Error(t, err)
+	})
+
+	t.Run("missing directory", func(t *testing.T) {
+		_, err := Open(Config{Path: filepath.Join(t.TempDir(), "missing", "audit.log")})
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,120 @@
+package audit
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/github/github-mcp-server/pkg/toolsets"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+type entryKey struct{}
+
+type userEmailKey struct{}
+
+// entry collects what happens during a tool call so it can be logged once the call returns
+type entry struct {
+	mu         sync.Mutex
+	decision   Decision
+	reason     string
+	statusCode int
+}
+
+// SetDecision records the access decision for the tool call in ctx.
+// It does nothing when the call is not being audited.
+func SetDecision(ctx context.Context, decision Decision, reason string) {
+	if e, ok := ctx.Value(entryKey{}).(*entry); ok {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		e.decision = decision
+		e.reason = reason
+	}
+}
+
+// SetStatusCode records the status code of a GitHub API response for the tool call in ctx.
+// The last response wins. It does nothing when the call is not being audited.
+func SetStatusCode(ctx context.Context, statusCode int) {
+	if e, ok := ctx.Value(entryKey{}).(*entry); ok {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		e.statusCode = statusCode
+	}
+}
+
+// ContextWithUserEmail records the user a request is made for. It overrides the user email
+// given to Middleware, for servers that are shared by several users.
+func ContextWithUserEmail(ctx context.Context, userEmail string) context.Context {
+	return context.WithValue(ctx, userEmailKey{}, userEmail)
+}
+
+// Middleware returns a tool middleware that writes an audit record for every tool call.
+// It must wrap the repository access middleware so calls refused by the access check are
+// recorded too.
+func Middleware(logger *Logger, userEmail string) toolsets.ToolMiddleware {
+	return func(tool mcp.Tool, next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		if logger == nil {
+			return next
+		}
+
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			e := &entry{}
+			ctx = context.WithValue(ctx, entryKey{}, e)
+
+			recordEmail := userEmail
+			if email, ok := ctx.Value(userEmailKey{}).(string); ok {
+				recordEmail = email
+			}
+
+			start := time.Now()
+			result, err := next(ctx, request)
+
+			args := request.GetArguments()
+			owner, _ := args["owner"].(string)
+			repo, _ := args["repo"].(string)
+
+			e.mu.Lock()
+			record := Record{
+				Timestamp:  start.UTC(),
+				UserEmail:  recordEmail,
+				Tool:       tool.Name,
+				Owner:      owner,
+				Repo:       repo,
+				Decision:   e.decision,
+				Reason:     e.reason,
+				StatusCode: e.statusCode,
+				ToolError:  err != nil || (result != nil && result.IsError),
+				DurationMS: time.Since(start).Milliseconds(),
+				Arguments:  RedactArguments(args),
+			}
+			e.mu.Unlock()
+
+			logger.logOrReport(record)
+			return result, err
+		}
+	}
+}
+
+// Transport records the status code of every GitHub API response in the audit entry of
+// the request context, so the audit record shows how GitHub answered the tool call. It sits
+// below the REST response cache, so a response served from the cache after revalidation is
+// recorded as the 304 GitHub answered, although the tool saw the cached status.
+type Transport struct {
+	Transport http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	transport := t.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	resp, err := transport.RoundTrip(req)
+	if resp != nil {
+		SetStatusCode(req.Context(), resp.StatusCode)
+	}
+	return resp, err
+}
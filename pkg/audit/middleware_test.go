@@ -0,0 +1,124 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func callTool(t *testing.T, handler func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error), args map[string]any) *mcp.CallToolResult {
+	t.Helper()
+	request := mcp.CallToolRequest{}
+	request.Params.Name = "test_tool"
+	request.Params.Arguments = args
+	result, err := handler(context.Background(), request)
+	require.NoError(t, err)
+	return result
+}
+
+func TestMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewLogger(&buf)
+	tool := mcp.NewTool("create_gist")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+	client := &http.Client{Transport: &Transport{}}
+
+	next := func(ctx context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		SetDecision(ctx, DecisionAllowed, "")
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, server.URL, nil)
+		require.NoError(t, err)
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		return mcp.NewToolResultText("ok"), nil
+	}
+
+	handler := Middleware(logger, "someone@example.com")(tool, next)
+	result := callTool(t, handler, map[string]any{
+		"owner":   "org",
+		"repo":    "repo",
+		"content": "my secret file",
+	})
+	require.False(t, result.IsError)
+
+	var record Record
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, "someone@example.com", record.UserEmail)
+	assert.Equal(t, "create_gist", record.Tool)
+	assert.Equal(t, "org", record.Owner)
+	assert.Equal(t, "repo", record.Repo)
+	assert.Equal(t, DecisionAllowed, record.Decision)
+	assert.Equal(t, http.StatusCreated, record.StatusCode)
+	assert.False(t, record.ToolError)
+	assert.Equal(t, Redacted, record.Arguments["content"])
+	assert.NotContains(t, buf.String(), "my secret file")
+}
+
+func TestMiddleware_UserEmailFromContext(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewLogger(&buf)
+
+	next := func(_ context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText("ok"), nil
+	}
+
+	handler := Middleware(logger, "")(mcp.NewTool("get_me"), next)
+	request := mcp.CallToolRequest{}
+	request.Params.Name = "get_me"
+	_, err := handler(ContextWithUserEmail(context.Background(), "someone@example.com"), request)
+	require.NoError(t, err)
+
+	var record Record
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, "someone@example.com", record.UserEmail)
+}
+
+func TestMiddleware_RecordsDeniedCalls(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewLogger(&buf)
+
+	next := func(ctx context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		SetDecision(ctx, DecisionDenied, "repository not accessible")
+		return mcp.NewToolResultError("Access denied"), nil
+	}
+
+	handler := Middleware(logger, "someone@example.com")(mcp.NewTool("list_branches"), next)
+	result := callTool(t, handler, map[string]any{"owner": "org", "repo": "secret"})
+	require.True(t, result.IsError)
+
+	var record Record
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, DecisionDenied, record.Decision)
+	assert.Equal(t, "repository not accessible", record.Reason)
+	assert.True(t, record.ToolError)
+	assert.Zero(t, record.StatusCode)
+}
+
+func TestMiddleware_NilLogger(t *testing.T) {
+	called := false
+	next := func(_ context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		called = true
+		return mcp.NewToolResultText("ok"), nil
+	}
+
+	handler := Middleware(nil, "someone@example.com")(mcp.NewTool("get_me"), next)
+	callTool(t, handler, nil)
+	assert.True(t, called)
+}
+
+func TestSetDecision_WithoutAudit(_ *testing.T) {
+	// Recording outside an audited call is a no-op
+	SetDecision(context.Background(), DecisionAllowed, "")
+	SetStatusCode(context.Background(), http.StatusOK)
+}
@@ -0,0 +1,65 @@
+package audit
+
+import "strings"
+
+// Redacted replaces the value of arguments that may hold secrets or file contents
+const Redacted = "[REDACTED]"
+
+// redactedArguments are tool arguments that carry file bodies or gist contents
+var redactedArguments = map[string]struct{}{
+	"content": {},
+	"files":   {},
+	"body":    {},
+	"patch":   {},
+	"diff":    {},
+}
+
+// redactedSubstrings mark argument names that look like credentials
+var redactedSubstrings = []string{"token", "secret", "password", "credential", "private_key"}
+
+// RedactArguments returns a copy of the tool arguments with secret-like values replaced
+// by Redacted. Nested objects are redacted recursively.
+func RedactArguments(args map[string]any) map[string]any {
+	if args == nil {
+		return nil
+	}
+
+	redacted := make(map[string]any, len(args))
+	for key, value := range args {
+		switch {
+		case isSecretArgument(key):
+			redacted[key] = Redacted
+		default:
+			redacted[key] = redactValue(value)
+		}
+	}
+	return redacted
+}
+
+func redactValue(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		return RedactArguments(v)
+	case []any:
+		values := make([]any, len(v))
+		for i, item := range v {
+			values[i] = redactValue(item)
+		}
+		return values
+	default:
+		return value
+	}
+}
+
+func isSecretArgument(key string) bool {
+	lower := strings.ToLower(key)
+	if _, ok := redactedArguments[lower]; ok {
+		return true
+	}
+	for _, substring := range redactedSubstrings {
+		if strings.Contains(lower, substring) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,51 @@
+package audit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactArguments(t *testing.T) {
+	args := map[string]any{
+		"owner":        "org",
+		"repo":         "repo",
+		"content":      "package main",
+		"Body":         "secret plans",
+		"access_token": "ghp_123",
+		"files": map[string]any{
+			"main.go": map[string]any{"content": "package main"},
+		},
+		"options": map[string]any{
+			"client_secret": "shh",
+			"limit":         float64(10),
+		},
+		"items": []any{
+			map[string]any{"password": "hunter2", "name": "a"},
+			"plain",
+		},
+	}
+
+	redacted := RedactArguments(args)
+
+	assert.Equal(t, map[string]any{
+		"owner":        "org",
+		"repo":         "repo",
+		"content":      Redacted,
+		"Body":         Redacted,
+		"access_token": Redacted,
+		"files":        Redacted,
+		"options": map[string]any{
+			"client_secret": Redacted,
+			"limit":         float64(10),
+		},
+		"items": []any{
+			map[string]any{"password": Redacted, "name": "a"},
+			"plain",
+		},
+	}, redacted)
+
+	// The original arguments are left untouched
+	assert.Equal(t, "package main", args["content"])
+	assert.Nil(t, RedactArguments(nil))
+}
@@ -0,0 +1,94 @@
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	file, err := openRotatingFile(path, 10, 2)
+	require.NoError(t, err)
+	defer func() { _ = file.Close() }()
+
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		_, err := file.Write([]byte(line))
+		require.NoError(t, err)
+	}
+
+	read := func(path string) string {
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		return string(data)
+	}
+
+	// Every line overflows the 10 byte limit, so each one starts a new file
+	// and only the two most recent backups are kept
+	assert.Equal(t, "fourth\n", read(path))
+	assert.Equal(t, "third\n", read(path+".1"))
+	assert.Equal(t, "second\n", read(path+".2"))
+	assert.NoFileExists(t, path+".3")
+}
+
+func TestRotatingFile_KeepsLinesTogether(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	file, err := openRotatingFile(path, 5, 1)
+	require.NoError(t, err)
+	defer func() { _ = file.Close() }()
+
+	// A write larger than the limit goes to a single file rather than being split
+	long := strings.Repeat("x", 20) + "\n"
+	_, err = file.Write([]byte(long))
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, long, string(data))
+}
+
+func TestRotatingFile_ResumesExistingSize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	require.NoError(t, os.WriteFile(path, []byte("existing\n"), 0600))
+
+	file, err := openRotatingFile(path, 12, 1)
+	require.NoError(t, err)
+	defer func() { _ = file.Close() }()
+
+	_, err = file.Write([]byte("next\n"))
+	require.NoError(t, err)
+
+	backup, err := os.ReadFile(path + ".1")
+	require.NoError(t, err)
+	assert.Equal(t, "existing\n", string(backup))
+}
+
+func TestRotatingFile_WithoutBackups(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	file, err := openRotatingFile(path, 5, 0)
+	require.NoError(t, err)
+	defer func() { _ = file.Close() }()
+
+	for _, line := range []string{"first\n", "second\n"} {
+		_, err := file.Write([]byte(line))
+		require.NoError(t, err)
+	}
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "second\n", string(data))
+	assert.NoFileExists(t, path+".1")
+}
+
+func TestRotatingFile_WriteAfterClose(t *testing.T) {
+	file, err := openRotatingFile(filepath.Join(t.TempDir(), "audit.log"), 0, 0)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	_, err = file.Write([]byte("late\n"))
+	require.ErrorIs(t, err, os.ErrClosed)
+}
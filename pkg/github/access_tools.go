@@ -0,0 +1,247 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/github/github-mcp-server/pkg/access"
+	"github.com/github/github-mcp-server/pkg/translations"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// AccessibleRepository is a repository the current user may work in
+type AccessibleRepository struct {
+	Owner      string `json:"owner"`
+	Repo       string `json:"repo"`
+	Permission string `json:"permission"`
+}
+
+// AccessiblePattern is a wildcard allow rule, granting every repository matching it that
+// no deny rule matches
+type AccessiblePattern struct {
+	Owner      string `json:"owner"`
+	Repo       string `json:"repo"`
+	Permission string `json:"permission"`
+}
+
+// AccessibleRepositoriesResult is a page of the repositories the current user may work in,
+// along with every wildcard allow rule matching the filters
+type AccessibleRepositoriesResult struct {
+	TotalCount   int                    `json:"total_count"`
+	Repositories []AccessibleRepository `json:"repositories"`
+	Patterns     []AccessiblePattern    `json:"patterns,omitempty"`
+}
+
+// RepositoryAccessResult is the access decision for a repository
+type RepositoryAccessResult struct {
+	Owner      string `json:"owner"`
+	Repo       string `json:"repo"`
+	Accessible bool   `json:"accessible"`
+	Permission string `json:"permission"`
+	Required   string `json:"required,omitempty"`
+	Allowed    bool   `json:"allowed"`
+	Reason     string `json:"reason"`
+	// TeamPath is the chain of teams granting access, starting with the team the user is a member of
+	TeamPath []access.Team `json:"team_path,omitempty"`
+}
+
+// ListAccessibleRepositories creates a tool to list the repositories the current user may work in
+func ListAccessibleRepositories(getValidator GetValidatorFn, t translations.TranslationHelperFunc) (tool mcp.Tool, handler server.ToolHandlerFunc) {
+	return mcp.NewTool("list_accessible_repositories",
+			mcp.WithDescription(t("TOOL_LIST_ACCESSIBLE_REPOSITORIES_DESCRIPTION", "List the repositories the current user is allowed to work in with this server, and the permission held on each. Repositories granted by wildcard or org-level rules are not listed one by one: each rule is returned under patterns, e.g. owner \"my-org\" and repo \"*\", and grants every matching repository. Calls targeting any other repository are refused.")),
+			mcp.WithToolAnnotation(mcp.ToolAnnotation{
+				Title:        t("TOOL_LIST_ACCESSIBLE_REPOSITORIES_USER_TITLE", "List accessible repositories"),
+				ReadOnlyHint: ToBoolPtr(true),
+			}),
+			mcp.WithString("org",
+				mcp.Description("Only list repositories owned by this organization or user"),
+			),
+			mcp.WithString("prefix",
+				mcp.Description("Only list repositories whose name starts with this prefix"),
+			),
+			WithPagination(),
+		),
+		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			org, err := OptionalParam[string](request, "org")
+			if err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+			prefix, err := OptionalParam[string](request, "prefix")
+			if err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+			pagination, err := OptionalPaginationParams(request)
+			if err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+
+			validator, err := resolveValidator(ctx, getValidator)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("failed to validate repository access: %s", err)), nil
+			}
+			if validator == nil {
+				return mcp.NewToolResultError("repository access validation is not configured for this server"), nil
+			}
+
+			org = strings.ToLower(org)
+			prefix = strings.ToLower(prefix)
+
+			repos := []AccessibleRepository{}
+			for repoURL, permission := range validator.GetRepositoryPermissions() {
+				// Repositories are keyed as host/owner/repo
+				parts := strings.Split(repoURL, "/")
+				if len(parts) != 3 {
+					continue
+				}
+				owner, repo := parts[1], parts[2]
+				if (org != "" && owner != org) || !strings.HasPrefix(repo, prefix) {
+					continue
+				}
+				repos = append(repos, AccessibleRepository{Owner: owner, Repo: repo, Permission: permission.String()})
+			}
+			sort.Slice(repos, func(i, j int) bool {
+				if repos[i].Owner != repos[j].Owner {
+					return repos[i].Owner < repos[j].Owner
+				}
+				return repos[i].Repo < repos[j].Repo
+			})
+
+			result := AccessibleRepositoriesResult{TotalCount: len(repos), Repositories: []AccessibleRepository{}}
+			start := (pagination.Page - 1) * pagination.PerPage
+			if start < len(repos) {
+				result.Repositories = repos[start:min(start+pagination.PerPage, len(repos))]
+			}
+
+			// Patterns are few, so they are returned with every page. One whose owner has
+			// wildcards may match any org, and one whose name has wildcards any prefix.
+			for pattern, permission := range validator.GetGrantPatterns() {
+				parts := strings.Split(pattern, "/")
+				if len(parts) != 3 {
+					continue
+				}
+				owner, repo := parts[1], parts[2]
+				if org != "" && owner != org && !strings.ContainsAny(owner, "*?") {
+					continue
+				}
+				if !strings.HasPrefix(repo, prefix) && !strings.ContainsAny(repo, "*?") {
+					continue
+				}
+				result.Patterns = append(result.Patterns, AccessiblePattern{Owner: owner, Repo: repo, Permission: permission.String()})
+			}
+			sort.Slice(result.Patterns, func(i, j int) bool {
+				if result.Patterns[i].Owner != result.Patterns[j].Owner {
+					return result.Patterns[i].Owner < result.Patterns[j].Owner
+				}
+				return result.Patterns[i].Repo < result.Patterns[j].Repo
+			})
+
+			r, err := json.Marshal(result)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal response: %w", err)
+			}
+
+			return mcp.NewToolResultText(string(r)), nil
+		}
+}
+
+// CheckRepositoryAccess creates a tool to explain whether the current user may work in a repository
+func CheckRepositoryAccess(getValidator GetValidatorFn, t translations.TranslationHelperFunc) (tool mcp.Tool, handler server.ToolHandlerFunc) {
+	return mcp.NewTool("check_repository_access",
+			mcp.WithDescription(t("TOOL_CHECK_REPOSITORY_ACCESS_DESCRIPTION", "Check whether the current user may work in a repository with this server, and with which permission. Use it to find out why a call was refused before retrying.")),
+			mcp.WithToolAnnotation(mcp.ToolAnnotation{
+				Title:        t("TOOL_CHECK_REPOSITORY_ACCESS_USER_TITLE", "Check repository access"),
+				ReadOnlyHint: ToBoolPtr(true),
+			}),
+			mcp.WithString("owner",
+				mcp.Required(),
+				mcp.Description("Repository owner"),
+			),
+			mcp.WithString("repo",
+				mcp.Required(),
+				mcp.Description("Repository name"),
+			),
+			mcp.WithString("permission",
+				mcp.Description("Permission the intended call needs, e.g. write to create a branch or pull request"),
+				mcp.Enum("read", "triage", "write", "admin"),
+			),
+		),
+		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			owner, err := RequiredParam[string](request, "owner")
+			if err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+			repo, err := RequiredParam[string](request, "repo")
+			if err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+			requiredParam, err := OptionalParam[string](request, "permission")
+			if err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+
+			required := access.PermissionRead
+			if requiredParam != "" {
+				required, err = access.ParsePermission(requiredParam)
+				if err != nil {
+					return mcp.NewToolResultError(err.Error()), nil
+				}
+			}
+
+			validator, err := resolveValidator(ctx, getValidator)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("failed to validate repository access: %s", err)), nil
+			}
+			if validator == nil {
+				return mcp.NewToolResultError("repository access validation is not configured for this server"), nil
+			}
+
+			repoURL := fmt.Sprintf("%s/%s", owner, repo)
+			permission, err := validator.RepositoryPermission(repoURL)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("failed to validate repository access: %s", err)), nil
+			}
+			teams, err := validator.GrantingTeams(repoURL)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("failed to validate repository access: %s", err)), nil
+			}
+			rule, err := validator.DenyingRule(repoURL)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("failed to validate repository access: %s", err)), nil
+			}
+
+			result := RepositoryAccessResult{
+				Owner:      owner,
+				Repo:       repo,
+				Accessible: permission != access.PermissionNone,
+				Permission: permission.String(),
+				Allowed:    permission >= required,
+				TeamPath:   teams,
+			}
+			if requiredParam != "" {
+				result.Required = required.String()
+			}
+			switch {
+			case rule != "":
+				result.Reason = fmt.Sprintf("repository is denied by access rule %s", rule)
+			case !result.Accessible:
+				result.Reason = "repository is not in the set of repositories accessible to the current user"
+			case !result.Allowed:
+				result.Reason = fmt.Sprintf("%s permission required, user has %s", required, permission)
+			case len(teams) > 0:
+				result.Reason = fmt.Sprintf("user has %s permission, %s", permission, access.DescribeGrant(teams))
+			default:
+				result.Reason = fmt.Sprintf("user has %s permission", permission)
+			}
+
+			r, err := json.Marshal(result)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal response: %w", err)
+			}
+
+			return mcp.NewToolResultText(string(r)), nil
+		}
+}
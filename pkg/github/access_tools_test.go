@@ -0,0 +1,255 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/github/github-mcp-server/internal/toolsnaps"
+	"github.com/github/github-mcp-server/pkg/access"
+	"github.com/github/github-mcp-server/pkg/translations"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ListAccessibleRepositories(t *testing.T) {
+	// Verify tool definition once
+	tool, _ := ListAccessibleRepositories(nil, translations.NullTranslationHelper)
+	require.NoError(t, toolsnaps.Test(tool.Name, tool))
+
+	assert.Equal(t, "list_accessible_repositories", tool.Name)
+	assert.NotEmpty(t, tool.Description)
+	assert.Contains(t, tool.InputSchema.Properties, "org")
+	assert.Contains(t, tool.InputSchema.Properties, "prefix")
+	assert.Contains(t, tool.InputSchema.Properties, "page")
+	assert.Contains(t, tool.InputSchema.Properties, "perPage")
+	assert.Empty(t, tool.InputSchema.Required)
+
+	validator := newTestValidator(t, "octo-org/api", "octo-org/api-docs", "Octo-Org/Web", "other-org/api")
+
+	tests := []struct {
+		name          string
+		requestArgs   map[string]interface{}
+		expectedTotal int
+		expectedRepos []string
+	}{
+		{
+			name:          "all repositories sorted by owner and name",
+			requestArgs:   map[string]interface{}{},
+			expectedTotal: 4,
+			expectedRepos: []string{"octo-org/api", "octo-org/api-docs", "octo-org/web", "other-org/api"},
+		},
+		{
+			name:          "filter by org, case insensitive",
+			requestArgs:   map[string]interface{}{"org": "OCTO-ORG"},
+			expectedTotal: 3,
+			expectedRepos: []string{"octo-org/api", "octo-org/api-docs", "octo-org/web"},
+		},
+		{
+			name:          "filter by org and prefix",
+			requestArgs:   map[string]interface{}{"org": "octo-org", "prefix": "api"},
+			expectedTotal: 2,
+			expectedRepos: []string{"octo-org/api", "octo-org/api-docs"},
+		},
+		{
+			name:          "second page",
+			requestArgs:   map[string]interface{}{"page": float64(2), "perPage": float64(3)},
+			expectedTotal: 4,
+			expectedRepos: []string{"other-org/api"},
+		},
+		{
+			name:          "page past the end",
+			requestArgs:   map[string]interface{}{"page": float64(3), "perPage": float64(3)},
+			expectedTotal: 4,
+			expectedRepos: []string{},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, handler := ListAccessibleRepositories(StaticValidator(validator), translations.NullTranslationHelper)
+
+			result, err := handler(context.Background(), createMCPRequest(tc.requestArgs))
+			require.NoError(t, err)
+			require.False(t, result.IsError)
+
+			var returned AccessibleRepositoriesResult
+			require.NoError(t, json.Unmarshal([]byte(getTextResult(t, result).Text), &returned))
+			assert.Equal(t, tc.expectedTotal, returned.TotalCount)
+
+			repos := make([]string, 0, len(returned.Repositories))
+			for _, repo := range returned.Repositories {
+				repos = append(repos, repo.Owner+"/"+repo.Repo)
+				assert.Equal(t, "read", repo.Permission)
+			}
+			assert.Equal(t, tc.expectedRepos, repos)
+		})
+	}
+}
+
+func Test_CheckRepositoryAccess(t *testing.T) {
+	// Verify tool definition once
+	tool, _ := CheckRepositoryAccess(nil, translations.NullTranslationHelper)
+	require.NoError(t, toolsnaps.Test(tool.Name, tool))
+
+	assert.Equal(t, "check_repository_access", tool.Name)
+	assert.NotEmpty(t, tool.Description)
+	assert.Contains(t, tool.InputSchema.Properties, "owner")
+	assert.Contains(t, tool.InputSchema.Properties, "repo")
+	assert.Contains(t, tool.InputSchema.Properties, "permission")
+	assert.ElementsMatch(t, tool.InputSchema.Required, []string{"owner", "repo"})
+
+	team := access.Team{Org: "allowed-owner", Name: "backend"}
+	validator := newTeamValidator(t, "allowed-owner", "allowed-repo", team)
+
+	tests := []struct {
+		name           string
+		requestArgs    map[string]interface{}
+		expectError    bool
+		expectedResult RepositoryAccessResult
+		expectedErrMsg string
+	}{
+		{
+			name:        "accessible repository",
+			requestArgs: map[string]interface{}{"owner": "Allowed-Owner", "repo": "allowed-repo"},
+			expectedResult: RepositoryAccessResult{
+				Owner:      "Allowed-Owner",
+				Repo:       "allowed-repo",
+				Accessible: true,
+				Permission: "read",
+				Allowed:    true,
+				Reason:     "user has read permission, allowed via team backend (org allowed-owner)",
+				TeamPath:   []access.Team{team},
+			},
+		},
+		{
+			name:        "inaccessible repository is reported, not refused",
+			requestArgs: map[string]interface{}{"owner": "other-owner", "repo": "other-repo"},
+			expectedResult: RepositoryAccessResult{
+				Owner:      "other-owner",
+				Repo:       "other-repo",
+				Accessible: false,
+				Permission: "none",
+				Allowed:    false,
+				Reason:     "repository is not in the set of repositories accessible to the current user",
+			},
+		},
+		{
+			name:        "insufficient permission",
+			requestArgs: map[string]interface{}{"owner": "allowed-owner", "repo": "allowed-repo", "permission": "write"},
+			expectedResult: RepositoryAccessResult{
+				Owner:      "allowed-owner",
+				Repo:       "allowed-repo",
+				Accessible: true,
+				Permission: "read",
+				Required:   "write",
+				Allowed:    false,
+				Reason:     "write permission required, user has read",
+				TeamPath:   []access.Team{team},
+			},
+		},
+		{
+			name:           "invalid permission",
+			requestArgs:    map[string]interface{}{"owner": "allowed-owner", "repo": "allowed-repo", "permission": "owner"},
+			expectError:    true,
+			expectedErrMsg: "unknown permission",
+		},
+		{
+			name:           "missing repo",
+			requestArgs:    map[string]interface{}{"owner": "allowed-owner"},
+			expectError:    true,
+			expectedErrMsg: "missing required parameter: repo",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// Go through the access middleware like the server does
+			tool, handler := CheckRepositoryAccess(StaticValidator(validator), translations.NullTranslationHelper)
+			handler = RepositoryAccessMiddleware(StaticValidator(validator))(tool, handler)
+
+			result, err := handler(context.Background(), createMCPRequest(tc.requestArgs))
+			require.NoError(t, err)
+
+			if tc.expectError {
+				require.True(t, result.IsError)
+				assert.Contains(t, getErrorResult(t, result).Text, tc.expectedErrMsg)
+				return
+			}
+
+			require.False(t, result.IsError)
+			var returned RepositoryAccessResult
+			require.NoError(t, json.Unmarshal([]byte(getTextResult(t, result).Text), &returned))
+			assert.Equal(t, tc.expectedResult, returned)
+		})
+	}
+}
+
+func Test_ListAccessibleRepositories_Patterns(t *testing.T) {
+	overrideFile := filepath.Join(t.TempDir(), "overrides.yaml")
+	require.NoError(t, os.WriteFile(overrideFile, []byte("allow:\n  - other-org/*\n  - repository: octo-org/api-*\n    permission: write\n  - \"*/docs\"\n"), 0600))
+
+	validator := access.NewValidatorWithProvider("test@example.com", access.NewStaticProvider("octo-org/web"))
+	validator.SetOverrideFile(overrideFile)
+	require.NoError(t, validator.Initialize())
+
+	tests := []struct {
+		name             string
+		requestArgs      map[string]interface{}
+		expectedPatterns []AccessiblePattern
+	}{
+		{
+			name:        "every pattern, sorted",
+			requestArgs: map[string]interface{}{},
+			expectedPatterns: []AccessiblePattern{
+				{Owner: "*", Repo: "docs", Permission: "read"},
+				{Owner: "octo-org", Repo: "api-*", Permission: "write"},
+				{Owner: "other-org", Repo: "*", Permission: "read"},
+			},
+		},
+		{
+			name:        "patterns that may match the org and prefix",
+			requestArgs: map[string]interface{}{"org": "octo-org", "prefix": "api"},
+			expectedPatterns: []AccessiblePattern{
+				{Owner: "octo-org", Repo: "api-*", Permission: "write"},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, handler := ListAccessibleRepositories(StaticValidator(validator), translations.NullTranslationHelper)
+
+			result, err := handler(context.Background(), createMCPRequest(tc.requestArgs))
+			require.NoError(t, err)
+			require.False(t, result.IsError)
+
+			var returned AccessibleRepositoriesResult
+			require.NoError(t, json.Unmarshal([]byte(getTextResult(t, result).Text), &returned))
+			assert.Equal(t, tc.expectedPatterns, returned.Patterns)
+		})
+	}
+}
+
+func Test_CheckRepositoryAccess_DenyRule(t *testing.T) {
+	overrideFile := filepath.Join(t.TempDir(), "overrides.yaml")
+	require.NoError(t, os.WriteFile(overrideFile, []byte("deny: [\"*/*-secrets\"]\n"), 0600))
+
+	validator := access.NewValidatorWithProvider("test@example.com", access.NewStaticProvider("octo-org/prod-secrets"))
+	validator.SetOverrideFile(overrideFile)
+	require.NoError(t, validator.Initialize())
+
+	tool, handler := CheckRepositoryAccess(StaticValidator(validator), translations.NullTranslationHelper)
+	handler = RepositoryAccessMiddleware(StaticValidator(validator))(tool, handler)
+
+	result, err := handler(context.Background(), createMCPRequest(map[string]interface{}{"owner": "octo-org", "repo": "prod-secrets"}))
+	require.NoError(t, err)
+	require.False(t, result.IsError)
+
+	var returned RepositoryAccessResult
+	require.NoError(t, json.Unmarshal([]byte(getTextResult(t, result).Text), &returned))
+	assert.False(t, returned.Accessible)
+	assert.Equal(t, "repository is denied by access rule github.com/*/*-secrets", returned.Reason)
+}
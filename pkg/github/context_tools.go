@@ -6,6 +6,7 @@ import (
 
 	ghErrors "github.com/github/github-mcp-server/pkg/errors"
 	"github.com/github/github-mcp-server/pkg/translations"
+	"github.com/google/go-github/v74/github"
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 	"github.com/shurcooL/githubv4"
@@ -92,6 +93,70 @@ func GetMe(getClient GetClientFn, t translations.TranslationHelperFunc) (mcp.Too
 	return tool, handler
 }
 
+// RateLimitStatus is the quota of a GitHub rate limit resource
+type RateLimitStatus struct {
+	Resource  string    `json:"resource"`
+	Limit     int       `json:"limit"`
+	Used      int       `json:"used"`
+	Remaining int       `json:"remaining"`
+	ResetAt   time.Time `json:"reset_at"`
+}
+
+// GetRateLimit creates a tool to get the remaining GitHub API quota of the current credentials.
+func GetRateLimit(getClient GetClientFn, t translations.TranslationHelperFunc) (mcp.Tool, server.ToolHandlerFunc) {
+	tool := mcp.NewTool("get_rate_limit",
+		mcp.WithDescription(t("TOOL_GET_RATE_LIMIT_DESCRIPTION", "Get the remaining GitHub API quota of the current credentials for REST, search and GraphQL requests, and when it resets. Use this before large batches of calls or after rate limit errors. Checking the quota does not count against it.")),
+		mcp.WithToolAnnotation(mcp.ToolAnnotation{
+			Title:        t("TOOL_GET_RATE_LIMIT_TITLE", "Get API rate limits"),
+			ReadOnlyHint: ToBoolPtr(true),
+		}),
+	)
+
+	type args struct{}
+	handler := mcp.NewTypedToolHandler(func(ctx context.Context, _ mcp.CallToolRequest, _ args) (*mcp.CallToolResult, error) {
+		client, err := getClient(ctx)
+		if err != nil {
+			return mcp.NewToolResultErrorFromErr("failed to get GitHub client", err), nil
+		}
+
+		limits, res, err := client.RateLimit.Get(ctx)
+		if err != nil {
+			return ghErrors.NewGitHubAPIErrorResponse(ctx,
+				"failed to get rate limits",
+				res,
+				err,
+			), nil
+		}
+
+		resources := []struct {
+			name string
+			rate *github.Rate
+		}{
+			{"core", limits.GetCore()},
+			{"search", limits.GetSearch()},
+			{"code_search", limits.GetCodeSearch()},
+			{"graphql", limits.GetGraphQL()},
+		}
+		statuses := make([]RateLimitStatus, 0, len(resources))
+		for _, r := range resources {
+			if r.rate == nil {
+				continue
+			}
+			statuses = append(statuses, RateLimitStatus{
+				Resource:  r.name,
+				Limit:     r.rate.Limit,
+				Used:      r.rate.Used,
+				Remaining: r.rate.Remaining,
+				ResetAt:   r.rate.Reset.Time,
+			})
+		}
+
+		return MarshalledTextResult(statuses), nil
+	})
+
+	return tool, handler
+}
+
 type TeamInfo struct {
 	Name        string `json:"name"`
 	Slug        string `json:"slug"`
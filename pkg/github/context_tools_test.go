@@ -143,6 +143,93 @@ func Test_GetMe(t *testing.T) {
 	}
 }
 
+func Test_GetRateLimit(t *testing.T) {
+	t.Parallel()
+
+	tool, _ := GetRateLimit(nil, translations.NullTranslationHelper)
+	require.NoError(t, toolsnaps.Test(tool.Name, tool))
+
+	assert.Equal(t, "get_rate_limit", tool.Name)
+	assert.True(t, *tool.Annotations.ReadOnlyHint, "get_rate_limit tool should be read-only")
+
+	reset := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	mockLimits := map[string]any{
+		"resources": map[string]any{
+			"core":    map[string]any{"limit": 5000, "used": 4990, "remaining": 10, "reset": reset.Unix()},
+			"search":  map[string]any{"limit": 30, "used": 0, "remaining": 30, "reset": reset.Unix()},
+			"graphql": map[string]any{"limit": 5000, "used": 12, "remaining": 4988, "reset": reset.Unix()},
+		},
+	}
+
+	tests := []struct {
+		name               string
+		stubbedGetClientFn GetClientFn
+		expectToolError    bool
+		expectedStatuses   []RateLimitStatus
+		expectedToolErrMsg string
+	}{
+		{
+			name: "successful get rate limit",
+			stubbedGetClientFn: stubGetClientFromHTTPFn(
+				mock.NewMockedHTTPClient(
+					mock.WithRequestMatch(
+						mock.GetRateLimit,
+						mockLimits,
+					),
+				),
+			),
+			expectedStatuses: []RateLimitStatus{
+				{Resource: "core", Limit: 5000, Used: 4990, Remaining: 10, ResetAt: reset},
+				{Resource: "search", Limit: 30, Used: 0, Remaining: 30, ResetAt: reset},
+				{Resource: "graphql", Limit: 5000, Used: 12, Remaining: 4988, ResetAt: reset},
+			},
+		},
+		{
+			name:               "getting client fails",
+			stubbedGetClientFn: stubGetClientFnErr("expected test error"),
+			expectToolError:    true,
+			expectedToolErrMsg: "failed to get GitHub client: expected test error",
+		},
+		{
+			name: "get rate limit fails",
+			stubbedGetClientFn: stubGetClientFromHTTPFn(
+				mock.NewMockedHTTPClient(
+					mock.WithRequestMatchHandler(
+						mock.GetRateLimit,
+						badRequestHandler("expected test failure"),
+					),
+				),
+			),
+			expectToolError:    true,
+			expectedToolErrMsg: "expected test failure",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, handler := GetRateLimit(tc.stubbedGetClientFn, translations.NullTranslationHelper)
+
+			request := createMCPRequest(map[string]any{})
+			result, err := handler(context.Background(), request)
+			require.NoError(t, err)
+			textContent := getTextResult(t, result)
+
+			if tc.expectToolError {
+				assert.True(t, result.IsError, "expected tool call result to be an error")
+				assert.Contains(t, textContent.Text, tc.expectedToolErrMsg)
+				return
+			}
+
+			var returned []RateLimitStatus
+			require.NoError(t, json.Unmarshal([]byte(textContent.Text), &returned))
+			for i := range returned {
+				returned[i].ResetAt = returned[i].ResetAt.UTC()
+			}
+			assert.Equal(t, tc.expectedStatuses, returned)
+		})
+	}
+}
+
 func Test_GetTeams(t *testing.T) {
 	t.Parallel()
 
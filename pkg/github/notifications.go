@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/github/github-mcp-server/pkg/audit"
 	ghErrors "github.com/github/github-mcp-server/pkg/errors"
 	"github.com/github/github-mcp-server/pkg/translations"
 	"github.com/google/go-github/v74/github"
@@ -22,9 +23,11 @@ const (
 )
 
 // ListNotifications creates a tool to list notifications for the current user.
-func ListNotifications(getClient GetClientFn, t translations.TranslationHelperFunc) (tool mcp.Tool, handler server.ToolHandlerFunc) {
+// Notifications from repositories the validator does not allow are redacted, and counted in
+// the redacted_count of the result.
+func ListNotifications(getClient GetClientFn, t translations.TranslationHelperFunc, getValidator GetValidatorFn) (tool mcp.Tool, handler server.ToolHandlerFunc) {
 	return mcp.NewTool("list_notifications",
-			mcp.WithDescription(t("TOOL_LIST_NOTIFICATIONS_DESCRIPTION", "Lists all GitHub notifications for the authenticated user, including unread notifications, mentions, review requests, assignments, and updates on issues or pull requests. Use this tool whenever the user asks what to work on next, requests a summary of their GitHub activity, wants to see pending reviews, or needs to check for new updates or tasks. This tool is the primary way to discover actionable items, reminders, and outstanding work on GitHub. Always call this tool when asked what to work on next, what is pending, or what needs attention in GitHub.")),
+			mcp.WithDescription(t("TOOL_LIST_NOTIFICATIONS_DESCRIPTION", "Lists all GitHub notifications for the authenticated user, including unread notifications, mentions, review requests, assignments, and updates on issues or pull requests. Use this tool whenever the user asks what to work on next, requests a summary of their GitHub activity, wants to see pending reviews, or needs to check for new updates or tasks. This tool is the primary way to discover actionable items, reminders, and outstanding work on GitHub. Always call this tool when asked what to work on next, what is pending, or what needs attention in GitHub. While repository access validation is enabled, the result is an object with the notifications under \"items\" and, when notifications from repositories outside the accessible list were hidden, their number under \"redacted_count\".")),
 			mcp.WithToolAnnotation(mcp.ToolAnnotation{
 				Title:        t("TOOL_LIST_NOTIFICATIONS_USER_TITLE", "List notifications"),
 				ReadOnlyHint: ToBoolPtr(true),
@@ -134,8 +137,16 @@ func ListNotifications(getClient GetClientFn, t translations.TranslationHelperFu
 				return mcp.NewToolResultError(fmt.Sprintf("failed to get notifications: %s", string(body))), nil
 			}
 
+			validator, err := resolveValidator(ctx, getValidator)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("failed to validate repository access: %s", err)), nil
+			}
+			notifications, redacted := filterWithValidator(validator, notifications, func(n *github.Notification) (string, string) {
+				return n.GetRepository().GetOwner().GetLogin(), n.GetRepository().GetName()
+			})
+
 			// Marshal response to JSON
-			r, err := json.Marshal(notifications)
+			r, err := json.Marshal(accessibleList(validator, notifications, redacted))
 			if err != nil {
 				return nil, fmt.Errorf("failed to marshal response: %w", err)
 			}
@@ -147,7 +158,8 @@ func ListNotifications(getClient GetClientFn, t translations.TranslationHelperFu
 
 
 // GetNotificationDetails creates a tool to get details for a specific notification.
-func GetNotificationDetails(getClient GetClientFn, t translations.TranslationHelperFunc) (tool mcp.Tool, handler server.ToolHandlerFunc) {
+// Notifications from repositories the validator does not allow are refused.
+func GetNotificationDetails(getClient GetClientFn, t translations.TranslationHelperFunc, getValidator GetValidatorFn) (tool mcp.Tool, handler server.ToolHandlerFunc) {
 	return mcp.NewTool("get_notification_details",
 			mcp.WithDescription(t("TOOL_GET_NOTIFICATION_DETAILS_DESCRIPTION", "Get detailed information for a specific GitHub notification, always call this tool when the user asks for details about a specific notification, if you don't know the ID list notifications first.")),
 			mcp.WithToolAnnotation(mcp.ToolAnnotation{
@@ -188,6 +200,19 @@ func GetNotificationDetails(getClient GetClientFn, t translations.TranslationHel
 				return mcp.NewToolResultError(fmt.Sprintf("failed to get notification details: %s", string(body))), nil
 			}
 
+			// Refuse threads that list_notifications would redact, without naming their repository
+			accessible, _, err := filterAccessible(ctx, getValidator, []*github.Notification{thread}, func(n *github.Notification) (string, string) {
+				return n.GetRepository().GetOwner().GetLogin(), n.GetRepository().GetName()
+			})
+			if err != nil {
+				audit.SetDecision(ctx, audit.DecisionError, err.Error())
+				return mcp.NewToolResultError(fmt.Sprintf("failed to validate repository access: %s", err)), nil
+			}
+			if len(accessible) == 0 {
+				audit.SetDecision(ctx, audit.DecisionDenied, "repository not accessible")
+				return mcp.NewToolResultError(fmt.Sprintf("Access denied: notification %s belongs to a repository not accessible to the current user", notificationID)), nil
+			}
+
 			r, err := json.Marshal(thread)
 			if err != nil {
 				return nil, fmt.Errorf("failed to marshal response: %w", err)
@@ -17,7 +17,7 @@ import (
 func Test_ListNotifications(t *testing.T) {
 	// Verify tool definition and schema
 	mockClient := github.NewClient(nil)
-	tool, _ := ListNotifications(stubGetClientFn(mockClient), translations.NullTranslationHelper)
+	tool, _ := ListNotifications(stubGetClientFn(mockClient), translations.NullTranslationHelper, nil)
 	require.NoError(t, toolsnaps.Test(tool.Name, tool))
 
 	assert.Equal(t, "list_notifications", tool.Name)
@@ -122,7 +122,7 @@ func Test_ListNotifications(t *testing.T) {
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
 			client := github.NewClient(tc.mockedClient)
-			_, handler := ListNotifications(stubGetClientFn(client), translations.NullTranslationHelper)
+			_, handler := ListNotifications(stubGetClientFn(client), translations.NullTranslationHelper, nil)
 			request := createMCPRequest(tc.requestArgs)
 			result, err := handler(context.Background(), request)
 
@@ -156,7 +156,7 @@ func Test_ListNotifications(t *testing.T) {
 func Test_GetNotificationDetails(t *testing.T) {
 	// Verify tool definition and schema
 	mockClient := github.NewClient(nil)
-	tool, _ := GetNotificationDetails(stubGetClientFn(mockClient), translations.NullTranslationHelper)
+	tool, _ := GetNotificationDetails(stubGetClientFn(mockClient), translations.NullTranslationHelper, nil)
 	require.NoError(t, toolsnaps.Test(tool.Name, tool))
 
 	assert.Equal(t, "get_notification_details", tool.Name)
@@ -207,7 +207,7 @@ func Test_GetNotificationDetails(t *testing.T) {
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
 			client := github.NewClient(tc.mockedClient)
-			_, handler := GetNotificationDetails(stubGetClientFn(client), translations.NullTranslationHelper)
+			_, handler := GetNotificationDetails(stubGetClientFn(client), translations.NullTranslationHelper, nil)
 			request := createMCPRequest(tc.requestArgs)
 			result, err := handler(context.Background(), request)
 
@@ -273,7 +273,7 @@ func Test_ListPullRequests(t *testing.T) {
 
 func Test_SearchPullRequests(t *testing.T) {
 	mockClient := github.NewClient(nil)
-	tool, _ := SearchPullRequests(stubGetClientFn(mockClient), translations.NullTranslationHelper)
+	tool, _ := SearchPullRequests(stubGetClientFn(mockClient), translations.NullTranslationHelper, nil)
 	require.NoError(t, toolsnaps.Test(tool.Name, tool))
 
 	assert.Equal(t, "search_pull_requests", tool.Name)
@@ -538,7 +538,7 @@ func Test_SearchPullRequests(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			// Setup client with mock
 			client := github.NewClient(tc.mockedClient)
-			_, handler := SearchPullRequests(stubGetClientFn(client), translations.NullTranslationHelper)
+			_, handler := SearchPullRequests(stubGetClientFn(client), translations.NullTranslationHelper, nil)
 
 			// Create call request
 			request := createMCPRequest(tc.requestArgs)
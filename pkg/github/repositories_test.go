@@ -282,6 +282,56 @@ func Test_GetFileContents(t *testing.T) {
 	}
 }
 
+func Test_GetFileContents_CachedAtCommit(t *testing.T) {
+	const commitSHA = "0123456789abcdef0123456789abcdef01234567"
+	var contentsRequests, rawRequests int
+	client := github.NewClient(mock.NewMockedHTTPClient(
+		mock.WithRequestMatchHandler(
+			mock.GetReposGitRefByOwnerByRepoByRef,
+			mockResponse(t, http.StatusOK, &github.Reference{Ref: github.Ptr("refs/heads/main"), Object: &github.GitObject{SHA: github.Ptr(commitSHA)}}),
+		),
+		mock.WithRequestMatchHandler(
+			mock.GetReposContentsByOwnerByRepoByPath,
+			expectQueryParams(t, map[string]string{"ref": commitSHA}).andThen(
+				func(w http.ResponseWriter, r *http.Request) {
+					contentsRequests++
+					mockResponse(t, http.StatusOK, &github.RepositoryContent{Path: github.Ptr("README.md"), SHA: github.Ptr("abc123")})(w, r)
+				},
+			),
+		),
+		mock.WithRequestMatchHandler(
+			raw.GetRawReposContentsByOwnerByRepoBySHAByPath,
+			http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				rawRequests++
+				w.Header().Set("Content-Type", "text/markdown")
+				_, _ = w.Write([]byte("# Test Repository"))
+			}),
+		),
+	))
+	cache, err := raw.NewCache(raw.CacheConfig{MaxBytes: 1024 * 1024})
+	require.NoError(t, err)
+	rawClient := raw.NewClient(client, &url.URL{Scheme: "https", Host: "raw.example.com", Path: "/"}).WithCache(cache, "token")
+	_, handler := GetFileContents(stubGetClientFn(client), stubGetRawClientFn(rawClient), translations.NullTranslationHelper)
+
+	// The branch is resolved on every call, but the file at its commit is only read once
+	for i := 0; i < 2; i++ {
+		result, err := handler(context.Background(), createMCPRequest(map[string]interface{}{
+			"owner": "owner",
+			"repo":  "repo",
+			"path":  "README.md",
+			"ref":   "main",
+		}))
+		require.NoError(t, err)
+		assert.Equal(t, mcp.TextResourceContents{
+			URI:      "repo://owner/repo/main/contents/README.md",
+			Text:     "# Test Repository",
+			MIMEType: "text/markdown",
+		}, getTextResourceResult(t, result))
+	}
+	assert.Equal(t, 1, contentsRequests)
+	assert.Equal(t, 1, rawRequests)
+}
+
 
 func Test_CreateBranch(t *testing.T) {
 	// Verify tool definition once
@@ -0,0 +1,219 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/github/github-mcp-server/pkg/access"
+	"github.com/github/github-mcp-server/pkg/audit"
+	"github.com/github/github-mcp-server/pkg/toolsets"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// toolPermissions declares the permission level of the tools that need something other than
+// what their annotations imply: read for read-only tools and write for every other tool. Some
+// writes, such as opening an issue, are open to anyone who can read the repository, and some
+// need triage.
+var toolPermissions = map[string]access.Permission{
+	// Issues
+	"create_issue":      access.PermissionRead,
+	"add_issue_comment": access.PermissionRead,
+	"add_sub_issue":     access.PermissionTriage,
+
+	// Pull requests
+	"request_copilot_review":        access.PermissionTriage,
+	"add_comment_to_pending_review": access.PermissionRead,
+}
+
+// uncheckedTools take an owner and repo but report on access themselves, so they must
+// answer for repositories the user cannot access instead of being refused
+var uncheckedTools = map[string]struct{}{
+	"check_repository_access": {},
+}
+
+// requiredPermission returns the permission level a tool needs on its target repository. A
+// tool that is not declared in toolPermissions needs write unless it is annotated as read-only,
+// so a new write tool cannot run with read access only.
+func requiredPermission(tool mcp.Tool) access.Permission {
+	if permission, ok := toolPermissions[tool.Name]; ok {
+		return permission
+	}
+	if readOnly := tool.Annotations.ReadOnlyHint; readOnly != nil && *readOnly {
+		return access.PermissionRead
+	}
+	return access.PermissionWrite
+}
+
+// RepositoryAccessMiddleware returns a tool middleware that refuses calls targeting a repository
+// outside the set the validator allows, or on which the user lacks the permission level the
+// tool needs, see requiredPermission. It applies to every tool whose schema has both an
+// "owner" and a "repo" parameter, and only checks calls where both are set, so optional
+// repository filters (e.g. on notifications) are validated when they are used.
+// The validator is resolved on every call; a nil getValidator, or a nil validator, disables the check.
+func RepositoryAccessMiddleware(getValidator GetValidatorFn) toolsets.ToolMiddleware {
+	return func(tool mcp.Tool, next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		if _, unchecked := uncheckedTools[tool.Name]; getValidator == nil || unchecked || !hasRepositoryParams(tool) {
+			return next
+		}
+		required := requiredPermission(tool)
+
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			owner, err := OptionalParam[string](request, "owner")
+			if err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+			repo, err := OptionalParam[string](request, "repo")
+			if err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+
+			// Leave reporting of missing required parameters to the tool itself
+			if owner == "" || repo == "" {
+				return next(ctx, request)
+			}
+
+			validator, err := getValidator(ctx)
+			if err != nil {
+				audit.SetDecision(ctx, audit.DecisionError, err.Error())
+				return mcp.NewToolResultError(fmt.Sprintf("Failed to validate repository access: %s", err.Error())), nil
+			}
+			if validator == nil {
+				return next(ctx, request)
+			}
+
+			if result := checkRepositoryAccess(ctx, validator, owner, repo, required); result != nil {
+				return result, nil
+			}
+			return next(ctx, request)
+		}
+	}
+}
+
+// checkRepositoryAccess returns an error result if the user lacks the required permission
+// on owner/repo, or nil if they have it. The decision is recorded for the audit log.
+func checkRepositoryAccess(ctx context.Context, validator *access.Validator, owner, repo string, required access.Permission) *mcp.CallToolResult {
+	repoURL := fmt.Sprintf("%s/%s", owner, repo)
+	permission, err := validator.RepositoryPermission(repoURL)
+	if err != nil {
+		audit.SetDecision(ctx, audit.DecisionError, err.Error())
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to validate repository access: %s", err.Error()))
+	}
+	if permission == access.PermissionNone {
+		if rule, _ := validator.DenyingRule(repoURL); rule != "" {
+			audit.SetDecision(ctx, audit.DecisionDenied, fmt.Sprintf("denied by access rule %s", rule))
+			return mcp.NewToolResultError(fmt.Sprintf("Access denied: Repository %s/%s is denied by access rule %s", owner, repo, rule))
+		}
+		audit.SetDecision(ctx, audit.DecisionDenied, "repository not accessible")
+		return mcp.NewToolResultError(fmt.Sprintf("Access denied: Repository %s/%s is not accessible to the current user", owner, repo))
+	}
+	if permission < required {
+		audit.SetDecision(ctx, audit.DecisionDenied, fmt.Sprintf("%s permission required, user has %s", required, permission))
+		return mcp.NewToolResultError(fmt.Sprintf("Access denied: %s permission on repository %s/%s is required, but the current user has %s", required, owner, repo, permission))
+	}
+	teams, _ := validator.GrantingTeams(repoURL)
+	audit.SetDecision(ctx, audit.DecisionAllowed, access.DescribeGrant(teams))
+	return nil
+}
+
+// validateRepositoryAccess returns an error if owner/repo is not accessible, or nil if it is.
+// It is the error counterpart of checkRepositoryAccess for handlers that do not return tool results.
+func validateRepositoryAccess(validator *access.Validator, owner, repo string) error {
+	accessible, err := validator.IsRepositoryAccessible(fmt.Sprintf("%s/%s", owner, repo))
+	if err != nil {
+		return fmt.Errorf("failed to validate repository access: %w", err)
+	}
+	if !accessible {
+		return fmt.Errorf("access denied: repository %s/%s is not accessible to the current user", owner, repo)
+	}
+	return nil
+}
+
+// hasRepositoryParams reports whether the tool takes both owner and repo parameters
+func hasRepositoryParams(tool mcp.Tool) bool {
+	_, hasOwner := tool.InputSchema.Properties["owner"]
+	_, hasRepo := tool.InputSchema.Properties["repo"]
+	return hasOwner && hasRepo
+}
+
+// filterAccessible drops the items that belong to a repository the validator does not allow.
+// repoOf returns the owner and name of the repository an item belongs to; items whose
+// repository cannot be determined are dropped as well. It returns the kept items and the
+// number of items that were redacted. A nil validator keeps every item.
+func filterAccessible[T any](ctx context.Context, getValidator GetValidatorFn, items []T, repoOf func(T) (string, string)) ([]T, int, error) {
+	validator, err := resolveValidator(ctx, getValidator)
+	if err != nil {
+		return nil, 0, err
+	}
+	kept, redacted := filterWithValidator(validator, items, repoOf)
+	return kept, redacted, nil
+}
+
+// filterWithValidator is filterAccessible for a validator that was already resolved
+func filterWithValidator[T any](validator *access.Validator, items []T, repoOf func(T) (string, string)) ([]T, int) {
+	if validator == nil {
+		return items, 0
+	}
+
+	kept := make([]T, 0, len(items))
+	for _, item := range items {
+		owner, repo := repoOf(item)
+		if owner == "" || repo == "" {
+			continue
+		}
+		accessible, err := validator.IsRepositoryAccessible(fmt.Sprintf("%s/%s", owner, repo))
+		if err != nil || !accessible {
+			continue
+		}
+		kept = append(kept, item)
+	}
+	return kept, len(items) - len(kept)
+}
+
+// redactedList is a list result annotated with the number of redacted items, the list
+// counterpart of the redacted search results
+type redactedList[T any] struct {
+	Items         []T `json:"items"`
+	RedactedCount int `json:"redacted_count,omitempty"`
+}
+
+// accessibleList returns what a list tool encodes for items filtered by validator: the items
+// with the number that were redacted when the validator applies, or the plain list without one.
+func accessibleList[T any](validator *access.Validator, items []T, redacted int) any {
+	if validator == nil {
+		return items
+	}
+	return redactedList[T]{Items: items, RedactedCount: redacted}
+}
+
+// repositoryFromURL extracts the owner and repository name from a GitHub API URL
+// (e.g. https://api.github.com/repos/owner/repo/issues/1) or web URL
+// (e.g. https://github.com/owner/repo/security/advisories/GHSA-xxxx).
+func repositoryFromURL(rawURL string) (string, string) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", ""
+	}
+
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+
+	// API URLs are on an api. host, or under /api/v3 on GitHub Enterprise Server. A web URL
+	// of a repository owned by "api" is not one of them.
+	apiURL := strings.HasPrefix(u.Host, "api.")
+	if len(segments) > 2 && segments[0] == "api" && segments[1] == "v3" {
+		apiURL, segments = true, segments[2:]
+	}
+	if apiURL {
+		if len(segments) < 3 || segments[0] != "repos" {
+			return "", ""
+		}
+		return segments[1], segments[2]
+	}
+
+	if len(segments) < 2 {
+		return "", ""
+	}
+	return segments[0], segments[1]
+}
@@ -0,0 +1,609 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"path"
+	"testing"
+
+	"github.com/github/github-mcp-server/pkg/access"
+	"github.com/github/github-mcp-server/pkg/audit"
+	"github.com/github/github-mcp-server/pkg/raw"
+	"github.com/github/github-mcp-server/pkg/translations"
+	"github.com/google/go-github/v74/github"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/migueleliasweb/go-github-mock/src/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestValidator returns an initialized validator granting access to the given repositories
+func newTestValidator(t *testing.T, repos ...string) *access.Validator {
+	t.Helper()
+	validator := access.NewValidatorWithProvider("test@example.com", access.NewStaticProvider(repos...))
+	require.NoError(t, validator.Initialize())
+	return validator
+}
+
+// grantedRepositoriesProvider serves repositories along with the teams granting them
+type grantedRepositoriesProvider []access.Repository
+
+func (p grantedRepositoriesProvider) AccessibleRepositories(_ context.Context, _ string) ([]access.Repository, error) {
+	return p, nil
+}
+
+// newTeamValidator returns a validator granting read access to owner/repo through a team
+func newTeamValidator(t *testing.T, owner, repo string, team access.Team) *access.Validator {
+	t.Helper()
+	validator := access.NewValidatorWithProvider("test@example.com", grantedRepositoriesProvider{
+		{Org: owner, Repo: repo, Teams: []access.Team{team}},
+	})
+	require.NoError(t, validator.Initialize())
+	return validator
+}
+
+func Test_RepositoryAccessMiddleware(t *testing.T) {
+	validator := newTestValidator(t, "allowed-owner/allowed-repo")
+
+	mockBranches := []*github.Branch{
+		{
+			Name:   github.Ptr("main"),
+			Commit: &github.RepositoryCommit{SHA: github.Ptr("abc123")},
+		},
+	}
+
+	tests := []struct {
+		name           string
+		validator      *access.Validator
+		requestArgs    map[string]interface{}
+		expectCalled   bool
+		expectError    bool
+		expectedErrMsg string
+	}{
+		{
+			name:      "accessible repository is passed through",
+			validator: validator,
+			requestArgs: map[string]interface{}{
+				"owner": "allowed-owner",
+				"repo":  "allowed-repo",
+			},
+			expectCalled: true,
+		},
+		{
+			name:      "repository access is case insensitive",
+			validator: validator,
+			requestArgs: map[string]interface{}{
+				"owner": "Allowed-Owner",
+				"repo":  "ALLOWED-REPO",
+			},
+			expectCalled: true,
+		},
+		{
+			name:      "inaccessible repository is denied",
+			validator: validator,
+			requestArgs: map[string]interface{}{
+				"owner": "other-owner",
+				"repo":  "other-repo",
+			},
+			expectError:    true,
+			expectedErrMsg: "Access denied: Repository other-owner/other-repo is not accessible to the current user",
+		},
+		{
+			name:      "uninitialized validator fails closed",
+			validator: access.NewValidatorWithProvider("test@example.com", access.NewStaticProvider()),
+			requestArgs: map[string]interface{}{
+				"owner": "allowed-owner",
+				"repo":  "allowed-repo",
+			},
+			expectError:    true,
+			expectedErrMsg: "Failed to validate repository access",
+		},
+		{
+			name:      "missing parameters are left to the tool",
+			validator: validator,
+			requestArgs: map[string]interface{}{
+				"owner": "allowed-owner",
+			},
+			expectError:    true,
+			expectedErrMsg: "missing required parameter: repo",
+		},
+		{
+			name:      "nil validator disables the check",
+			validator: nil,
+			requestArgs: map[string]interface{}{
+				"owner": "other-owner",
+				"repo":  "other-repo",
+			},
+			expectCalled: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			called := false
+			client := github.NewClient(mock.NewMockedHTTPClient(
+				mock.WithRequestMatchHandler(
+					mock.GetReposBranchesByOwnerByRepo,
+					http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+						called = true
+						mockResponse(t, http.StatusOK, mockBranches)(w, r)
+					}),
+				),
+			))
+			tool, handler := ListBranches(stubGetClientFn(client), translations.NullTranslationHelper)
+			handler = RepositoryAccessMiddleware(StaticValidator(tc.validator))(tool, handler)
+
+			result, err := handler(context.Background(), createMCPRequest(tc.requestArgs))
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectCalled, called)
+
+			if tc.expectError {
+				require.True(t, result.IsError)
+				errorContent := getErrorResult(t, result)
+				assert.Contains(t, errorContent.Text, tc.expectedErrMsg)
+				return
+			}
+			require.False(t, result.IsError)
+		})
+	}
+}
+
+func Test_RepositoryAccessMiddleware_RequiredPermission(t *testing.T) {
+	readValidator := newTestValidator(t, "owner/repo")
+
+	writeValidator := access.NewValidatorWithProvider("test@example.com", access.NewStaticProvider("owner/repo"))
+	writeValidator.SetDefaultPermission(access.PermissionWrite)
+	require.NoError(t, writeValidator.Initialize())
+
+	tests := []struct {
+		name           string
+		validator      *access.Validator
+		expectCalled   bool
+		expectedErrMsg string
+	}{
+		{
+			name:           "read permission cannot create branches",
+			validator:      readValidator,
+			expectedErrMsg: "Access denied: write permission on repository owner/repo is required, but the current user has read",
+		},
+		{
+			name:         "write permission can create branches",
+			validator:    writeValidator,
+			expectCalled: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			called := false
+			client := github.NewClient(mock.NewMockedHTTPClient(
+				mock.WithRequestMatchHandler(
+					mock.GetReposGitRefByOwnerByRepoByRef,
+					http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+						called = true
+						mockResponse(t, http.StatusOK, &github.Reference{Object: &github.GitObject{SHA: github.Ptr("abc123")}})(w, r)
+					}),
+				),
+				mock.WithRequestMatch(
+					mock.PostReposGitRefsByOwnerByRepo,
+					&github.Reference{Ref: github.Ptr("refs/heads/new-feature")},
+				),
+			))
+			tool, handler := CreateBranch(stubGetClientFn(client), translations.NullTranslationHelper)
+			handler = RepositoryAccessMiddleware(StaticValidator(tc.validator))(tool, handler)
+
+			result, err := handler(context.Background(), createMCPRequest(map[string]interface{}{
+				"owner":       "owner",
+				"repo":        "repo",
+				"branch":      "new-feature",
+				"from_branch": "main",
+			}))
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectCalled, called)
+
+			if tc.expectedErrMsg != "" {
+				require.True(t, result.IsError)
+				assert.Contains(t, getErrorResult(t, result).Text, tc.expectedErrMsg)
+				return
+			}
+			require.False(t, result.IsError)
+		})
+	}
+}
+
+func Test_DefaultToolsetGroup_WriteToolsNeedWritePermission(t *testing.T) {
+	getRawClient := func(_ context.Context) (*raw.Client, error) { return nil, nil }
+	tsg := DefaultToolsetGroup(false, stubGetClientFn(github.NewClient(nil)), stubGetGQLClientFn(nil), getRawClient, translations.NullTranslationHelper, 5000, nil)
+
+	// Every write tool needs at least write, unless it is declared to need less
+	writeTools := map[string]bool{}
+	for _, toolset := range tsg.Toolsets {
+		for _, tool := range toolset.GetAvailableTools() {
+			if tool.Tool.Annotations.ReadOnlyHint != nil && *tool.Tool.Annotations.ReadOnlyHint {
+				continue
+			}
+			writeTools[tool.Tool.Name] = true
+			if _, declared := toolPermissions[tool.Tool.Name]; declared {
+				continue
+			}
+			assert.GreaterOrEqual(t, requiredPermission(tool.Tool), access.PermissionWrite, tool.Tool.Name)
+		}
+	}
+
+	// The declared exceptions are existing write tools
+	for name := range toolPermissions {
+		assert.True(t, writeTools[name], name)
+	}
+}
+
+func Test_RequiredPermission(t *testing.T) {
+	assert.Equal(t, access.PermissionRead, requiredPermission(mcp.NewTool("list_branches", mcp.WithToolAnnotation(mcp.ToolAnnotation{ReadOnlyHint: ToBoolPtr(true)}))))
+	assert.Equal(t, access.PermissionWrite, requiredPermission(mcp.NewTool("new_write_tool", mcp.WithToolAnnotation(mcp.ToolAnnotation{ReadOnlyHint: ToBoolPtr(false)}))))
+	assert.Equal(t, access.PermissionWrite, requiredPermission(mcp.NewTool("unannotated_tool")), "tools without a read-only hint are writes")
+	assert.Equal(t, access.PermissionTriage, requiredPermission(mcp.NewTool("add_sub_issue")))
+}
+
+func Test_RepositoryAccessMiddleware_RecordsAuditDecision(t *testing.T) {
+	validator := newTeamValidator(t, "owner", "allowed", access.Team{Org: "owner", Name: "backend"})
+	client := github.NewClient(mock.NewMockedHTTPClient(
+		mock.WithRequestMatch(
+			mock.GetReposBranchesByOwnerByRepo,
+			[]*github.Branch{{Name: github.Ptr("main")}},
+		),
+	))
+
+	var buf bytes.Buffer
+	tool, handler := ListBranches(stubGetClientFn(client), translations.NullTranslationHelper)
+	handler = RepositoryAccessMiddleware(StaticValidator(validator))(tool, handler)
+	handler = audit.Middleware(audit.NewLogger(&buf), "test@example.com")(tool, handler)
+
+	for _, repo := range []string{"allowed", "secret"} {
+		_, err := handler(context.Background(), createMCPRequest(map[string]interface{}{
+			"owner": "owner",
+			"repo":  repo,
+		}))
+		require.NoError(t, err)
+	}
+
+	var records []audit.Record
+	decoder := json.NewDecoder(&buf)
+	for decoder.More() {
+		var record audit.Record
+		require.NoError(t, decoder.Decode(&record))
+		records = append(records, record)
+	}
+	require.Len(t, records, 2)
+	assert.Equal(t, audit.DecisionAllowed, records[0].Decision)
+	assert.Equal(t, "allowed", records[0].Repo)
+	assert.Equal(t, "allowed via team backend (org owner)", records[0].Reason)
+	assert.Equal(t, audit.DecisionDenied, records[1].Decision)
+	assert.Equal(t, "secret", records[1].Repo)
+	assert.True(t, records[1].ToolError)
+}
+
+func Test_RepositoryAccessMiddleware_SkipsToolsWithoutRepository(t *testing.T) {
+	validator := newTestValidator(t)
+	tool := mcp.NewTool("search_users", mcp.WithString("query"))
+
+	called := false
+	next := func(_ context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		called = true
+		return mcp.NewToolResultText("ok"), nil
+	}
+
+	handler := RepositoryAccessMiddleware(StaticValidator(validator))(tool, next)
+	result, err := handler(context.Background(), createMCPRequest(map[string]interface{}{
+		"query": "octocat",
+	}))
+	require.NoError(t, err)
+	require.False(t, result.IsError)
+	assert.True(t, called)
+}
+
+func Test_RepositoryAccessMiddleware_ResolvesValidatorPerCall(t *testing.T) {
+	validators := map[string]*access.Validator{
+		"alice": newTestValidator(t, "owner/alice-repo"),
+		"bob":   newTestValidator(t, "owner/bob-repo"),
+	}
+	type userKey struct{}
+	getValidator := func(ctx context.Context) (*access.Validator, error) {
+		user, _ := ctx.Value(userKey{}).(string)
+		validator, ok := validators[user]
+		if !ok {
+			return nil, errors.New("unknown user")
+		}
+		return validator, nil
+	}
+
+	tool, handler := GetFileContents(stubGetClientFn(github.NewClient(nil)), stubGetRawClientFn(nil), translations.NullTranslationHelper)
+	handler = RepositoryAccessMiddleware(getValidator)(tool, handler)
+
+	call := func(user, repo string) *mcp.CallToolResult {
+		ctx := context.WithValue(context.Background(), userKey{}, user)
+		result, err := handler(ctx, createMCPRequest(map[string]interface{}{
+			"owner": "owner",
+			"repo":  repo,
+			"path":  "README.md",
+		}))
+		require.NoError(t, err)
+		return result
+	}
+
+	result := call("alice", "bob-repo")
+	require.True(t, result.IsError)
+	assert.Contains(t, getTextResult(t, result).Text, "Access denied: Repository owner/bob-repo is not accessible")
+
+	result = call("bob", "alice-repo")
+	require.True(t, result.IsError)
+	assert.Contains(t, getTextResult(t, result).Text, "Access denied: Repository owner/alice-repo is not accessible")
+
+	// Failing to resolve the validator refuses the call
+	result = call("mallory", "alice-repo")
+	require.True(t, result.IsError)
+	assert.Equal(t, "Failed to validate repository access: unknown user", getTextResult(t, result).Text)
+}
+
+func Test_DefaultToolsetGroup_EnforcesRepositoryAccess(t *testing.T) {
+	validator := newTestValidator(t, "allowed-owner/allowed-repo")
+	client := github.NewClient(nil)
+	getRawClient := func(_ context.Context) (*raw.Client, error) { return nil, nil }
+
+	tsg := DefaultToolsetGroup(false, stubGetClientFn(client), stubGetGQLClientFn(nil), getRawClient, translations.NullTranslationHelper, 5000, StaticValidator(validator))
+	require.NoError(t, tsg.EnableToolsets([]string{"all"}))
+
+	// Every active owner/repo tool must refuse an inaccessible repository before calling GitHub
+	checked := 0
+	for _, toolset := range tsg.Toolsets {
+		for _, tool := range toolset.GetActiveTools() {
+			if _, unchecked := uncheckedTools[tool.Tool.Name]; unchecked || !hasRepositoryParams(tool.Tool) {
+				continue
+			}
+			checked++
+
+			result, err := tool.Handler(context.Background(), createMCPRequest(map[string]interface{}{
+				"owner": "other-owner",
+				"repo":  "other-repo",
+			}))
+			require.NoError(t, err, tool.Tool.Name)
+			require.True(t, result.IsError, tool.Tool.Name)
+			errorContent := getErrorResult(t, result)
+			assert.Contains(t, errorContent.Text, "Access denied", tool.Tool.Name)
+		}
+	}
+	assert.Greater(t, checked, 20)
+}
+
+func Test_RepositoryFromURL(t *testing.T) {
+	tests := []struct {
+		name          string
+		url           string
+		expectedOwner string
+		expectedRepo  string
+	}{
+		{
+			name:          "dotcom API URL",
+			url:           "https://api.github.com/repos/owner/repo",
+			expectedOwner: "owner",
+			expectedRepo:  "repo",
+		},
+		{
+			name:          "dotcom API URL with subresource",
+			url:           "https://api.github.com/repos/owner/repo/security-advisories/GHSA-xxxx-xxxx-xxxx",
+			expectedOwner: "owner",
+			expectedRepo:  "repo",
+		},
+		{
+			name:          "GHES API URL",
+			url:           "https://ghe.example.com/api/v3/repos/owner/repo/issues/1",
+			expectedOwner: "owner",
+			expectedRepo:  "repo",
+		},
+		{
+			name:          "web URL",
+			url:           "https://github.com/owner/repo/security/advisories/GHSA-xxxx-xxxx-xxxx",
+			expectedOwner: "owner",
+			expectedRepo:  "repo",
+		},
+		{
+			name:          "web URL of a repository owned by api",
+			url:           "https://github.com/api/service/issues/1",
+			expectedOwner: "api",
+			expectedRepo:  "service",
+		},
+		{
+			name: "API URL without repository",
+			url:  "https://api.github.com/orgs/owner",
+		},
+		{
+			name: "empty URL",
+			url:  "",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			owner, repo := repositoryFromURL(tc.url)
+			assert.Equal(t, tc.expectedOwner, owner)
+			assert.Equal(t, tc.expectedRepo, repo)
+		})
+	}
+}
+
+func Test_SearchResultsAreRedacted(t *testing.T) {
+	validator := newTestValidator(t, "owner/allowed")
+
+	t.Run("search_repositories", func(t *testing.T) {
+		client := github.NewClient(mock.NewMockedHTTPClient(
+			mock.WithRequestMatch(
+				mock.GetSearchRepositories,
+				&github.RepositoriesSearchResult{
+					Total: github.Ptr(10),
+					Repositories: []*github.Repository{
+						{Name: github.Ptr("allowed"), Owner: &github.User{Login: github.Ptr("owner")}},
+						{Name: github.Ptr("secret"), Owner: &github.User{Login: github.Ptr("owner")}},
+					},
+				},
+			),
+		))
+		_, handler := SearchRepositories(stubGetClientFn(client), translations.NullTranslationHelper, StaticValidator(validator))
+
+		result, err := handler(context.Background(), createMCPRequest(map[string]interface{}{"query": "owner"}))
+		require.NoError(t, err)
+		require.False(t, result.IsError)
+
+		var returned struct {
+			Total         int                  `json:"total_count"`
+			Repositories  []*github.Repository `json:"items"`
+			RedactedCount int                  `json:"redacted_count"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(getTextResult(t, result).Text), &returned))
+		require.Len(t, returned.Repositories, 1)
+		assert.Equal(t, "allowed", returned.Repositories[0].GetName())
+		assert.Equal(t, 10, returned.Total, "the total is GitHub's")
+		assert.Equal(t, 1, returned.RedactedCount)
+	})
+
+	t.Run("search_code", func(t *testing.T) {
+		client := github.NewClient(mock.NewMockedHTTPClient(
+			mock.WithRequestMatch(
+				mock.GetSearchCode,
+				&github.CodeSearchResult{
+					Total: github.Ptr(2),
+					CodeResults: []*github.CodeResult{
+						{Name: github.Ptr("a.go"), Repository: &github.Repository{Name: github.Ptr("allowed"), Owner: &github.User{Login: github.Ptr("owner")}}},
+						{Name: github.Ptr("b.go"), Repository: &github.Repository{Name: github.Ptr("secret"), Owner: &github.User{Login: github.Ptr("owner")}}},
+						{Name: github.Ptr("c.go")},
+					},
+				},
+			),
+		))
+		_, handler := SearchCode(stubGetClientFn(client), translations.NullTranslationHelper, StaticValidator(validator))
+
+		result, err := handler(context.Background(), createMCPRequest(map[string]interface{}{"query": "func"}))
+		require.NoError(t, err)
+		require.False(t, result.IsError)
+
+		var returned struct {
+			CodeResults   []*github.CodeResult `json:"items"`
+			RedactedCount int                  `json:"redacted_count"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(getTextResult(t, result).Text), &returned))
+		require.Len(t, returned.CodeResults, 1)
+		assert.Equal(t, "a.go", returned.CodeResults[0].GetName())
+		assert.Equal(t, 2, returned.RedactedCount)
+	})
+
+	t.Run("search_issues", func(t *testing.T) {
+		client := github.NewClient(mock.NewMockedHTTPClient(
+			mock.WithRequestMatch(
+				mock.GetSearchIssues,
+				&github.IssuesSearchResult{
+					Total: github.Ptr(2),
+					Issues: []*github.Issue{
+						{Number: github.Ptr(1), RepositoryURL: github.Ptr("https://api.github.com/repos/owner/allowed")},
+						{Number: github.Ptr(2), RepositoryURL: github.Ptr("https://api.github.com/repos/owner/secret")},
+					},
+				},
+			),
+		))
+		_, handler := SearchIssues(stubGetClientFn(client), translations.NullTranslationHelper, StaticValidator(validator))
+
+		result, err := handler(context.Background(), createMCPRequest(map[string]interface{}{"query": "bug"}))
+		require.NoError(t, err)
+		require.False(t, result.IsError)
+
+		var returned struct {
+			Total         int             `json:"total_count"`
+			Issues        []*github.Issue `json:"items"`
+			RedactedCount int             `json:"redacted_count"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(getTextResult(t, result).Text), &returned))
+		require.Len(t, returned.Issues, 1)
+		assert.Equal(t, 1, returned.Issues[0].GetNumber())
+		assert.Equal(t, 2, returned.Total, "the total is GitHub's")
+		assert.Equal(t, 1, returned.RedactedCount)
+	})
+}
+
+func Test_ListResultsAreRedacted(t *testing.T) {
+	validator := newTestValidator(t, "owner/allowed")
+
+	t.Run("list_notifications", func(t *testing.T) {
+		client := github.NewClient(mock.NewMockedHTTPClient(
+			mock.WithRequestMatch(
+				mock.GetNotifications,
+				[]*github.Notification{
+					{ID: github.Ptr("1"), Repository: &github.Repository{Name: github.Ptr("allowed"), Owner: &github.User{Login: github.Ptr("owner")}}},
+					{ID: github.Ptr("2"), Repository: &github.Repository{Name: github.Ptr("secret"), Owner: &github.User{Login: github.Ptr("owner")}}},
+				},
+			),
+		))
+		_, handler := ListNotifications(stubGetClientFn(client), translations.NullTranslationHelper, StaticValidator(validator))
+
+		result, err := handler(context.Background(), createMCPRequest(map[string]interface{}{}))
+		require.NoError(t, err)
+		require.False(t, result.IsError)
+
+		var returned redactedList[*github.Notification]
+		require.NoError(t, json.Unmarshal([]byte(getTextResult(t, result).Text), &returned))
+		require.Len(t, returned.Items, 1)
+		assert.Equal(t, "1", returned.Items[0].GetID())
+		assert.Equal(t, 1, returned.RedactedCount)
+	})
+
+	t.Run("list_org_repository_security_advisories", func(t *testing.T) {
+		client := github.NewClient(mock.NewMockedHTTPClient(
+			mock.WithRequestMatch(
+				mock.GetOrgsSecurityAdvisoriesByOrg,
+				[]*github.SecurityAdvisory{
+					{GHSAID: github.Ptr("GHSA-1"), URL: github.Ptr("https://api.github.com/repos/owner/allowed/security-advisories/GHSA-1")},
+					{GHSAID: github.Ptr("GHSA-2"), URL: github.Ptr("https://api.github.com/repos/owner/secret/security-advisories/GHSA-2")},
+				},
+			),
+		))
+		_, handler := ListOrgRepositorySecurityAdvisories(stubGetClientFn(client), translations.NullTranslationHelper, StaticValidator(validator))
+
+		result, err := handler(context.Background(), createMCPRequest(map[string]interface{}{"org": "owner"}))
+		require.NoError(t, err)
+		require.False(t, result.IsError)
+
+		var returned redactedList[*github.SecurityAdvisory]
+		require.NoError(t, json.Unmarshal([]byte(getTextResult(t, result).Text), &returned))
+		require.Len(t, returned.Items, 1)
+		assert.Equal(t, "GHSA-1", returned.Items[0].GetGHSAID())
+		assert.Equal(t, 1, returned.RedactedCount)
+	})
+}
+
+func Test_GetNotificationDetails_RefusesInaccessibleRepository(t *testing.T) {
+	validator := newTestValidator(t, "owner/allowed")
+	client := github.NewClient(mock.NewMockedHTTPClient(
+		mock.WithRequestMatchHandler(
+			mock.GetNotificationsThreadsByThreadId,
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				repo := map[string]string{"/notifications/threads/1": "allowed", "/notifications/threads/2": "secret"}[r.URL.Path]
+				_ = json.NewEncoder(w).Encode(&github.Notification{
+					ID:         github.Ptr(path.Base(r.URL.Path)),
+					Subject:    &github.NotificationSubject{Title: github.Ptr("subject of " + repo)},
+					Repository: &github.Repository{Name: github.Ptr(repo), Owner: &github.User{Login: github.Ptr("owner")}},
+				})
+			}),
+		),
+	))
+	_, handler := GetNotificationDetails(stubGetClientFn(client), translations.NullTranslationHelper, StaticValidator(validator))
+
+	result, err := handler(context.Background(), createMCPRequest(map[string]interface{}{"notificationID": "1"}))
+	require.NoError(t, err)
+	require.False(t, result.IsError)
+	assert.Contains(t, getTextResult(t, result).Text, "subject of allowed")
+
+	result, err = handler(context.Background(), createMCPRequest(map[string]interface{}{"notificationID": "2"}))
+	require.NoError(t, err)
+	require.True(t, result.IsError)
+	text := getErrorResult(t, result).Text
+	assert.Contains(t, text, "Access denied: notification 2")
+	assert.NotContains(t, text, "secret", "the repository is not named")
+}
@@ -6,6 +6,7 @@ import (
 	"net/url"
 	"testing"
 
+	"github.com/github/github-mcp-server/pkg/access"
 	"github.com/github/github-mcp-server/pkg/raw"
 	"github.com/github/github-mcp-server/pkg/translations"
 	"github.com/google/go-github/v74/github"
@@ -232,7 +233,7 @@ func Test_repositoryResourceContentsHandler(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			client := github.NewClient(tc.mockedClient)
 			mockRawClient := raw.NewClient(client, base)
-			handler := RepositoryResourceContentsHandler((stubGetClientFn(client)), stubGetRawClientFn(mockRawClient))
+			handler := RepositoryResourceContentsHandler((stubGetClientFn(client)), stubGetRawClientFn(mockRawClient), nil)
 
 			request := mcp.ReadResourceRequest{
 				Params: struct {
@@ -258,23 +259,99 @@ func Test_repositoryResourceContentsHandler(t *testing.T) {
 
 func Test_GetRepositoryResourceContent(t *testing.T) {
 	mockRawClient := raw.NewClient(github.NewClient(nil), &url.URL{})
-	tmpl, _ := GetRepositoryResourceContent(nil, stubGetRawClientFn(mockRawClient), translations.NullTranslationHelper)
+	tmpl, _ := GetRepositoryResourceContent(nil, stubGetRawClientFn(mockRawClient), translations.NullTranslationHelper, nil)
 	require.Equal(t, "repo://{owner}/{repo}/contents{/path*}", tmpl.URITemplate.Raw())
 }
 
 func Test_GetRepositoryResourceBranchContent(t *testing.T) {
 	mockRawClient := raw.NewClient(github.NewClient(nil), &url.URL{})
-	tmpl, _ := GetRepositoryResourceBranchContent(nil, stubGetRawClientFn(mockRawClient), translations.NullTranslationHelper)
+	tmpl, _ := GetRepositoryResourceBranchContent(nil, stubGetRawClientFn(mockRawClient), translations.NullTranslationHelper, nil)
 	require.Equal(t, "repo://{owner}/{repo}/refs/heads/{branch}/contents{/path*}", tmpl.URITemplate.Raw())
 }
 func Test_GetRepositoryResourceCommitContent(t *testing.T) {
 	mockRawClient := raw.NewClient(github.NewClient(nil), &url.URL{})
-	tmpl, _ := GetRepositoryResourceCommitContent(nil, stubGetRawClientFn(mockRawClient), translations.NullTranslationHelper)
+	tmpl, _ := GetRepositoryResourceCommitContent(nil, stubGetRawClientFn(mockRawClient), translations.NullTranslationHelper, nil)
 	require.Equal(t, "repo://{owner}/{repo}/sha/{sha}/contents{/path*}", tmpl.URITemplate.Raw())
 }
 
 func Test_GetRepositoryResourceTagContent(t *testing.T) {
 	mockRawClient := raw.NewClient(github.NewClient(nil), &url.URL{})
-	tmpl, _ := GetRepositoryResourceTagContent(nil, stubGetRawClientFn(mockRawClient), translations.NullTranslationHelper)
+	tmpl, _ := GetRepositoryResourceTagContent(nil, stubGetRawClientFn(mockRawClient), translations.NullTranslationHelper, nil)
 	require.Equal(t, "repo://{owner}/{repo}/refs/tags/{tag}/contents{/path*}", tmpl.URITemplate.Raw())
 }
+
+func Test_repositoryResourceContentsHandler_RepositoryAccess(t *testing.T) {
+	base, _ := url.Parse("https://raw.example.com/")
+	validator := newTestValidator(t, "owner/allowed")
+
+	tests := []struct {
+		name        string
+		validator   *access.Validator
+		repo        string
+		expectError string
+	}{
+		{
+			name:      "accessible repository is served",
+			validator: validator,
+			repo:      "allowed",
+		},
+		{
+			name:        "inaccessible repository is rejected",
+			validator:   validator,
+			repo:        "secret",
+			expectError: "access denied: repository owner/secret is not accessible to the current user",
+		},
+		{
+			name:        "uninitialized validator fails closed",
+			validator:   access.NewValidatorWithProvider("test@example.com", access.NewStaticProvider()),
+			repo:        "allowed",
+			expectError: "failed to validate repository access",
+		},
+		{
+			name:      "nil validator disables the check",
+			validator: nil,
+			repo:      "secret",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			called := false
+			client := github.NewClient(mock.NewMockedHTTPClient(
+				mock.WithRequestMatchHandler(
+					raw.GetRawReposContentsByOwnerByRepoByPath,
+					http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+						called = true
+						w.Header().Set("Content-Type", "text/markdown")
+						_, err := w.Write([]byte("# Test Repository"))
+						require.NoError(t, err)
+					}),
+				),
+			))
+			mockRawClient := raw.NewClient(client, base)
+			handler := RepositoryResourceContentsHandler(stubGetClientFn(client), stubGetRawClientFn(mockRawClient), StaticValidator(tc.validator))
+
+			request := mcp.ReadResourceRequest{
+				Params: struct {
+					URI       string         `json:"uri"`
+					Arguments map[string]any `json:"arguments,omitempty"`
+				}{
+					Arguments: map[string]any{
+						"owner": []string{"owner"},
+						"repo":  []string{tc.repo},
+						"path":  []string{"README.md"},
+					},
+				},
+			}
+
+			_, err := handler(context.TODO(), request)
+			if tc.expectError != "" {
+				require.ErrorContains(t, err, tc.expectError)
+				require.False(t, called)
+				return
+			}
+			require.NoError(t, err)
+			require.True(t, called)
+		})
+	}
+}
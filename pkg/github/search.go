@@ -14,7 +14,8 @@ import (
 )
 
 // SearchRepositories creates a tool to search for GitHub repositories.
-func SearchRepositories(getClient GetClientFn, t translations.TranslationHelperFunc) (tool mcp.Tool, handler server.ToolHandlerFunc) {
+// Results from repositories the validator does not allow are redacted.
+func SearchRepositories(getClient GetClientFn, t translations.TranslationHelperFunc, getValidator GetValidatorFn) (tool mcp.Tool, handler server.ToolHandlerFunc) {
 	return mcp.NewTool("search_repositories",
 			mcp.WithDescription(t("TOOL_SEARCH_REPOSITORIES_DESCRIPTION", "Find GitHub repositories by name, description, readme, topics, or other metadata. Perfect for discovering projects, finding examples, or locating specific repositories across GitHub.")),
 
@@ -67,7 +68,15 @@ func SearchRepositories(getClient GetClientFn, t translations.TranslationHelperF
 				return mcp.NewToolResultError(fmt.Sprintf("failed to search repositories: %s", string(body))), nil
 			}
 
-			r, err := json.Marshal(result)
+			var redacted int
+			result.Repositories, redacted, err = filterAccessible(ctx, getValidator, result.Repositories, func(r *github.Repository) (string, string) {
+				return r.GetOwner().GetLogin(), r.GetName()
+			})
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("failed to validate repository access: %s", err)), nil
+			}
+
+			r, err := json.Marshal(redactedRepositoriesSearchResult{result, redacted})
 			if err != nil {
 				return nil, fmt.Errorf("failed to marshal response: %w", err)
 			}
@@ -77,7 +86,8 @@ func SearchRepositories(getClient GetClientFn, t translations.TranslationHelperF
 }
 
 // SearchCode creates a tool to search for code across GitHub repositories.
-func SearchCode(getClient GetClientFn, t translations.TranslationHelperFunc) (tool mcp.Tool, handler server.ToolHandlerFunc) {
+// Results from repositories the validator does not allow are redacted.
+func SearchCode(getClient GetClientFn, t translations.TranslationHelperFunc, getValidator GetValidatorFn) (tool mcp.Tool, handler server.ToolHandlerFunc) {
 	return mcp.NewTool("search_code",
 			mcp.WithDescription(t("TOOL_SEARCH_CODE_DESCRIPTION", "Fast and precise code search across ALL GitHub repositories using GitHub's native search engine. Best for finding exact symbols, functions, classes, or specific code patterns.")),
 			mcp.WithToolAnnotation(mcp.ToolAnnotation{
@@ -147,7 +157,15 @@ func SearchCode(getClient GetClientFn, t translations.TranslationHelperFunc) (to
 				return mcp.NewToolResultError(fmt.Sprintf("failed to search code: %s", string(body))), nil
 			}
 
-			r, err := json.Marshal(result)
+			var redacted int
+			result.CodeResults, redacted, err = filterAccessible(ctx, getValidator, result.CodeResults, func(c *github.CodeResult) (string, string) {
+				return c.GetRepository().GetOwner().GetLogin(), c.GetRepository().GetName()
+			})
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("failed to validate repository access: %s", err)), nil
+			}
+
+			r, err := json.Marshal(redactedCodeSearchResult{result, redacted})
 			if err != nil {
 				return nil, fmt.Errorf("failed to marshal response: %w", err)
 			}
@@ -12,6 +12,26 @@ import (
 	"github.com/mark3labs/mcp-go/mcp"
 )
 
+// redactedRepositoriesSearchResult is a repository search result annotated with the number of
+// items redacted from the current page. Like the other redacted search results, it keeps the
+// total_count GitHub reported, which also counts the results of inaccessible repositories.
+type redactedRepositoriesSearchResult struct {
+	*github.RepositoriesSearchResult
+	RedactedCount int `json:"redacted_count,omitempty"`
+}
+
+// redactedCodeSearchResult is a code search result annotated with the number of redacted items
+type redactedCodeSearchResult struct {
+	*github.CodeSearchResult
+	RedactedCount int `json:"redacted_count,omitempty"`
+}
+
+// redactedIssuesSearchResult is an issue search result annotated with the number of redacted items
+type redactedIssuesSearchResult struct {
+	*github.IssuesSearchResult
+	RedactedCount int `json:"redacted_count,omitempty"`
+}
+
 func hasFilter(query, filterType string) bool {
 	// Match filter at start of string, after whitespace, or after non-word characters like '('
 	pattern := fmt.Sprintf(`(^|\s|\W)%s:\S+`, regexp.QuoteMeta(filterType))
@@ -38,6 +58,7 @@ func hasTypeFilter(query string) bool {
 func searchHandler(
 	ctx context.Context,
 	getClient GetClientFn,
+	getValidator GetValidatorFn,
 	request mcp.CallToolRequest,
 	searchType string,
 	errorPrefix string,
@@ -106,7 +127,15 @@ func searchHandler(
 		return mcp.NewToolResultError(fmt.Sprintf("%s: %s", errorPrefix, string(body))), nil
 	}
 
-	r, err := json.Marshal(result)
+	var redacted int
+	result.Issues, redacted, err = filterAccessible(ctx, getValidator, result.Issues, func(i *github.Issue) (string, string) {
+		return repositoryFromURL(i.GetRepositoryURL())
+	})
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to validate repository access: %s", err)), nil
+	}
+
+	r, err := json.Marshal(redactedIssuesSearchResult{result, redacted})
 	if err != nil {
 		return nil, fmt.Errorf("%s: failed to marshal response: %w", errorPrefix, err)
 	}
@@ -316,9 +316,12 @@ func GetGlobalSecurityAdvisory(getClient GetClientFn, t translations.Translation
 		}
 }
 
-func ListOrgRepositorySecurityAdvisories(getClient GetClientFn, t translations.TranslationHelperFunc) (tool mcp.Tool, handler server.ToolHandlerFunc) {
+// ListOrgRepositorySecurityAdvisories creates a tool to list repository security advisories for an organization.
+// Advisories of repositories the validator does not allow are redacted, and counted in the
+// redacted_count of the result.
+func ListOrgRepositorySecurityAdvisories(getClient GetClientFn, t translations.TranslationHelperFunc, getValidator GetValidatorFn) (tool mcp.Tool, handler server.ToolHandlerFunc) {
 	return mcp.NewTool("list_org_repository_security_advisories",
-			mcp.WithDescription(t("TOOL_LIST_ORG_REPOSITORY_SECURITY_ADVISORIES_DESCRIPTION", "List repository security advisories for a GitHub organization.")),
+			mcp.WithDescription(t("TOOL_LIST_ORG_REPOSITORY_SECURITY_ADVISORIES_DESCRIPTION", "List repository security advisories for a GitHub organization. While repository access validation is enabled, the result is an object with the advisories under \"items\" and, when advisories from repositories outside the accessible list were hidden, their number under \"redacted_count\".")),
 			mcp.WithToolAnnotation(mcp.ToolAnnotation{
 				Title:        t("TOOL_LIST_ORG_REPOSITORY_SECURITY_ADVISORIES_USER_TITLE", "List org repository security advisories"),
 				ReadOnlyHint: ToBoolPtr(true),
@@ -387,7 +390,15 @@ func ListOrgRepositorySecurityAdvisories(getClient GetClientFn, t translations.T
 				return mcp.NewToolResultError(fmt.Sprintf("failed to list organization repository advisories: %s", string(body))), nil
 			}
 
-			r, err := json.Marshal(advisories)
+			validator, err := resolveValidator(ctx, getValidator)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("failed to validate repository access: %s", err)), nil
+			}
+			advisories, redacted := filterWithValidator(validator, advisories, func(a *github.SecurityAdvisory) (string, string) {
+				return repositoryFromURL(a.GetURL())
+			})
+
+			r, err := json.Marshal(accessibleList(validator, advisories, redacted))
 			if err != nil {
 				return nil, fmt.Errorf("failed to marshal advisories: %w", err)
 			}
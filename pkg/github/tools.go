@@ -15,21 +15,47 @@ import (
 type GetClientFn func(context.Context) (*github.Client, error)
 type GetGQLClientFn func(context.Context) (*githubv4.Client, error)
 
+// GetValidatorFn returns the access validator for the current request. A nil function, or a
+// nil validator, disables repository access validation.
+type GetValidatorFn func(context.Context) (*access.Validator, error)
+
+// StaticValidator returns a GetValidatorFn that always returns validator, for servers where
+// every request is made on behalf of the same user
+func StaticValidator(validator *access.Validator) GetValidatorFn {
+	return func(context.Context) (*access.Validator, error) {
+		return validator, nil
+	}
+}
+
+// resolveValidator returns the validator for the current request, or nil if getValidator is nil
+func resolveValidator(ctx context.Context, getValidator GetValidatorFn) (*access.Validator, error) {
+	if getValidator == nil {
+		return nil, nil
+	}
+	return getValidator(ctx)
+}
+
 var DefaultTools = []string{"all"}
 
-func DefaultToolsetGroup(readOnly bool, getClient GetClientFn, getGQLClient GetGQLClientFn, getRawClient raw.GetRawClientFn, t translations.TranslationHelperFunc, contentWindowSize int, validator *access.Validator) *toolsets.ToolsetGroup {
+// DefaultToolsetGroup creates the toolset group with every GitHub tool. The given middleware
+// wraps every tool outside of the repository access check, e.g. to audit refused calls.
+func DefaultToolsetGroup(readOnly bool, getClient GetClientFn, getGQLClient GetGQLClientFn, getRawClient raw.GetRawClientFn, t translations.TranslationHelperFunc, contentWindowSize int, getValidator GetValidatorFn, middleware ...toolsets.ToolMiddleware) *toolsets.ToolsetGroup {
 	tsg := toolsets.NewToolsetGroup(readOnly)
+	tsg.Use(middleware...)
+
+	// Enforce repository access on every tool that targets an owner/repo
+	tsg.Use(RepositoryAccessMiddleware(getValidator))
 
 	// Define all available features with their default state (disabled)
 	// Create toolsets
 	repos := toolsets.NewToolset("repos", "GitHub Repository related tools").
 		AddReadTools(
-			toolsets.NewServerTool(SearchRepositories(getClient, t)),
-			toolsets.NewServerTool(GetFileContentsWithValidation(getClient, getRawClient, t, validator)),
-			toolsets.NewServerTool(ListCommitsWithValidation(getClient, t, validator)),
-			toolsets.NewServerTool(SearchCode(getClient, t)),
-			toolsets.NewServerTool(GetCommitWithValidation(getClient, t, validator)),
-			toolsets.NewServerTool(ListBranchesWithValidation(getClient, t, validator)),
+			toolsets.NewServerTool(SearchRepositories(getClient, t, getValidator)),
+			toolsets.NewServerTool(GetFileContents(getClient, getRawClient, t)),
+			toolsets.NewServerTool(ListCommits(getClient, t)),
+			toolsets.NewServerTool(SearchCode(getClient, t, getValidator)),
+			toolsets.NewServerTool(GetCommit(getClient, t)),
+			toolsets.NewServerTool(ListBranches(getClient, t)),
 			toolsets.NewServerTool(ListTags(getClient, t)),
 			toolsets.NewServerTool(GetTag(getClient, t)),
 			toolsets.NewServerTool(ListReleases(getClient, t)),
@@ -37,19 +63,19 @@ func DefaultToolsetGroup(readOnly bool, getClient GetClientFn, getGQLClient GetG
 			toolsets.NewServerTool(GetReleaseByTag(getClient, t)),
 		).
 		AddWriteTools(
-			toolsets.NewServerTool(CreateBranchWithValidation(getClient, t, validator)),
+			toolsets.NewServerTool(CreateBranch(getClient, t)),
 		).
 		AddResourceTemplates(
-			toolsets.NewServerResourceTemplate(GetRepositoryResourceContent(getClient, getRawClient, t)),
-			toolsets.NewServerResourceTemplate(GetRepositoryResourceBranchContent(getClient, getRawClient, t)),
-			toolsets.NewServerResourceTemplate(GetRepositoryResourceCommitContent(getClient, getRawClient, t)),
-			toolsets.NewServerResourceTemplate(GetRepositoryResourceTagContent(getClient, getRawClient, t)),
-			toolsets.NewServerResourceTemplate(GetRepositoryResourcePrContent(getClient, getRawClient, t)),
+			toolsets.NewServerResourceTemplate(GetRepositoryResourceContent(getClient, getRawClient, t, getValidator)),
+			toolsets.NewServerResourceTemplate(GetRepositoryResourceBranchContent(getClient, getRawClient, t, getValidator)),
+			toolsets.NewServerResourceTemplate(GetRepositoryResourceCommitContent(getClient, getRawClient, t, getValidator)),
+			toolsets.NewServerResourceTemplate(GetRepositoryResourceTagContent(getClient, getRawClient, t, getValidator)),
+			toolsets.NewServerResourceTemplate(GetRepositoryResourcePrContent(getClient, getRawClient, t, getValidator)),
 		)
 	issues := toolsets.NewToolset("issues", "GitHub Issues related tools").
 		AddReadTools(
 			toolsets.NewServerTool(GetIssue(getClient, t)),
-			toolsets.NewServerTool(SearchIssues(getClient, t)),
+			toolsets.NewServerTool(SearchIssues(getClient, t, getValidator)),
 			toolsets.NewServerTool(ListIssues(getGQLClient, t)),
 			toolsets.NewServerTool(GetIssueComments(getClient, t)),
 			toolsets.NewServerTool(ListIssueTypes(getClient, t)),
@@ -76,7 +102,7 @@ func DefaultToolsetGroup(readOnly bool, getClient GetClientFn, getGQLClient GetG
 			toolsets.NewServerTool(GetPullRequest(getClient, t)),
 			toolsets.NewServerTool(ListPullRequests(getClient, t)),
 			toolsets.NewServerTool(GetPullRequestFiles(getClient, t)),
-			toolsets.NewServerTool(SearchPullRequests(getClient, t)),
+			toolsets.NewServerTool(SearchPullRequests(getClient, t, getValidator)),
 			toolsets.NewServerTool(GetPullRequestStatus(getClient, t)),
 			toolsets.NewServerTool(GetPullRequestComments(getClient, t)),
 			toolsets.NewServerTool(GetPullRequestReviews(getClient, t)),
@@ -107,8 +133,8 @@ func DefaultToolsetGroup(readOnly bool, getClient GetClientFn, getGQLClient GetG
 
 	notifications := toolsets.NewToolset("notifications", "GitHub Notifications related tools").
 		AddReadTools(
-			toolsets.NewServerTool(ListNotifications(getClient, t)),
-			toolsets.NewServerTool(GetNotificationDetails(getClient, t)),
+			toolsets.NewServerTool(ListNotifications(getClient, t, getValidator)),
+			toolsets.NewServerTool(GetNotificationDetails(getClient, t, getValidator)),
 		).
 		AddWriteTools()
 
@@ -145,7 +171,7 @@ func DefaultToolsetGroup(readOnly bool, getClient GetClientFn, getGQLClient GetG
 			toolsets.NewServerTool(ListGlobalSecurityAdvisories(getClient, t)),
 			toolsets.NewServerTool(GetGlobalSecurityAdvisory(getClient, t)),
 			toolsets.NewServerTool(ListRepositorySecurityAdvisories(getClient, t)),
-			toolsets.NewServerTool(ListOrgRepositorySecurityAdvisories(getClient, t)),
+			toolsets.NewServerTool(ListOrgRepositorySecurityAdvisories(getClient, t, getValidator)),
 		)
 
 	// Keep experiments alive so the system doesn't error out when it's always enabled
@@ -154,6 +180,7 @@ func DefaultToolsetGroup(readOnly bool, getClient GetClientFn, getGQLClient GetG
 	contextTools := toolsets.NewToolset("context", "Tools that provide context about the current user and GitHub context you are operating in").
 		AddReadTools(
 			toolsets.NewServerTool(GetMe(getClient, t)),
+			toolsets.NewServerTool(GetRateLimit(getClient, t)),
 			toolsets.NewServerTool(GetTeams(getClient, getGQLClient, t)),
 			toolsets.NewServerTool(GetTeamMembers(getGQLClient, t)),
 		)
@@ -167,8 +194,15 @@ func DefaultToolsetGroup(readOnly bool, getClient GetClientFn, getGQLClient GetG
 			toolsets.NewServerTool(UpdateGist(getClient, t)),
 		)
 
+	accessTools := toolsets.NewToolset("access", "Tools that show which repositories the current user is allowed to work in").
+		AddReadTools(
+			toolsets.NewServerTool(ListAccessibleRepositories(getValidator, t)),
+			toolsets.NewServerTool(CheckRepositoryAccess(getValidator, t)),
+		)
+
 	// Add toolsets to the group
 	tsg.AddToolset(contextTools)
+	tsg.AddToolset(accessTools)
 	tsg.AddToolset(repos)
 	tsg.AddToolset(issues)
 	tsg.AddToolset(orgs)
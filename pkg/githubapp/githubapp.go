@@ -0,0 +1,203 @@
+// Package githubapp authenticates as a GitHub App installation. It signs app JWTs with the
+// app's private key, exchanges them for installation tokens and refreshes the installation
+// token before it expires.
+package githubapp
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v74/github"
+)
+
+const (
+	// RefreshMargin is how long before its expiry the installation token is replaced
+	RefreshMargin = 5 * time.Minute
+
+	// jwtLifetime is how long app JWTs are valid, GitHub accepts at most 10 minutes
+	jwtLifetime = 9 * time.Minute
+
+	// jwtClockSkew backdates app JWTs to allow for clock drift between us and GitHub
+	jwtClockSkew = 60 * time.Second
+)
+
+// Config identifies the GitHub App installation to authenticate as
+type Config struct {
+	// AppID is the ID of the GitHub App
+	AppID int64
+
+	// InstallationID is the ID of the installation of the app on an organization or user
+	InstallationID int64
+
+	// PrivateKeyFile is the path to the PEM private key of the app
+	PrivateKeyFile string
+}
+
+// Enabled reports whether GitHub App authentication is configured
+func (c Config) Enabled() bool {
+	return c.AppID != 0 || c.InstallationID != 0 || c.PrivateKeyFile != ""
+}
+
+// Validate returns an error if the config does not fully identify an installation
+func (c Config) Validate() error {
+	var missing []error
+	if c.AppID <= 0 {
+		missing = append(missing, errors.New("app ID is required"))
+	}
+	if c.InstallationID <= 0 {
+		missing = append(missing, errors.New("installation ID is required"))
+	}
+	if c.PrivateKeyFile == "" {
+		missing = append(missing, errors.New("private key file is required"))
+	}
+	return errors.Join(missing...)
+}
+
+// LoadPrivateKey reads a PEM encoded RSA private key in PKCS #1 or PKCS #8 form, as
+// downloaded from the GitHub App settings
+func LoadPrivateKey(path string) (*rsa.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read private key: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("private key %s is not PEM encoded", path)
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key %s: %w", path, err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key %s is not an RSA key", path)
+	}
+	return key, nil
+}
+
+// TokenSource provides installation tokens of a GitHub App installation. It is safe for
+// concurrent use; the token is exchanged once and replaced when it is about to expire.
+type TokenSource struct {
+	appID          int64
+	installationID int64
+	key            *rsa.PrivateKey
+	// client calls the app endpoints, authenticated with app JWTs
+	client *github.Client
+	now    func() time.Time
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+// NewTokenSource creates a token source for the installation in config. baseURL is the REST
+// API root of the GitHub host, and transport carries the token exchanges.
+func NewTokenSource(config Config, baseURL *url.URL, transport http.RoundTripper) (*TokenSource, error) {
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid GitHub App configuration: %w", err)
+	}
+	key, err := LoadPrivateKey(config.PrivateKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	s := &TokenSource{
+		appID:          config.AppID,
+		installationID: config.InstallationID,
+		key:            key,
+		now:            time.Now,
+	}
+	s.client = github.NewClient(&http.Client{Transport: &jwtTransport{source: s, transport: transport}})
+	s.client.BaseURL = baseURL
+	return s, nil
+}
+
+// Token returns an installation token valid for at least RefreshMargin, exchanging a new one
+// if the current token is about to expire
+func (s *TokenSource) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token != "" && s.now().Add(RefreshMargin).Before(s.expiresAt) {
+		return s.token, nil
+	}
+
+	token, _, err := s.client.Apps.CreateInstallationToken(ctx, s.installationID, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create installation token for installation %d of app %d: %w", s.installationID, s.appID, err)
+	}
+	s.token = token.GetToken()
+	s.expiresAt = token.GetExpiresAt().Time
+	return s.token, nil
+}
+
+// ExpiresAt returns when the current installation token expires, or the zero time if none
+// has been exchanged yet
+func (s *TokenSource) ExpiresAt() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.expiresAt
+}
+
+// jwt returns an app JWT, signed with RS256 as GitHub requires
+func (s *TokenSource) jwt() (string, error) {
+	now := s.now()
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]any{
+		"iat": now.Add(-jwtClockSkew).Unix(),
+		"exp": now.Add(jwtLifetime).Unix(),
+		"iss": strconv.FormatInt(s.appID, 10),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign GitHub App JWT: %w", err)
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// jwtTransport authenticates requests to the app endpoints with a fresh app JWT
+type jwtTransport struct {
+	source    *TokenSource
+	transport http.RoundTripper
+}
+
+func (t *jwtTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	jwt, err := t.source.jwt()
+	if err != nil {
+		return nil, err
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	return t.transport.RoundTrip(req)
+}
@@ -0,0 +1,164 @@
+package githubapp
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeKey(t *testing.T, key *rsa.PrivateKey, pkcs8 bool) string {
+	t.Helper()
+	block := &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}
+	if pkcs8 {
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		require.NoError(t, err)
+		block = &pem.Block{Type: "PRIVATE KEY", Bytes: der}
+	}
+	path := filepath.Join(t.TempDir(), "app.pem")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(block), 0600))
+	return path
+}
+
+// verifyJWT checks the RS256 signature of an app JWT and returns its claims
+func verifyJWT(t *testing.T, jwt string, key *rsa.PublicKey) map[string]any {
+	t.Helper()
+	parts := strings.Split(jwt, ".")
+	require.Len(t, parts, 3)
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	require.NoError(t, err)
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	require.NoError(t, rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature))
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	require.NoError(t, err)
+	var claims map[string]any
+	require.NoError(t, json.Unmarshal(payload, &claims))
+	return claims
+}
+
+func TestConfig_Validate(t *testing.T) {
+	assert.False(t, Config{}.Enabled())
+	assert.True(t, Config{AppID: 1}.Enabled())
+
+	require.NoError(t, Config{AppID: 1, InstallationID: 2, PrivateKeyFile: "app.pem"}.Validate())
+
+	err := Config{AppID: 1}.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "installation ID is required")
+	assert.Contains(t, err.Error(), "private key file is required")
+}
+
+func TestLoadPrivateKey(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	for _, pkcs8 := range []bool{false, true} {
+		loaded, err := LoadPrivateKey(writeKey(t, key, pkcs8))
+		require.NoError(t, err)
+		assert.True(t, key.Equal(loaded))
+	}
+
+	notPEM := filepath.Join(t.TempDir(), "app.pem")
+	require.NoError(t, os.WriteFile(notPEM, []byte("not a key"), 0600))
+	_, err = LoadPrivateKey(notPEM)
+	assert.ErrorContains(t, err, "not PEM encoded")
+
+	_, err = LoadPrivateKey(filepath.Join(t.TempDir(), "missing.pem"))
+	assert.ErrorContains(t, err, "failed to read private key")
+}
+
+func TestTokenSource(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	now := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	var mu sync.Mutex
+	exchanges := 0
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/app/installations/42/access_tokens" {
+			http.NotFound(w, r)
+			return
+		}
+		claims := verifyJWT(t, strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "), &key.PublicKey)
+		assert.Equal(t, "7", claims["iss"])
+		assert.Equal(t, float64(now.Add(-jwtClockSkew).Unix()), claims["iat"])
+		assert.Equal(t, float64(now.Add(jwtLifetime).Unix()), claims["exp"])
+
+		mu.Lock()
+		exchanges++
+		token := fmt.Sprintf("ghs_token%d", exchanges)
+		mu.Unlock()
+
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"token":      token,
+			"expires_at": now.Add(time.Hour).Format(time.RFC3339),
+		})
+	}))
+	defer api.Close()
+	baseURL, err := url.Parse(api.URL + "/")
+	require.NoError(t, err)
+
+	source, err := NewTokenSource(Config{AppID: 7, InstallationID: 42, PrivateKeyFile: writeKey(t, key, false)}, baseURL, nil)
+	require.NoError(t, err)
+	source.now = func() time.Time { return now }
+
+	token, err := source.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "ghs_token1", token)
+	assert.Equal(t, now.Add(time.Hour), source.ExpiresAt())
+
+	// The token is reused while it is valid for longer than the refresh margin
+	now = now.Add(time.Hour - RefreshMargin - time.Second)
+	token, err = source.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "ghs_token1", token)
+
+	// and replaced once it is about to expire
+	now = now.Add(2 * time.Second)
+	token, err = source.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "ghs_token2", token)
+}
+
+func TestTokenSource_ExchangeFails(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"message":"A JSON web token could not be decoded"}`))
+	}))
+	defer api.Close()
+	baseURL, err := url.Parse(api.URL + "/")
+	require.NoError(t, err)
+
+	source, err := NewTokenSource(Config{AppID: 7, InstallationID: 42, PrivateKeyFile: writeKey(t, key, true)}, baseURL, nil)
+	require.NoError(t, err)
+
+	_, err = source.Token(context.Background())
+	assert.ErrorContains(t, err, "failed to create installation token for installation 42 of app 7")
+
+	_, err = NewTokenSource(Config{AppID: 7}, baseURL, nil)
+	assert.ErrorContains(t, err, "invalid GitHub App configuration")
+}
@@ -0,0 +1,347 @@
+// Package httpcache caches GitHub REST responses and revalidates them with conditional
+// requests. A 304 Not Modified answer does not count against the rate limit, so repeated
+// reads of unchanged resources become free while always returning current data.
+package httpcache
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultMaxBytes is the default size of the cache
+const DefaultMaxBytes = 32 * 1024 * 1024
+
+// maxEntryFraction limits a single response to this fraction of the cache, so one large
+// response cannot evict everything else
+const maxEntryFraction = 8
+
+// Config sizes the cache and selects its optional disk backing
+type Config struct {
+	// MaxBytes is the total size of the cached response bodies, zero disables the cache
+	MaxBytes int64
+
+	// Dir persists the cached responses across restarts, empty keeps them in memory only.
+	// The responses may contain private repository data, so the directory is only readable
+	// by the current user.
+	Dir string
+}
+
+// Stats counts how the cache answered requests
+type Stats struct {
+	// Hits are requests answered from the cache after GitHub confirmed it with a 304
+	Hits int64 `json:"hits"`
+	// Misses are cacheable requests without a cached response, or whose response changed
+	Misses int64 `json:"misses"`
+	// Evictions are responses removed to make room for newer ones
+	Evictions int64 `json:"evictions"`
+	// Entries and Bytes are the number and body size of the cached responses
+	Entries int   `json:"entries"`
+	Bytes   int64 `json:"bytes"`
+}
+
+// entry is a cached response, also the format of the files of the disk backing
+type entry struct {
+	Key          string      `json:"key"`
+	StatusCode   int         `json:"status_code"`
+	Header       http.Header `json:"header"`
+	Body         []byte      `json:"body"`
+	ETag         string      `json:"etag,omitempty"`
+	LastModified string      `json:"last_modified,omitempty"`
+	StoredAt     time.Time   `json:"stored_at"`
+}
+
+// Cache is an LRU of REST responses keyed by URL, media type and auth identity. It is safe
+// for concurrent use.
+type Cache struct {
+	maxBytes      int64
+	maxEntryBytes int64
+	dir           string
+
+	mu      sync.Mutex
+	lru     *list.List // of *entry, most recently used first
+	entries map[string]*list.Element
+	bytes   int64
+	stats   Stats
+}
+
+// New creates a cache, loading the responses persisted in config.Dir if it is set
+func New(config Config) (*Cache, error) {
+	c := &Cache{
+		maxBytes:      config.MaxBytes,
+		maxEntryBytes: config.MaxBytes / maxEntryFraction,
+		dir:           config.Dir,
+		lru:           list.New(),
+		entries:       make(map[string]*list.Element),
+	}
+	if c.dir == "" {
+		return c, nil
+	}
+	if err := os.MkdirAll(c.dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Stats returns the current counters of the cache
+func (c *Cache) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Entries = c.lru.Len()
+	stats.Bytes = c.bytes
+	return stats
+}
+
+// Transport returns a http.RoundTripper that caches the GET responses of transport. It must
+// see the Authorization header, so it goes below the transport that adds it.
+func (c *Cache) Transport(transport http.RoundTripper) http.RoundTripper {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	return &cachingTransport{cache: c, transport: transport}
+}
+
+type cachingTransport struct {
+	cache     *Cache
+	transport http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *cachingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !cacheable(req) {
+		return t.transport.RoundTrip(req)
+	}
+
+	key := cacheKey(req)
+	cached := t.cache.get(key)
+	if cached != nil {
+		req = req.Clone(req.Context())
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+
+	resp, err := t.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if cached != nil && resp.StatusCode == http.StatusNotModified {
+		t.cache.count(func(s *Stats) { s.Hits++ })
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+		return cached.response(req, resp.Header), nil
+	}
+
+	t.cache.count(func(s *Stats) { s.Misses++ })
+	etag, lastModified := resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")
+	if resp.StatusCode != http.StatusOK || (etag == "" && lastModified == "") {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	t.cache.put(&entry{
+		Key:          key,
+		StatusCode:   resp.StatusCode,
+		Header:       resp.Header.Clone(),
+		Body:         body,
+		ETag:         etag,
+		LastModified: lastModified,
+		StoredAt:     time.Now().UTC(),
+	})
+	return resp, nil
+}
+
+// cacheable reports whether req is a plain read whose response can be cached
+func cacheable(req *http.Request) bool {
+	return req.Method == http.MethodGet &&
+		req.Header.Get("Range") == "" &&
+		req.Header.Get("If-None-Match") == "" &&
+		req.Header.Get("If-Modified-Since") == ""
+}
+
+// cacheKey identifies the response of req. Responses differ by the media type asked for and
+// by who asks, so the key covers the Accept header and a hash of the Authorization header;
+// the token itself is never stored.
+func cacheKey(req *http.Request) string {
+	identity := sha256.Sum256([]byte(req.Header.Get("Authorization")))
+	key := sha256.Sum256([]byte(strings.Join([]string{
+		hex.EncodeToString(identity[:]),
+		req.Header.Get("Accept"),
+		req.URL.String(),
+	}, "\n")))
+	return hex.EncodeToString(key[:])
+}
+
+// response rebuilds the cached response for req. The headers of the 304 answer, such as the
+// current rate limit, replace the cached ones.
+func (e *entry) response(req *http.Request, fresh http.Header) *http.Response {
+	header := e.Header.Clone()
+	for k, v := range fresh {
+		header[k] = v
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode)),
+		StatusCode:    e.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
+
+func (c *Cache) count(update func(*Stats)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	update(&c.stats)
+}
+
+// get returns the cached response of key and marks it as recently used
+func (c *Cache) get(key string) *entry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	c.lru.MoveToFront(element)
+	return element.Value.(*entry)
+}
+
+// put stores e, evicting the least recently used responses until it fits. The response is
+// written to disk before taking the lock, and only moved in place under it, so the disk
+// backing follows the order of the in-memory updates and evictions.
+func (c *Cache) put(e *entry) {
+	if int64(len(e.Body)) > c.maxEntryBytes {
+		return
+	}
+	tmp := c.write(e)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.insert(e)
+	if tmp != "" && os.Rename(tmp, c.path(e.Key)) != nil {
+		_ = os.Remove(tmp)
+	}
+	for c.bytes > c.maxBytes {
+		c.evict()
+	}
+}
+
+// insert adds or replaces the entry in memory, the lock must be held
+func (c *Cache) insert(e *entry) {
+	if element, ok := c.entries[e.Key]; ok {
+		c.bytes -= int64(len(element.Value.(*entry).Body))
+		c.lru.Remove(element)
+	}
+	c.entries[e.Key] = c.lru.PushFront(e)
+	c.bytes += int64(len(e.Body))
+}
+
+// evict removes the least recently used entry, the lock must be held
+func (c *Cache) evict() {
+	element := c.lru.Back()
+	if element == nil {
+		return
+	}
+	e := element.Value.(*entry)
+	c.lru.Remove(element)
+	delete(c.entries, e.Key)
+	c.bytes -= int64(len(e.Body))
+	c.stats.Evictions++
+	if c.dir != "" {
+		_ = os.Remove(c.path(e.Key))
+	}
+}
+
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.dir, key+".json")
+}
+
+// write writes e to a temporary file of the disk backing and returns its path, or an empty
+// string if there is no disk backing. A response that cannot be written is only cached in
+// memory.
+func (c *Cache) write(e *entry) string {
+	if c.dir == "" {
+		return ""
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return ""
+	}
+	tmp, err := os.CreateTemp(c.dir, ".entry-*")
+	if err != nil {
+		return ""
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err != nil || closeErr != nil {
+		_ = os.Remove(tmp.Name())
+		return ""
+	}
+	return tmp.Name()
+}
+
+// load reads the persisted responses, most recent first, dropping those that no longer fit
+// and files that cannot be read
+func (c *Cache) load() error {
+	files, err := filepath.Glob(filepath.Join(c.dir, "*.json"))
+	if err != nil {
+		return fmt.Errorf("failed to list cache directory: %w", err)
+	}
+
+	var entries []*entry
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		var e entry
+		if err := json.Unmarshal(data, &e); err != nil || c.path(e.Key) != file {
+			_ = os.Remove(file)
+			continue
+		}
+		entries = append(entries, &e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].StoredAt.After(entries[j].StoredAt)
+	})
+
+	for _, e := range entries {
+		size := int64(len(e.Body))
+		if size > c.maxEntryBytes || c.bytes+size > c.maxBytes {
+			_ = os.Remove(c.path(e.Key))
+			continue
+		}
+		// Entries are loaded newest first, so each goes behind the ones loaded before
+		c.entries[e.Key] = c.lru.PushBack(e)
+		c.bytes += size
+	}
+	return nil
+}
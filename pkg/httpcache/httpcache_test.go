@@ -0,0 +1,204 @@
+package httpcache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAPI serves the body of each path with an ETag derived from it and the token,
+// answering 304 when the client already has it. It counts the requests in the rate limit
+// header, and the full responses it sent.
+type fakeAPI struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	bodies   map[string]string
+	requests int
+	full     int
+}
+
+func newFakeAPI(t *testing.T, bodies map[string]string) *fakeAPI {
+	t.Helper()
+	api := &fakeAPI{bodies: bodies}
+	api.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		api.mu.Lock()
+		defer api.mu.Unlock()
+		body, ok := api.bodies[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		sum := sha256.Sum256([]byte(r.Header.Get("Authorization") + "\n" + body))
+		etag := `"` + hex.EncodeToString(sum[:8]) + `"`
+		api.requests++
+		w.Header().Set("X-RateLimit-Used", strconv.Itoa(api.requests))
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		api.full++
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(api.Close)
+	return api
+}
+
+func (api *fakeAPI) set(path, body string) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	api.bodies[path] = body
+}
+
+func (api *fakeAPI) fullResponses() int {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	return api.full
+}
+
+// get sends a GET through transport with the token and returns the status and body
+func get(t *testing.T, transport http.RoundTripper, url, token string) (int, string, http.Header) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := transport.RoundTrip(req)
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp.StatusCode, string(body), resp.Header
+}
+
+func TestTransport_Revalidates(t *testing.T) {
+	api := newFakeAPI(t, map[string]string{"/repos/o/r/pulls/1": `{"number":1,"state":"open"}`})
+	cache, err := New(Config{MaxBytes: 1024})
+	require.NoError(t, err)
+	transport := cache.Transport(nil)
+
+	status, body, _ := get(t, transport, api.URL+"/repos/o/r/pulls/1", "alice")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, `{"number":1,"state":"open"}`, body)
+
+	// An unchanged response is confirmed with a 304 and served from the cache, with the
+	// current headers of the 304
+	status, body, header := get(t, transport, api.URL+"/repos/o/r/pulls/1", "alice")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, `{"number":1,"state":"open"}`, body)
+	assert.Equal(t, "2", header.Get("X-RateLimit-Used"))
+	assert.Equal(t, "application/json", header.Get("Content-Type"))
+	assert.Equal(t, 1, api.fullResponses())
+
+	// A changed response replaces the cached one, without counting as a hit
+	api.set("/repos/o/r/pulls/1", `{"number":1,"state":"closed"}`)
+	_, body, _ = get(t, transport, api.URL+"/repos/o/r/pulls/1", "alice")
+	assert.Equal(t, `{"number":1,"state":"closed"}`, body)
+
+	// Responses are not shared between tokens
+	_, body, _ = get(t, transport, api.URL+"/repos/o/r/pulls/1", "bob")
+	assert.Equal(t, `{"number":1,"state":"closed"}`, body)
+	assert.Equal(t, 3, api.fullResponses())
+
+	assert.Equal(t, Stats{Hits: 1, Misses: 3, Entries: 2, Bytes: int64(2 * len(`{"number":1,"state":"closed"}`))}, cache.Stats())
+}
+
+func TestTransport_OnlyCachesPlainReads(t *testing.T) {
+	api := newFakeAPI(t, map[string]string{"/repos/o/r/issues": `[]`})
+	cache, err := New(Config{MaxBytes: 1024})
+	require.NoError(t, err)
+	transport := cache.Transport(nil)
+
+	req, err := http.NewRequest(http.MethodPost, api.URL+"/repos/o/r/issues", strings.NewReader(`{}`))
+	require.NoError(t, err)
+	resp, err := transport.RoundTrip(req)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+
+	req, err = http.NewRequest(http.MethodGet, api.URL+"/repos/o/r/issues", nil)
+	require.NoError(t, err)
+	req.Header.Set("Range", "bytes=0-1")
+	resp, err = transport.RoundTrip(req)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+
+	assert.Equal(t, Stats{}, cache.Stats())
+}
+
+func TestCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	api := newFakeAPI(t, map[string]string{
+		"/a": strings.Repeat("a", 10),
+		"/b": strings.Repeat("b", 10),
+		"/c": strings.Repeat("c", 10),
+		"/d": strings.Repeat("d", 100),
+	})
+	cache, err := New(Config{MaxBytes: 25})
+	require.NoError(t, err)
+	cache.maxEntryBytes = 20
+	transport := cache.Transport(nil)
+
+	get(t, transport, api.URL+"/a", "alice")
+	get(t, transport, api.URL+"/b", "alice")
+	get(t, transport, api.URL+"/a", "alice") // a is now more recently used than b
+	get(t, transport, api.URL+"/c", "alice") // evicts b
+	get(t, transport, api.URL+"/d", "alice") // too large to cache
+
+	stats := cache.Stats()
+	assert.Equal(t, int64(1), stats.Evictions)
+	assert.Equal(t, 2, stats.Entries)
+	assert.NotNil(t, cache.get(cacheKeyFor(t, api.URL+"/a", "alice")))
+	assert.Nil(t, cache.get(cacheKeyFor(t, api.URL+"/b", "alice")))
+	assert.NotNil(t, cache.get(cacheKeyFor(t, api.URL+"/c", "alice")))
+	assert.Nil(t, cache.get(cacheKeyFor(t, api.URL+"/d", "alice")))
+}
+
+func cacheKeyFor(t *testing.T, url, token string) string {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+token)
+	return cacheKey(req)
+}
+
+func TestCache_Disk(t *testing.T) {
+	api := newFakeAPI(t, map[string]string{"/repos/o/r/branches": `[{"name":"main"}]`})
+	dir := filepath.Join(t.TempDir(), "cache")
+
+	cache, err := New(Config{MaxBytes: 1024, Dir: dir})
+	require.NoError(t, err)
+	get(t, cache.Transport(nil), api.URL+"/repos/o/r/branches", "alice")
+
+	info, err := os.Stat(dir)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0700), info.Mode().Perm())
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	data, err := os.ReadFile(files[0])
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "Bearer alice", "the token is never persisted")
+
+	// A new cache revalidates the persisted response instead of fetching it again
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "corrupt.json"), []byte("not json"), 0600))
+	restarted, err := New(Config{MaxBytes: 1024, Dir: dir})
+	require.NoError(t, err)
+	_, body, _ := get(t, restarted.Transport(nil), api.URL+"/repos/o/r/branches", "alice")
+	assert.Equal(t, `[{"name":"main"}]`, body)
+	assert.Equal(t, 1, api.fullResponses())
+	assert.Equal(t, int64(1), restarted.Stats().Hits)
+
+	_, err = os.Stat(filepath.Join(dir, "corrupt.json"))
+	assert.True(t, os.IsNotExist(err), "unreadable entries are removed")
+}
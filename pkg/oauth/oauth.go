@@ -0,0 +1,272 @@
+// Package oauth logs users in with the GitHub OAuth device flow, stores their token on disk and
+// refreshes it before it expires.
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultScopes are requested by OAuth apps; GitHub Apps ignore scopes and use their permissions
+var DefaultScopes = []string{"repo", "read:org", "user:email", "notifications", "gist", "workflow"}
+
+// slowDownIncrease is added to the polling interval whenever GitHub asks to slow down
+const slowDownIncrease = 5 * time.Second
+
+// Errors returned while polling for the token of a device code
+var (
+	ErrAccessDenied = errors.New("the authorization request was denied")
+	ErrExpiredCode  = errors.New("the device code expired before it was authorized")
+)
+
+// DeviceCode is the code the user enters at the verification URI to authorize the device
+type DeviceCode struct {
+	DeviceCode      string `json:"device_code"`
+	UserCode        string `json:"user_code"`
+	VerificationURI string `json:"verification_uri"`
+	// ExpiresIn and Interval are in seconds
+	ExpiresIn int `json:"expires_in"`
+	Interval  int `json:"interval"`
+}
+
+// Token is an OAuth user access token. Tokens of OAuth apps do not expire, tokens of
+// GitHub Apps expire and come with a refresh token.
+type Token struct {
+	// Host is the GitHub host the token was issued by, as passed to --gh-host
+	Host string `json:"host"`
+	// ClientID is the OAuth client the token was issued to, needed to refresh it
+	ClientID    string `json:"client_id"`
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	Scope       string `json:"scope,omitempty"`
+	// ExpiresAt is the zero time for tokens that do not expire
+	ExpiresAt             time.Time `json:"expires_at"`
+	RefreshToken          string    `json:"refresh_token,omitempty"`
+	RefreshTokenExpiresAt time.Time `json:"refresh_token_expires_at"`
+}
+
+// ExpiresWithin reports whether the access token expires within d of now
+func (t *Token) ExpiresWithin(now time.Time, d time.Duration) bool {
+	return !t.ExpiresAt.IsZero() && now.Add(d).After(t.ExpiresAt)
+}
+
+// tokenResponse is the answer of the access token endpoint, either a token or an error
+type tokenResponse struct {
+	AccessToken           string `json:"access_token"`
+	TokenType             string `json:"token_type"`
+	Scope                 string `json:"scope"`
+	ExpiresIn             int    `json:"expires_in"`
+	RefreshToken          string `json:"refresh_token"`
+	RefreshTokenExpiresIn int    `json:"refresh_token_expires_in"`
+	Error                 string `json:"error"`
+	ErrorDescription      string `json:"error_description"`
+	Interval              int    `json:"interval"`
+}
+
+// Client runs the device flow and refreshes tokens against the web host of a GitHub host
+type Client struct {
+	host         string
+	webURL       *url.URL
+	clientID     string
+	clientSecret string
+	httpClient   *http.Client
+	now          func() time.Time
+	// wait pauses between polls of the access token endpoint
+	wait func(ctx context.Context, d time.Duration) error
+}
+
+// NewClient creates a client for the OAuth app with clientID on the GitHub host, as passed to
+// --gh-host. The client secret is only needed to refresh tokens of GitHub Apps that require it.
+func NewClient(host, clientID, clientSecret string) (*Client, error) {
+	if clientID == "" {
+		return nil, errors.New("an OAuth client ID is required")
+	}
+	webURL, err := WebURL(host)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{
+		host:         host,
+		webURL:       webURL,
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		httpClient:   http.DefaultClient,
+		now:          time.Now,
+		wait:         wait,
+	}, nil
+}
+
+// WebURL returns the web root of a GitHub host, where the OAuth endpoints live. An empty host,
+// github.com and its subdomains mean github.com.
+func WebURL(host string) (*url.URL, error) {
+	if host == "" {
+		return url.Parse("https://github.com/")
+	}
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse host as URL: %s", host)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("host must have a scheme (http or https): %s", host)
+	}
+	if hostname := u.Hostname(); hostname == "github.com" || strings.HasSuffix(hostname, ".github.com") {
+		return url.Parse("https://github.com/")
+	}
+	return &url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/"}, nil
+}
+
+// RequestDeviceCode starts the device flow, returning the code the user has to enter
+func (c *Client) RequestDeviceCode(ctx context.Context, scopes []string) (*DeviceCode, error) {
+	form := url.Values{"client_id": {c.clientID}, "scope": {strings.Join(scopes, " ")}}
+
+	var code DeviceCode
+	if err := c.post(ctx, "login/device/code", form, &code); err != nil {
+		return nil, fmt.Errorf("failed to request device code: %w", err)
+	}
+	if code.DeviceCode == "" || code.UserCode == "" {
+		return nil, errors.New("failed to request device code: the response has no code")
+	}
+	return &code, nil
+}
+
+// PollToken waits until the user has authorized the device code and returns the token.
+// It fails with ErrAccessDenied if the user declines, and ErrExpiredCode if the code expires.
+func (c *Client) PollToken(ctx context.Context, code *DeviceCode) (*Token, error) {
+	interval := time.Duration(code.Interval) * time.Second
+	expired := func() bool { return false }
+	if code.ExpiresIn > 0 {
+		deadline := c.now().Add(time.Duration(code.ExpiresIn) * time.Second)
+		expired = func() bool { return c.now().After(deadline) }
+	}
+	form := url.Values{
+		"client_id":   {c.clientID},
+		"device_code": {code.DeviceCode},
+		"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+	}
+
+	for {
+		if err := c.wait(ctx, interval); err != nil {
+			return nil, err
+		}
+		if expired() {
+			return nil, ErrExpiredCode
+		}
+
+		var res tokenResponse
+		if err := c.post(ctx, "login/oauth/access_token", form, &res); err != nil {
+			return nil, fmt.Errorf("failed to poll for the access token: %w", err)
+		}
+		switch res.Error {
+		case "":
+			return c.token(res), nil
+		case "authorization_pending":
+			continue
+		case "slow_down":
+			interval += slowDownIncrease
+			if res.Interval > 0 {
+				interval = time.Duration(res.Interval) * time.Second
+			}
+		case "access_denied":
+			return nil, ErrAccessDenied
+		case "expired_token":
+			return nil, ErrExpiredCode
+		default:
+			return nil, fmt.Errorf("failed to poll for the access token: %s: %s", res.Error, res.ErrorDescription)
+		}
+	}
+}
+
+// Refresh exchanges the refresh token of token for a new token
+func (c *Client) Refresh(ctx context.Context, token *Token) (*Token, error) {
+	if token.RefreshToken == "" {
+		return nil, errors.New("the token cannot be refreshed, log in again")
+	}
+	if !token.RefreshTokenExpiresAt.IsZero() && c.now().After(token.RefreshTokenExpiresAt) {
+		return nil, errors.New("the refresh token expired, log in again")
+	}
+
+	form := url.Values{
+		"client_id":     {c.clientID},
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {token.RefreshToken},
+	}
+	if c.clientSecret != "" {
+		form.Set("client_secret", c.clientSecret)
+	}
+
+	var res tokenResponse
+	if err := c.post(ctx, "login/oauth/access_token", form, &res); err != nil {
+		return nil, fmt.Errorf("failed to refresh the access token: %w", err)
+	}
+	if res.Error != "" {
+		return nil, fmt.Errorf("failed to refresh the access token: %s: %s", res.Error, res.ErrorDescription)
+	}
+	return c.token(res), nil
+}
+
+// token converts a successful token response
+func (c *Client) token(res tokenResponse) *Token {
+	now := c.now()
+	token := &Token{
+		Host:         c.host,
+		ClientID:     c.clientID,
+		AccessToken:  res.AccessToken,
+		TokenType:    res.TokenType,
+		Scope:        res.Scope,
+		RefreshToken: res.RefreshToken,
+	}
+	if res.ExpiresIn > 0 {
+		token.ExpiresAt = now.Add(time.Duration(res.ExpiresIn) * time.Second).UTC()
+	}
+	if res.RefreshTokenExpiresIn > 0 {
+		token.RefreshTokenExpiresAt = now.Add(time.Duration(res.RefreshTokenExpiresIn) * time.Second).UTC()
+	}
+	return token
+}
+
+// post sends form to the endpoint of the web host and decodes the JSON answer into v
+func (c *Client) post(ctx context.Context, endpoint string, form url.Values, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.webURL.JoinPath(endpoint).String(), strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	return nil
+}
+
+// wait sleeps for d or until ctx is done
+func wait(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,358 @@
+package oauth
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeGitHub serves the OAuth endpoints, answering access token requests in turn with responses
+func fakeGitHub(t *testing.T, responses ...map[string]any) (*httptest.Server, *[]map[string]string) {
+	t.Helper()
+	var mu sync.Mutex
+	var requests []map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "application/json", r.Header.Get("Accept"))
+		form := map[string]string{"path": r.URL.Path}
+		for k := range r.PostForm {
+			form[k] = r.PostForm.Get(k)
+		}
+		mu.Lock()
+		requests = append(requests, form)
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/login/device/code":
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"device_code":      "dc123",
+				"user_code":        "ABCD-1234",
+				"verification_uri": "https://github.com/login/device",
+				"expires_in":       900,
+				"interval":         5,
+			})
+		case "/login/oauth/access_token":
+			mu.Lock()
+			defer mu.Unlock()
+			require.NotEmpty(t, responses, "unexpected access token request")
+			_ = json.NewEncoder(w).Encode(responses[0])
+			responses = responses[1:]
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func testClient(t *testing.T, host string, now *time.Time, waits *[]time.Duration) *Client {
+	t.Helper()
+	client, err := NewClient(host, "Iv1.client", "")
+	require.NoError(t, err)
+	client.now = func() time.Time { return *now }
+	client.wait = func(_ context.Context, d time.Duration) error {
+		*waits = append(*waits, d)
+		*now = now.Add(d)
+		return nil
+	}
+	return client
+}
+
+func TestWebURL(t *testing.T) {
+	tests := []struct {
+		host     string
+		expected string
+	}{
+		{"", "https://github.com/"},
+		{"https://github.com", "https://github.com/"},
+		{"https://api.github.com", "https://github.com/"},
+		{"https://github.example.com", "https://github.example.com/"},
+		{"https://notgithub.com", "https://notgithub.com/"},
+		{"http://localhost:8080", "http://localhost:8080/"},
+	}
+	for _, tc := range tests {
+		u, err := WebURL(tc.host)
+		require.NoError(t, err)
+		assert.Equal(t, tc.expected, u.String(), tc.host)
+	}
+
+	_, err := WebURL("github.example.com")
+	assert.ErrorContains(t, err, "host must have a scheme")
+}
+
+func TestDeviceFlow(t *testing.T) {
+	server, requests := fakeGitHub(t,
+		map[string]any{"error": "authorization_pending"},
+		map[string]any{"error": "slow_down"},
+		map[string]any{
+			"access_token":             "ghu_access",
+			"token_type":               "bearer",
+			"expires_in":               28800,
+			"refresh_token":            "ghr_refresh",
+			"refresh_token_expires_in": 15897600,
+		},
+	)
+	now := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	var waits []time.Duration
+	client := testClient(t, server.URL, &now, &waits)
+
+	code, err := client.RequestDeviceCode(context.Background(), []string{"repo", "read:org"})
+	require.NoError(t, err)
+	assert.Equal(t, "ABCD-1234", code.UserCode)
+	assert.Equal(t, "https://github.com/login/device", code.VerificationURI)
+	assert.Equal(t, "repo read:org", (*requests)[0]["scope"])
+	assert.Equal(t, "Iv1.client", (*requests)[0]["client_id"])
+
+	token, err := client.PollToken(context.Background(), code)
+	require.NoError(t, err)
+	assert.Equal(t, []time.Duration{5 * time.Second, 5 * time.Second, 10 * time.Second}, waits)
+	assert.Equal(t, "dc123", (*requests)[1]["device_code"])
+	assert.Equal(t, "urn:ietf:params:oauth:grant-type:device_code", (*requests)[1]["grant_type"])
+
+	assert.Equal(t, &Token{
+		Host:                  server.URL,
+		ClientID:              "Iv1.client",
+		AccessToken:           "ghu_access",
+		TokenType:             "bearer",
+		ExpiresAt:             now.Add(8 * time.Hour),
+		RefreshToken:          "ghr_refresh",
+		RefreshTokenExpiresAt: now.Add(15897600 * time.Second),
+	}, token)
+}
+
+func TestDeviceFlow_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		response map[string]any
+		expected error
+	}{
+		{"denied", map[string]any{"error": "access_denied"}, ErrAccessDenied},
+		{"expired", map[string]any{"error": "expired_token"}, ErrExpiredCode},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			server, _ := fakeGitHub(t, tc.response)
+			now := time.Now()
+			var waits []time.Duration
+			client := testClient(t, server.URL, &now, &waits)
+
+			_, err := client.PollToken(context.Background(), &DeviceCode{DeviceCode: "dc123", ExpiresIn: 900, Interval: 5})
+			assert.ErrorIs(t, err, tc.expected)
+		})
+	}
+
+	t.Run("code expires while pending", func(t *testing.T) {
+		server, _ := fakeGitHub(t, map[string]any{"error": "authorization_pending"})
+		now := time.Now()
+		var waits []time.Duration
+		client := testClient(t, server.URL, &now, &waits)
+
+		_, err := client.PollToken(context.Background(), &DeviceCode{DeviceCode: "dc123", ExpiresIn: 8, Interval: 5})
+		assert.ErrorIs(t, err, ErrExpiredCode)
+	})
+}
+
+func TestRefresh(t *testing.T) {
+	server, requests := fakeGitHub(t, map[string]any{
+		"access_token":  "ghu_new",
+		"token_type":    "bearer",
+		"expires_in":    28800,
+		"refresh_token": "ghr_new",
+	})
+	now := time.Now()
+	var waits []time.Duration
+	client := testClient(t, server.URL, &now, &waits)
+	client.clientSecret = "secret"
+
+	token, err := client.Refresh(context.Background(), &Token{RefreshToken: "ghr_old"})
+	require.NoError(t, err)
+	assert.Equal(t, "ghu_new", token.AccessToken)
+	assert.Equal(t, "ghr_new", token.RefreshToken)
+	assert.Equal(t, map[string]string{
+		"path":          "/login/oauth/access_token",
+		"client_id":     "Iv1.client",
+		"client_secret": "secret",
+		"grant_type":    "refresh_token",
+		"refresh_token": "ghr_old",
+	}, (*requests)[0])
+
+	_, err = client.Refresh(context.Background(), &Token{})
+	assert.ErrorContains(t, err, "cannot be refreshed, log in again")
+
+	_, err = client.Refresh(context.Background(), &Token{RefreshToken: "ghr_old", RefreshTokenExpiresAt: now.Add(-time.Second)})
+	assert.ErrorContains(t, err, "refresh token expired, log in again")
+}
+
+func TestStore(t *testing.T) {
+	token := &Token{Host: "https://github.com", ClientID: "Iv1.client", AccessToken: "ghu_access", TokenType: "bearer"}
+
+	t.Run("plain", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "tokens", "github.com.json")
+		store := NewStore(path, nil)
+
+		_, err := store.Load()
+		assert.True(t, IsNotLoggedIn(err))
+
+		require.NoError(t, store.Save(token))
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+		dirInfo, err := os.Stat(filepath.Dir(path))
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0700), dirInfo.Mode().Perm())
+
+		loaded, err := store.Load()
+		require.NoError(t, err)
+		assert.Equal(t, token, loaded)
+
+		require.NoError(t, store.Delete())
+		assert.True(t, IsNotLoggedIn(store.Delete()))
+	})
+
+	t.Run("encrypted", func(t *testing.T) {
+		key := make([]byte, 32)
+		_, err := rand.Read(key)
+		require.NoError(t, err)
+		path := filepath.Join(t.TempDir(), "github.com.json")
+
+		require.NoError(t, NewStore(path, key).Save(token))
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.NotContains(t, string(data), "ghu_access")
+
+		loaded, err := NewStore(path, key).Load()
+		require.NoError(t, err)
+		assert.Equal(t, token, loaded)
+
+		_, err = NewStore(path, nil).Load()
+		assert.ErrorContains(t, err, "is encrypted, set the token encryption key")
+
+		otherKey := make([]byte, 32)
+		_, err = NewStore(path, otherKey).Load()
+		assert.ErrorContains(t, err, "failed to decrypt token file")
+	})
+}
+
+func TestParseEncryptionKey(t *testing.T) {
+	key := make([]byte, 32)
+	key[0] = 1
+	parsed, err := ParseEncryptionKey(base64.StdEncoding.EncodeToString(key) + "\n")
+	require.NoError(t, err)
+	assert.Equal(t, key, parsed)
+
+	_, err = ParseEncryptionKey("not base64!")
+	assert.ErrorContains(t, err, "not base64 encoded")
+
+	_, err = ParseEncryptionKey(base64.StdEncoding.EncodeToString(key[:16]))
+	assert.ErrorContains(t, err, "must be 32 bytes, got 16")
+}
+
+func TestTokenSource(t *testing.T) {
+	server, _ := fakeGitHub(t, map[string]any{
+		"access_token":  "ghu_new",
+		"token_type":    "bearer",
+		"expires_in":    28800,
+		"refresh_token": "ghr_new",
+	})
+	now := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	var waits []time.Duration
+
+	store := NewStore(filepath.Join(t.TempDir(), "token.json"), nil)
+	require.NoError(t, store.Save(&Token{
+		Host:         server.URL,
+		ClientID:     "Iv1.client",
+		AccessToken:  "ghu_old",
+		ExpiresAt:    now.Add(time.Hour),
+		RefreshToken: "ghr_old",
+	}))
+
+	source, err := NewTokenSource(store, "")
+	require.NoError(t, err)
+	source.now = func() time.Time { return now }
+	source.newClient = func(token *Token) (*Client, error) {
+		return testClient(t, token.Host, &now, &waits), nil
+	}
+	assert.Equal(t, server.URL, source.Host())
+
+	// The token is used while it is valid for longer than the refresh margin
+	token, err := source.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "ghu_old", token)
+
+	// and refreshed and saved once it is about to expire
+	now = now.Add(time.Hour - RefreshMargin + time.Second)
+	token, err = source.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "ghu_new", token)
+
+	saved, err := store.Load()
+	require.NoError(t, err)
+	assert.Equal(t, "ghu_new", saved.AccessToken)
+	assert.Equal(t, "ghr_new", saved.RefreshToken)
+	assert.Equal(t, server.URL, saved.Host)
+
+	_, err = NewTokenSource(NewStore(filepath.Join(t.TempDir(), "missing.json"), nil), "")
+	assert.True(t, IsNotLoggedIn(err))
+}
+
+func TestTokenSource_KeepsTokenThatCannotBeSaved(t *testing.T) {
+	server, _ := fakeGitHub(t, map[string]any{
+		"access_token":  "ghu_new",
+		"token_type":    "bearer",
+		"expires_in":    28800,
+		"refresh_token": "ghr_new",
+	})
+	now := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	var waits []time.Duration
+
+	store := NewStore(filepath.Join(t.TempDir(), "token.json"), nil)
+	require.NoError(t, store.Save(&Token{
+		Host:         server.URL,
+		ClientID:     "Iv1.client",
+		AccessToken:  "ghu_old",
+		ExpiresAt:    now.Add(time.Minute),
+		RefreshToken: "ghr_old",
+	}))
+	source, err := NewTokenSource(store, "")
+	require.NoError(t, err)
+	source.now = func() time.Time { return now }
+	source.newClient = func(token *Token) (*Client, error) {
+		return testClient(t, token.Host, &now, &waits), nil
+	}
+	var logs bytes.Buffer
+	source.SetLogger(slog.New(slog.NewTextHandler(&logs, nil)))
+
+	// The store cannot be written, because its directory is a file
+	blocker := filepath.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(blocker, nil, 0600))
+	source.store = NewStore(filepath.Join(blocker, "token.json"), nil)
+
+	// The refreshed token is used anyway, as the old refresh token no longer works
+	token, err := source.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "ghu_new", token)
+	assert.Contains(t, logs.String(), "failed to save the refreshed GitHub token")
+
+	// and saved once the store can be written again
+	source.store = store
+	token, err = source.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "ghu_new", token)
+	saved, err := store.Load()
+	require.NoError(t, err)
+	assert.Equal(t, "ghr_new", saved.RefreshToken)
+}
@@ -0,0 +1,259 @@
+package oauth
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RefreshMargin is how long before it expires a stored token is refreshed
+const RefreshMargin = 5 * time.Minute
+
+// DefaultTokenPath returns the token file of the GitHub host under the user config directory
+func DefaultTokenPath(host string) (string, error) {
+	webURL, err := WebURL(host)
+	if err != nil {
+		return "", err
+	}
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find user config directory: %w", err)
+	}
+	name := strings.NewReplacer(":", "_", "/", "_").Replace(webURL.Host)
+	return filepath.Join(configDir, "github-mcp-server", "tokens", name+".json"), nil
+}
+
+// ParseEncryptionKey decodes a base64 encoded 32 byte AES-256 key, e.g. from `openssl rand -base64 32`
+func ParseEncryptionKey(s string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("the token encryption key is not base64 encoded: %w", err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("the token encryption key must be 32 bytes, got %d", len(key))
+	}
+	return key, nil
+}
+
+// storedToken is the content of a token file: the token, or its encryption with AES-256-GCM
+type storedToken struct {
+	Token      *Token `json:"token,omitempty"`
+	Nonce      []byte `json:"nonce,omitempty"`
+	Ciphertext []byte `json:"ciphertext,omitempty"`
+}
+
+// Store keeps a token in a file readable only by the current user, encrypted if it has a key
+type Store struct {
+	path string
+	key  []byte
+}
+
+// NewStore creates a store for the token file at path. A nil key stores the token in plain text.
+func NewStore(path string, key []byte) *Store {
+	return &Store{path: path, key: key}
+}
+
+// Path returns the token file of the store
+func (s *Store) Path() string {
+	return s.path
+}
+
+// Save writes the token, replacing the file atomically
+func (s *Store) Save(token *Token) error {
+	stored := storedToken{Token: token}
+	if s.key != nil {
+		plaintext, err := json.Marshal(token)
+		if err != nil {
+			return fmt.Errorf("failed to marshal token: %w", err)
+		}
+		gcm, err := newGCM(s.key)
+		if err != nil {
+			return err
+		}
+		nonce := make([]byte, gcm.NonceSize())
+		if _, err := rand.Read(nonce); err != nil {
+			return fmt.Errorf("failed to generate nonce: %w", err)
+		}
+		stored = storedToken{Nonce: nonce, Ciphertext: gcm.Seal(nil, nonce, plaintext, nil)}
+	}
+
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return fmt.Errorf("failed to marshal token: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return fmt.Errorf("failed to create token directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".token-*")
+	if err != nil {
+		return fmt.Errorf("failed to save token: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if err := tmp.Chmod(0600); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to save token: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to save token: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save token: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to save token: %w", err)
+	}
+	return nil
+}
+
+// Load reads the token. The error wraps os.ErrNotExist if no token was saved.
+func (s *Store) Load() (*Token, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, err
+	}
+	var stored storedToken
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, fmt.Errorf("failed to parse token file %s: %w", s.path, err)
+	}
+
+	if stored.Ciphertext == nil {
+		if stored.Token == nil {
+			return nil, fmt.Errorf("token file %s has no token", s.path)
+		}
+		return stored.Token, nil
+	}
+	if s.key == nil {
+		return nil, fmt.Errorf("token file %s is encrypted, set the token encryption key", s.path)
+	}
+	gcm, err := newGCM(s.key)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := gcm.Open(nil, stored.Nonce, stored.Ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt token file %s, is the encryption key right?", s.path)
+	}
+	var token Token
+	if err := json.Unmarshal(plaintext, &token); err != nil {
+		return nil, fmt.Errorf("failed to parse token file %s: %w", s.path, err)
+	}
+	return &token, nil
+}
+
+// Delete removes the token file. The error wraps os.ErrNotExist if no token was saved.
+func (s *Store) Delete() error {
+	return os.Remove(s.path)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid token encryption key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// TokenSource provides the access token of a store, refreshing the token and saving the
+// refreshed token when it is about to expire. It is safe for concurrent use.
+type TokenSource struct {
+	store *Store
+	now   func() time.Time
+	// newClient creates the client refreshing the token
+	newClient func(token *Token) (*Client, error)
+
+	mu     sync.Mutex
+	token  *Token
+	logger *slog.Logger
+	// unsaved is set while the token in memory could not be saved to the store
+	unsaved bool
+}
+
+// NewTokenSource loads the token of the store. clientSecret is passed on when refreshing it.
+func NewTokenSource(store *Store, clientSecret string) (*TokenSource, error) {
+	token, err := store.Load()
+	if err != nil {
+		return nil, err
+	}
+	return &TokenSource{
+		store: store,
+		now:   time.Now,
+		newClient: func(token *Token) (*Client, error) {
+			return NewClient(token.Host, token.ClientID, clientSecret)
+		},
+		token:  token,
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}, nil
+}
+
+// SetLogger sets the logger used to report refreshed tokens that could not be saved
+func (s *TokenSource) SetLogger(logger *slog.Logger) {
+	if logger == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.logger = logger
+}
+
+// Host returns the GitHub host the token was issued by
+func (s *TokenSource) Host() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.token.Host
+}
+
+// Token returns an access token valid for at least RefreshMargin, refreshing it if needed.
+// GitHub replaces the refresh token on every refresh, so a refreshed token that cannot be
+// saved is still used, and saving it is retried on later calls.
+func (s *TokenSource) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.token.ExpiresWithin(s.now(), RefreshMargin) {
+		if s.unsaved {
+			s.save()
+		}
+		return s.token.AccessToken, nil
+	}
+
+	client, err := s.newClient(s.token)
+	if err != nil {
+		return "", err
+	}
+	refreshed, err := client.Refresh(ctx, s.token)
+	if err != nil {
+		return "", err
+	}
+	s.token = refreshed
+	s.save()
+	return s.token.AccessToken, nil
+}
+
+// save saves the token in memory, logging a failure instead of returning it.
+// The caller must hold s.mu.
+func (s *TokenSource) save() {
+	if err := s.store.Save(s.token); err != nil {
+		s.unsaved = true
+		s.logger.Warn("failed to save the refreshed GitHub token, the stored login stops working once the server exits", "error", err)
+		return
+	}
+	s.unsaved = false
+}
+
+// IsNotLoggedIn reports whether err means no token was saved
+func IsNotLoggedIn(err error) bool {
+	return errors.Is(err, os.ErrNotExist)
+}
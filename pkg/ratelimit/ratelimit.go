@@ -0,0 +1,352 @@
+// Package ratelimit retries GitHub API requests that hit a rate limit or a transient server
+// error, waiting as long as GitHub asks within the budget of the tool call, and logs when the
+// remaining quota runs low.
+package ratelimit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/github/github-mcp-server/pkg/toolsets"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// DefaultBudget is how long a tool call may wait for rate limits and retries
+	DefaultBudget = 30 * time.Second
+
+	// DefaultMaxRetries is how often a request is retried
+	DefaultMaxRetries = 3
+
+	// secondaryLimitWait is how long to wait after a secondary rate limit that does not say
+	// when to retry, GitHub asks for at least a minute
+	secondaryLimitWait = time.Minute
+
+	// initialBackoff is the delay before the first retry of a transient server error, doubled
+	// after each attempt
+	initialBackoff = time.Second
+
+	// resetMargin is added to the reset time of a primary rate limit to allow for clock drift
+	resetMargin = time.Second
+
+	// lowQuotaFraction is the fraction of the limit below which the remaining quota is logged
+	lowQuotaFraction = 0.1
+)
+
+// Limit is the quota of a rate limit resource, as reported by GitHub
+type Limit struct {
+	Resource  string
+	Limit     int
+	Remaining int
+	Used      int
+	Reset     time.Time
+}
+
+// Transport is a http.RoundTripper that retries requests rejected by primary or secondary
+// rate limits and, for idempotent requests, transient 502, 503 and 504 errors. It waits as
+// long as GitHub asks, within the budget of the tool call in the request context or, outside
+// of tool calls, the budget of the transport. It is safe for concurrent use.
+type Transport struct {
+	transport  http.RoundTripper
+	budget     time.Duration
+	maxRetries int
+	logger     *slog.Logger
+	now        func() time.Time
+	sleep      func(ctx context.Context, d time.Duration) error
+
+	mu sync.Mutex
+	// warned is the reset time of the window a low quota was last logged for, by resource
+	warned map[string]time.Time
+}
+
+// NewTransport wraps transport, waiting at most budget per request outside of tool calls.
+// A nil logger discards the log.
+func NewTransport(transport http.RoundTripper, budget time.Duration, logger *slog.Logger) *Transport {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+	return &Transport{
+		transport:  transport,
+		budget:     budget,
+		maxRetries: DefaultMaxRetries,
+		logger:     logger,
+		now:        time.Now,
+		sleep:      sleep,
+		warned:     make(map[string]time.Time),
+	}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	b := budgetFrom(req.Context())
+	if b == nil {
+		b = newBudget(t.budget)
+	}
+
+	attemptReq := req
+	for attempt := 0; ; attempt++ {
+		resp, err := t.transport.RoundTrip(attemptReq)
+		if err != nil {
+			return nil, err
+		}
+
+		wait, reason := t.inspect(attemptReq, resp, attempt)
+		if reason == "" {
+			return resp, nil
+		}
+		if attempt >= t.maxRetries || !replayable(req) {
+			t.logger.Warn("giving up on GitHub request", "reason", reason, "method", req.Method, "path", req.URL.Path, "attempts", attempt+1)
+			return resp, nil
+		}
+		if !b.take(wait) {
+			t.logger.Warn("not waiting for GitHub, the wait exceeds the budget of the call", "reason", reason, "method", req.Method, "path", req.URL.Path, "wait", wait)
+			return resp, nil
+		}
+
+		t.logger.Warn("waiting for GitHub before retrying", "reason", reason, "method", req.Method, "path", req.URL.Path, "wait", wait, "attempt", attempt+1)
+		trace.SpanFromContext(req.Context()).AddEvent("waiting for GitHub before retrying", trace.WithAttributes(
+			attribute.String("reason", reason),
+			attribute.Int64("wait_ms", wait.Milliseconds()),
+			attribute.Int("attempt", attempt+1),
+		))
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+		if err := t.sleep(req.Context(), wait); err != nil {
+			return nil, err
+		}
+
+		attemptReq, err = rewind(req)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// inspect records the quota reported by resp and returns how long to wait before retrying
+// the request, with the reason, or an empty reason if the response is final
+func (t *Transport) inspect(req *http.Request, resp *http.Response, attempt int) (time.Duration, string) {
+	limit, hasLimit := parseLimit(resp.Header)
+
+	var body []byte
+	graphql := req.Method == http.MethodPost && strings.HasSuffix(req.URL.Path, "/graphql")
+	if graphql || resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusTooManyRequests {
+		// The body tells rate limits from other errors, so keep a copy for the caller
+		body, _ = io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	if hasLimit {
+		t.record(limit)
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusTooManyRequests:
+		if retryAfter, ok := parseRetryAfter(resp.Header); ok {
+			return retryAfter, "secondary rate limit"
+		}
+		if hasLimit && limit.Remaining == 0 {
+			return t.untilReset(limit), "primary rate limit"
+		}
+		if resp.StatusCode == http.StatusTooManyRequests || isSecondaryLimit(body) {
+			return secondaryLimitWait, "secondary rate limit"
+		}
+	case resp.StatusCode == http.StatusOK && graphql && isGraphQLRateLimited(body):
+		if hasLimit {
+			return t.untilReset(limit), "graphql rate limit"
+		}
+		return secondaryLimitWait, "graphql rate limit"
+	case resp.StatusCode == http.StatusBadGateway || resp.StatusCode == http.StatusServiceUnavailable || resp.StatusCode == http.StatusGatewayTimeout:
+		if !idempotent(req.Method) {
+			return 0, ""
+		}
+		if retryAfter, ok := parseRetryAfter(resp.Header); ok {
+			return retryAfter, resp.Status
+		}
+		return initialBackoff << attempt, resp.Status
+	}
+	return 0, ""
+}
+
+// record logs the quota of a resource once per window when it runs low
+func (t *Transport) record(limit Limit) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	low := limit.Limit > 0 && float64(limit.Remaining) < float64(limit.Limit)*lowQuotaFraction
+	if low && !t.warned[limit.Resource].Equal(limit.Reset) {
+		t.warned[limit.Resource] = limit.Reset
+		t.logger.Warn("GitHub rate limit running low", "resource", limit.Resource, "remaining", limit.Remaining, "limit", limit.Limit, "reset", limit.Reset)
+	}
+}
+
+// untilReset returns how long until the quota of limit is reset
+func (t *Transport) untilReset(limit Limit) time.Duration {
+	wait := limit.Reset.Sub(t.now()) + resetMargin
+	if wait < 0 {
+		return 0
+	}
+	return wait
+}
+
+// parseLimit reads the X-RateLimit-* headers of a response
+func parseLimit(header http.Header) (Limit, bool) {
+	limit, err := strconv.Atoi(header.Get("X-RateLimit-Limit"))
+	if err != nil {
+		return Limit{}, false
+	}
+	remaining, _ := strconv.Atoi(header.Get("X-RateLimit-Remaining"))
+	used, _ := strconv.Atoi(header.Get("X-RateLimit-Used"))
+	reset, _ := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64)
+	resource := header.Get("X-RateLimit-Resource")
+	if resource == "" {
+		resource = "core"
+	}
+	return Limit{
+		Resource:  resource,
+		Limit:     limit,
+		Remaining: remaining,
+		Used:      used,
+		Reset:     time.Unix(reset, 0),
+	}, true
+}
+
+// parseRetryAfter reads the Retry-After header in seconds
+func parseRetryAfter(header http.Header) (time.Duration, bool) {
+	seconds, err := strconv.Atoi(header.Get("Retry-After"))
+	if err != nil || seconds < 0 {
+		return 0, false
+	}
+	return time.Duration(seconds) * time.Second, true
+}
+
+// isSecondaryLimit reports whether a 403 body is a secondary rate limit or abuse detection
+// rather than missing permissions
+func isSecondaryLimit(body []byte) bool {
+	var res struct {
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(body, &res); err != nil {
+		return false
+	}
+	message := strings.ToLower(res.Message)
+	return strings.Contains(message, "secondary rate limit") || strings.Contains(message, "abuse")
+}
+
+// graphQLResponse holds the parts of a GraphQL response about rate limits
+type graphQLResponse struct {
+	Errors []struct {
+		Type string `json:"type"`
+	} `json:"errors"`
+}
+
+// isGraphQLRateLimited reports whether a GraphQL response failed on the rate limit, which
+// GitHub reports with a 200 status
+func isGraphQLRateLimited(body []byte) bool {
+	var res graphQLResponse
+	if err := json.Unmarshal(body, &res); err != nil {
+		return false
+	}
+	for _, e := range res.Errors {
+		if e.Type == "RATE_LIMITED" {
+			return true
+		}
+	}
+	return false
+}
+
+// idempotent reports whether a request with method can be repeated after a server error
+// without risking to apply it twice
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// replayable reports whether the body of req can be sent again
+func replayable(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// rewind returns a copy of req with a fresh body to send it again
+func rewind(req *http.Request) (*http.Request, error) {
+	clone := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		clone.Body = body
+	}
+	return clone, nil
+}
+
+// sleep waits for d or until ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// budget is the time left to wait for GitHub, shared by the requests of a tool call
+type budget struct {
+	mu        sync.Mutex
+	remaining time.Duration
+}
+
+type budgetKey struct{}
+
+func newBudget(d time.Duration) *budget {
+	return &budget{remaining: d}
+}
+
+// take spends d of the budget, and reports false without spending anything if not enough is left
+func (b *budget) take(d time.Duration) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if d > b.remaining {
+		return false
+	}
+	b.remaining -= d
+	return true
+}
+
+// ContextWithBudget returns a context whose GitHub requests wait at most d in total
+func ContextWithBudget(ctx context.Context, d time.Duration) context.Context {
+	return context.WithValue(ctx, budgetKey{}, newBudget(d))
+}
+
+func budgetFrom(ctx context.Context) *budget {
+	b, _ := ctx.Value(budgetKey{}).(*budget)
+	return b
+}
+
+// Middleware gives every tool call a budget of d to wait for rate limits and retries, shared
+// by all GitHub requests the call makes
+func Middleware(d time.Duration) toolsets.ToolMiddleware {
+	return func(_ mcp.Tool, next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return next(ContextWithBudget(ctx, d), request)
+		}
+	}
+}
@@ -0,0 +1,256 @@
+package ratelimit
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// response is a canned answer of the fake GitHub API
+type response struct {
+	status int
+	header map[string]string
+	body   string
+}
+
+// fakeAPI answers requests in turn with responses and records the bodies it received
+func fakeAPI(t *testing.T, responses ...response) (*httptest.Server, *[]string) {
+	t.Helper()
+	var mu sync.Mutex
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		bodies = append(bodies, string(body))
+		require.NotEmpty(t, responses, "unexpected request")
+		res := responses[0]
+		responses = responses[1:]
+		for k, v := range res.header {
+			w.Header().Set(k, v)
+		}
+		w.WriteHeader(res.status)
+		_, _ = w.Write([]byte(res.body))
+	}))
+	t.Cleanup(server.Close)
+	return server, &bodies
+}
+
+// testTransport returns a transport that records its waits instead of sleeping
+func testTransport(budget time.Duration, now time.Time, logs io.Writer) (*Transport, *[]time.Duration) {
+	var waits []time.Duration
+	transport := NewTransport(http.DefaultTransport, budget, slog.New(slog.NewTextHandler(logs, nil)))
+	transport.now = func() time.Time { return now }
+	transport.sleep = func(_ context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return nil
+	}
+	return transport, &waits
+}
+
+func TestTransport_Retries(t *testing.T) {
+	now := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	reset := strconv.FormatInt(now.Add(10*time.Second).Unix(), 10)
+
+	tests := []struct {
+		name          string
+		method        string
+		path          string
+		responses     []response
+		expectedWaits []time.Duration
+		expectedCode  int
+	}{
+		{
+			name:   "primary rate limit waits until the reset",
+			method: http.MethodGet,
+			responses: []response{
+				{status: http.StatusForbidden, header: map[string]string{"X-RateLimit-Limit": "5000", "X-RateLimit-Remaining": "0", "X-RateLimit-Reset": reset}, body: `{"message":"API rate limit exceeded"}`},
+				{status: http.StatusOK, body: `{}`},
+			},
+			expectedWaits: []time.Duration{11 * time.Second},
+			expectedCode:  http.StatusOK,
+		},
+		{
+			name:   "secondary rate limit waits for Retry-After",
+			method: http.MethodPost,
+			responses: []response{
+				{status: http.StatusForbidden, header: map[string]string{"Retry-After": "3"}, body: `{"message":"You have exceeded a secondary rate limit"}`},
+				{status: http.StatusCreated, body: `{}`},
+			},
+			expectedWaits: []time.Duration{3 * time.Second},
+			expectedCode:  http.StatusCreated,
+		},
+		{
+			name:   "abuse detection without Retry-After waits a minute",
+			method: http.MethodGet,
+			responses: []response{
+				{status: http.StatusForbidden, body: `{"message":"You have triggered an abuse detection mechanism"}`},
+				{status: http.StatusOK, body: `{}`},
+			},
+			expectedWaits: []time.Duration{time.Minute},
+			expectedCode:  http.StatusOK,
+		},
+		{
+			name:   "missing permissions are not retried",
+			method: http.MethodGet,
+			responses: []response{
+				{status: http.StatusForbidden, header: map[string]string{"X-RateLimit-Limit": "5000", "X-RateLimit-Remaining": "4000"}, body: `{"message":"Resource not accessible by integration"}`},
+			},
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:   "transient errors back off exponentially",
+			method: http.MethodGet,
+			responses: []response{
+				{status: http.StatusBadGateway},
+				{status: http.StatusServiceUnavailable},
+				{status: http.StatusOK, body: `{}`},
+			},
+			expectedWaits: []time.Duration{time.Second, 2 * time.Second},
+			expectedCode:  http.StatusOK,
+		},
+		{
+			name:   "transient errors of non-idempotent requests are not retried",
+			method: http.MethodPost,
+			responses: []response{
+				{status: http.StatusBadGateway},
+			},
+			expectedCode: http.StatusBadGateway,
+		},
+		{
+			name:   "retries are limited",
+			method: http.MethodGet,
+			responses: []response{
+				{status: http.StatusServiceUnavailable, header: map[string]string{"Retry-After": "1"}},
+				{status: http.StatusServiceUnavailable, header: map[string]string{"Retry-After": "1"}},
+				{status: http.StatusServiceUnavailable, header: map[string]string{"Retry-After": "1"}},
+				{status: http.StatusServiceUnavailable, header: map[string]string{"Retry-After": "1"}},
+			},
+			expectedWaits: []time.Duration{time.Second, time.Second, time.Second},
+			expectedCode:  http.StatusServiceUnavailable,
+		},
+		{
+			name:   "GraphQL rate limit errors wait until the reset",
+			method: http.MethodPost,
+			path:   "/graphql",
+			responses: []response{
+				{status: http.StatusOK, header: map[string]string{"X-RateLimit-Limit": "5000", "X-RateLimit-Remaining": "0", "X-RateLimit-Reset": reset, "X-RateLimit-Resource": "graphql"}, body: `{"errors":[{"type":"RATE_LIMITED","message":"API rate limit exceeded"}]}`},
+				{status: http.StatusOK, body: `{"data":{}}`},
+			},
+			expectedWaits: []time.Duration{11 * time.Second},
+			expectedCode:  http.StatusOK,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			server, bodies := fakeAPI(t, tc.responses...)
+			transport, waits := testTransport(time.Hour, now, io.Discard)
+
+			req, err := http.NewRequest(tc.method, server.URL+tc.path, bytes.NewBufferString(`{"title":"retried"}`))
+			require.NoError(t, err)
+			resp, err := transport.RoundTrip(req)
+			require.NoError(t, err)
+			defer func() { _ = resp.Body.Close() }()
+
+			assert.Equal(t, tc.expectedCode, resp.StatusCode)
+			assert.Equal(t, tc.expectedWaits, *waits)
+			for _, body := range *bodies {
+				assert.Equal(t, `{"title":"retried"}`, body, "every attempt sends the full body")
+			}
+		})
+	}
+}
+
+func TestTransport_Budget(t *testing.T) {
+	now := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	limited := response{status: http.StatusTooManyRequests, header: map[string]string{"Retry-After": "20"}, body: `{"message":"You have exceeded a secondary rate limit"}`}
+
+	// Requests of the same tool call share its budget
+	server, _ := fakeAPI(t, limited, response{status: http.StatusOK}, limited)
+	transport, waits := testTransport(time.Hour, now, io.Discard)
+	ctx := ContextWithBudget(context.Background(), 30*time.Second)
+
+	for _, expected := range []int{http.StatusOK, http.StatusTooManyRequests} {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+		require.NoError(t, err)
+		resp, err := transport.RoundTrip(req)
+		require.NoError(t, err)
+		assert.Equal(t, expected, resp.StatusCode)
+
+		// The body of the final response is still readable
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		if expected == http.StatusTooManyRequests {
+			assert.Contains(t, string(body), "secondary rate limit")
+		}
+	}
+	assert.Equal(t, []time.Duration{20 * time.Second}, *waits)
+
+	// A zero budget returns rate limits right away
+	server, _ = fakeAPI(t, limited)
+	transport, waits = testTransport(0, now, io.Discard)
+	req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+	resp, err := transport.RoundTrip(req)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Empty(t, *waits)
+}
+
+func TestTransport_LogsLowQuota(t *testing.T) {
+	now := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	reset := now.Add(time.Hour).Truncate(time.Second)
+	quota := func(remaining int) map[string]string {
+		return map[string]string{
+			"X-RateLimit-Limit":     "5000",
+			"X-RateLimit-Remaining": strconv.Itoa(remaining),
+			"X-RateLimit-Used":      strconv.Itoa(5000 - remaining),
+			"X-RateLimit-Reset":     strconv.FormatInt(reset.Unix(), 10),
+			"X-RateLimit-Resource":  "graphql",
+		}
+	}
+	server, _ := fakeAPI(t,
+		response{status: http.StatusOK, header: quota(600), body: `{"data":{}}`},
+		response{status: http.StatusOK, header: quota(400), body: `{"data":{}}`},
+		response{status: http.StatusOK, header: quota(300), body: `{"data":{}}`},
+	)
+	var logs bytes.Buffer
+	transport, _ := testTransport(time.Hour, now, &logs)
+
+	for i := 0; i < 3; i++ {
+		req, err := http.NewRequest(http.MethodPost, server.URL+"/graphql", strings.NewReader(`{"query":"{viewer{login}}"}`))
+		require.NoError(t, err)
+		resp, err := transport.RoundTrip(req)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+	}
+
+	// A low quota is logged once per window
+	assert.Equal(t, 1, strings.Count(logs.String(), "GitHub rate limit running low"))
+}
+
+func TestMiddleware(t *testing.T) {
+	var found bool
+	handler := Middleware(time.Minute)(mcp.Tool{}, func(ctx context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		b := budgetFrom(ctx)
+		found = b != nil && b.remaining == time.Minute
+		return mcp.NewToolResultText("ok"), nil
+	})
+	_, err := handler(context.Background(), mcp.CallToolRequest{})
+	require.NoError(t, err)
+	assert.True(t, found, "the tool call context carries the budget")
+}
@@ -0,0 +1,215 @@
+package raw
+
+import (
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultCacheMaxBytes is the default size of the content cache
+const DefaultCacheMaxBytes = 64 * 1024 * 1024
+
+// maxEntryFraction limits a single entry to this fraction of the cache, so one large file
+// cannot evict everything else
+const maxEntryFraction = 8
+
+// cacheFileSuffix names the files of the disk backing
+const cacheFileSuffix = ".content"
+
+// CacheConfig sizes the content cache and selects its optional disk backing
+type CacheConfig struct {
+	// MaxBytes is the total size of the cached content, zero disables the cache
+	MaxBytes int64
+
+	// Dir persists the cached content across restarts, empty keeps it in memory only. The
+	// content may come from private repositories, so the directory is only readable by the
+	// current user.
+	Dir string
+}
+
+// CacheStats counts how the content cache answered reads
+type CacheStats struct {
+	// Hits are reads answered from the cache without calling GitHub
+	Hits int64 `json:"hits"`
+	// Misses are reads pinned to a commit SHA that had to call GitHub
+	Misses int64 `json:"misses"`
+	// Evictions are entries removed to make room for newer ones
+	Evictions int64 `json:"evictions"`
+	// Entries and Bytes are the number and size of the cached entries
+	Entries int   `json:"entries"`
+	Bytes   int64 `json:"bytes"`
+}
+
+// Cache keeps what was read at a full commit SHA: raw file content, blob SHAs and trees.
+// Content at a commit never changes, so it is served again without calling GitHub. Entries
+// are content addressed by a hash of the identity that read them, the commit SHA and what was
+// read. It is safe for concurrent use.
+type Cache struct {
+	maxBytes      int64
+	maxEntryBytes int64
+	dir           string
+
+	mu      sync.Mutex
+	lru     *list.List // of *cacheEntry, most recently used first
+	entries map[string]*list.Element
+	bytes   int64
+	stats   CacheStats
+}
+
+type cacheEntry struct {
+	key  string
+	data []byte
+}
+
+// NewCache creates a content cache, loading the entries persisted in config.Dir if it is set
+func NewCache(config CacheConfig) (*Cache, error) {
+	c := &Cache{
+		maxBytes:      config.MaxBytes,
+		maxEntryBytes: config.MaxBytes / maxEntryFraction,
+		dir:           config.Dir,
+		lru:           list.New(),
+		entries:       make(map[string]*list.Element),
+	}
+	if c.dir == "" {
+		return c, nil
+	}
+	if err := os.MkdirAll(c.dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create content cache directory: %w", err)
+	}
+	if err := c.loadDir(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Stats returns the current counters of the cache
+func (c *Cache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Entries = c.lru.Len()
+	stats.Bytes = c.bytes
+	return stats
+}
+
+// load decodes the entry of key into v and reports whether there was one, counting a hit or
+// a miss. An entry that cannot be decoded is dropped.
+func (c *Cache) load(key string, v any) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.entries[key]
+	if ok && json.Unmarshal(element.Value.(*cacheEntry).data, v) == nil {
+		c.lru.MoveToFront(element)
+		c.stats.Hits++
+		return true
+	}
+	if ok {
+		c.remove(element)
+	}
+	c.stats.Misses++
+	return false
+}
+
+// store encodes v as the entry of key, evicting the least recently used entries until it fits
+func (c *Cache) store(key string, v any) {
+	data, err := json.Marshal(v)
+	if err != nil || int64(len(data)) > c.maxEntryBytes {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.entries[key]; ok {
+		c.remove(element)
+	}
+	c.entries[key] = c.lru.PushFront(&cacheEntry{key: key, data: data})
+	c.bytes += int64(len(data))
+	c.persist(key, data)
+	for c.bytes > c.maxBytes {
+		c.remove(c.lru.Back())
+		c.stats.Evictions++
+	}
+}
+
+// remove drops the entry from memory and disk, the lock must be held
+func (c *Cache) remove(element *list.Element) {
+	e := element.Value.(*cacheEntry)
+	c.lru.Remove(element)
+	delete(c.entries, e.key)
+	c.bytes -= int64(len(e.data))
+	if c.dir != "" {
+		_ = os.Remove(c.path(e.key))
+	}
+}
+
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.dir, key+cacheFileSuffix)
+}
+
+// persist writes the entry to the disk backing if there is one, the lock must be held. An
+// entry that cannot be written is only cached in memory.
+func (c *Cache) persist(key string, data []byte) {
+	if c.dir == "" {
+		return
+	}
+	tmp, err := os.CreateTemp(c.dir, ".entry-*")
+	if err != nil {
+		return
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err != nil || closeErr != nil {
+		return
+	}
+	_ = os.Rename(tmp.Name(), c.path(key))
+}
+
+// loadDir reads the persisted entries, most recently written first, dropping those that no
+// longer fit
+func (c *Cache) loadDir() error {
+	files, err := os.ReadDir(c.dir)
+	if err != nil {
+		return fmt.Errorf("failed to list content cache directory: %w", err)
+	}
+
+	type persisted struct {
+		key  string
+		info os.FileInfo
+	}
+	var entries []persisted
+	for _, file := range files {
+		key, ok := strings.CutSuffix(file.Name(), cacheFileSuffix)
+		if !ok || file.IsDir() {
+			continue
+		}
+		info, err := file.Info()
+		if err != nil {
+			continue
+		}
+		entries = append(entries, persisted{key: key, info: info})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].info.ModTime().After(entries[j].info.ModTime())
+	})
+
+	for _, e := range entries {
+		size := e.info.Size()
+		if size > c.maxEntryBytes || c.bytes+size > c.maxBytes {
+			_ = os.Remove(c.path(e.key))
+			continue
+		}
+		data, err := os.ReadFile(c.path(e.key))
+		if err != nil {
+			continue
+		}
+		// Entries are loaded newest first, so each goes behind the ones loaded before
+		c.entries[e.key] = c.lru.PushBack(&cacheEntry{key: e.key, data: data})
+		c.bytes += int64(len(data))
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package raw
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-github/v74/github"
+	"github.com/migueleliasweb/go-github-mock/src/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const commitSHA = "0123456789abcdef0123456789abcdef01234567"
+
+// countingClient serves a file through the raw, contents and trees endpoints and counts the
+// requests of each
+func countingClient(t *testing.T) (*github.Client, map[string]int) {
+	t.Helper()
+	var mu sync.Mutex
+	counts := map[string]int{}
+	count := func(name string, handler http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			counts[name]++
+			mu.Unlock()
+			handler(w, r)
+		}
+	}
+	client := mock.NewMockedHTTPClient(
+		mock.WithRequestMatchHandler(
+			mock.GetReposContentsByOwnerByRepoByPath,
+			count("contents", func(w http.ResponseWriter, _ *http.Request) {
+				_ = json.NewEncoder(w).Encode(&github.RepositoryContent{Path: github.Ptr("README.md"), SHA: github.Ptr("blob123")})
+			}),
+		),
+		mock.WithRequestMatchHandler(
+			mock.GetReposGitTreesByOwnerByRepoByTreeSha,
+			count("tree", func(w http.ResponseWriter, _ *http.Request) {
+				_ = json.NewEncoder(w).Encode(&github.Tree{SHA: github.Ptr(commitSHA), Entries: []*github.TreeEntry{{Path: github.Ptr("README.md")}}})
+			}),
+		),
+		// The raw pattern also matches API paths, so it goes last
+		mock.WithRequestMatchHandler(
+			GetRawReposContentsByOwnerByRepoBySHAByPath,
+			count("raw", func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set("Content-Type", "text/markdown")
+				_, _ = w.Write([]byte("# Hello"))
+			}),
+		),
+	)
+	return github.NewClient(client), counts
+}
+
+// readAll reads path at sha through client and returns the content type and body
+func readAll(t *testing.T, client *Client, sha string) (string, string) {
+	t.Helper()
+	resp, err := client.GetRawContent(context.Background(), "octocat", "hello", "README.md", &ContentOpts{SHA: sha})
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp.Header.Get("Content-Type"), string(body)
+}
+
+func TestClient_CachesCommitReads(t *testing.T) {
+	base, _ := url.Parse("https://raw.example.com/")
+	ghClient, counts := countingClient(t)
+	cache, err := NewCache(CacheConfig{MaxBytes: 1024 * 1024})
+	require.NoError(t, err)
+	client := NewClient(ghClient, base).WithCache(cache, "alice-token")
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		contentType, body := readAll(t, client, commitSHA)
+		assert.Equal(t, "text/markdown", contentType)
+		assert.Equal(t, "# Hello", body)
+
+		sha, resp, err := client.GetFileSHA(ctx, "octocat", "hello", "README.md", commitSHA)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		assert.Equal(t, "blob123", sha)
+
+		tree, resp, err := client.GetTree(ctx, "octocat", "hello", commitSHA, true)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		assert.Equal(t, "README.md", tree.Entries[0].GetPath())
+	}
+	assert.Equal(t, map[string]int{"raw": 1, "contents": 1, "tree": 1}, counts, "repeat reads at a commit are free")
+
+	// Abbreviated SHAs and refs may change, so their reads are not cached
+	readAll(t, client, commitSHA[:7])
+	readAll(t, client, commitSHA[:7])
+	_, resp, err := client.GetFileSHA(ctx, "octocat", "hello", "README.md", "main")
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, 3, counts["raw"])
+	assert.Equal(t, 2, counts["contents"])
+
+	// Content is not shared between identities
+	readAll(t, NewClient(ghClient, base).WithCache(cache, "bob-token"), commitSHA)
+	assert.Equal(t, 4, counts["raw"])
+
+	stats := cache.Stats()
+	assert.Equal(t, int64(3), stats.Hits)
+	assert.Equal(t, int64(4), stats.Misses)
+	assert.Equal(t, 4, stats.Entries)
+}
+
+func TestCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	cache, err := NewCache(CacheConfig{MaxBytes: 25})
+	require.NoError(t, err)
+	cache.maxEntryBytes = 20
+
+	var v string
+	cache.store("a", "aaaaaaaa") // 10 bytes encoded
+	cache.store("b", "bbbbbbbb")
+	assert.True(t, cache.load("a", &v)) // a is now more recently used than b
+	cache.store("c", "cccccccc")        // evicts b
+	cache.store("d", strings.Repeat("d", 30))
+
+	assert.True(t, cache.load("a", &v))
+	assert.False(t, cache.load("b", &v))
+	assert.True(t, cache.load("c", &v))
+	assert.False(t, cache.load("d", &v), "entries larger than the limit are not cached")
+	assert.Equal(t, int64(1), cache.Stats().Evictions)
+	assert.Equal(t, int64(20), cache.Stats().Bytes)
+}
+
+func TestCache_Disk(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "content")
+	cache, err := NewCache(CacheConfig{MaxBytes: 1024, Dir: dir})
+	require.NoError(t, err)
+	cache.store("key", cachedContent{ContentType: "text/plain", Body: []byte("hello")})
+
+	info, err := os.Stat(dir)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0700), info.Mode().Perm())
+
+	// A new cache serves the persisted entries
+	restarted, err := NewCache(CacheConfig{MaxBytes: 1024, Dir: dir})
+	require.NoError(t, err)
+	var content cachedContent
+	require.True(t, restarted.load("key", &content))
+	assert.Equal(t, "hello", string(content.Body))
+
+	// and drops those that no longer fit
+	small, err := NewCache(CacheConfig{MaxBytes: 16, Dir: dir})
+	require.NoError(t, err)
+	assert.Equal(t, 0, small.Stats().Entries)
+	_, err = os.Stat(filepath.Join(dir, "key"+cacheFileSuffix))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestIsCommitSHA(t *testing.T) {
+	assert.True(t, IsCommitSHA(commitSHA))
+	assert.True(t, IsCommitSHA(strings.ToUpper(commitSHA)))
+	assert.True(t, IsCommitSHA(strings.Repeat("a", 64)), "SHA-256 repositories")
+	assert.False(t, IsCommitSHA(commitSHA[:7]))
+	assert.False(t, IsCommitSHA("refs/heads/main"))
+	assert.False(t, IsCommitSHA(strings.Repeat("g", 40)))
+}
@@ -2,9 +2,15 @@
 package raw
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
 
 	gogithub "github.com/google/go-github/v74/github"
 )
@@ -16,13 +22,71 @@ type GetRawClientFn func(context.Context) (*Client, error)
 type Client struct {
 	url    *url.URL
 	client *gogithub.Client
+	api    *gogithub.Client
+
+	cache *Cache
+	scope string
 }
 
 // NewClient creates a new instance of the raw API Client with the provided GitHub client and provided URL.
 func NewClient(client *gogithub.Client, rawURL *url.URL) *Client {
+	api := client
 	client = gogithub.NewClient(client.Client())
 	client.BaseURL = rawURL
-	return &Client{client: client, url: rawURL}
+	return &Client{client: client, api: api, url: rawURL}
+}
+
+// WithCache returns a copy of the client that keeps reads pinned to a full commit SHA in cache.
+// Cached content is only served to the same identity, such as the token of the request, so it
+// never skips GitHub's access checks for anyone else.
+func (c *Client) WithCache(cache *Cache, identity string) *Client {
+	cached := *c
+	cached.cache = cache
+	sum := sha256.Sum256([]byte(identity))
+	cached.scope = hex.EncodeToString(sum[:])
+	return &cached
+}
+
+// IsCommitSHA reports whether sha is a full commit SHA, whose content never changes. An
+// abbreviated SHA may become ambiguous, so it is not one.
+func IsCommitSHA(sha string) bool {
+	if len(sha) != 40 && len(sha) != 64 {
+		return false
+	}
+	_, err := hex.DecodeString(sha)
+	return err == nil
+}
+
+// cached reports whether reads at sha go through the cache
+func (c *Client) cached(sha string) bool {
+	return c.cache != nil && IsCommitSHA(sha)
+}
+
+// cacheKey addresses what is read at sha, for the identity of the client
+func (c *Client) cacheKey(kind, owner, repo, sha, path string) string {
+	key := sha256.Sum256([]byte(strings.Join([]string{c.scope, c.url.String(), kind, owner, repo, strings.ToLower(sha), path}, "\n")))
+	return hex.EncodeToString(key[:])
+}
+
+// cachedContent is a raw file in the cache
+type cachedContent struct {
+	ContentType string `json:"content_type"`
+	Body        []byte `json:"body"`
+}
+
+// cachedResponse is the response returned for reads answered from the cache
+func cachedResponse(req *http.Request, header http.Header, body []byte) *http.Response {
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
 }
 
 func (c *Client) newRequest(ctx context.Context, method string, urlStr string, body interface{}, opts ...gogithub.RequestOption) (*http.Request, error) {
@@ -61,7 +125,8 @@ type ContentOpts struct {
 	SHA string
 }
 
-// GetRawContent fetches the raw content of a file from a GitHub repository.
+// GetRawContent fetches the raw content of a file from a GitHub repository. Content pinned to
+// a full commit SHA is served from the cache if the client has one.
 func (c *Client) GetRawContent(ctx context.Context, owner, repo, path string, opts *ContentOpts) (*http.Response, error) {
 	url := c.URLFromOpts(opts, owner, repo, path)
 	req, err := c.newRequest(ctx, "GET", url, nil)
@@ -69,5 +134,67 @@ func (c *Client) GetRawContent(ctx context.Context, owner, repo, path string, op
 		return nil, err
 	}
 
-	return c.client.Client().Do(req)
+	if opts == nil || !c.cached(opts.SHA) {
+		return c.client.Client().Do(req)
+	}
+
+	key := c.cacheKey("raw", owner, repo, opts.SHA, path)
+	var content cachedContent
+	if c.cache.load(key, &content) {
+		header := http.Header{}
+		if content.ContentType != "" {
+			header.Set("Content-Type", content.ContentType)
+		}
+		return cachedResponse(req, header, content.Body), nil
+	}
+
+	resp, err := c.client.Client().Do(req)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return resp, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	c.cache.store(key, cachedContent{ContentType: resp.Header.Get("Content-Type"), Body: body})
+	return resp, nil
+}
+
+// GetFileSHA returns the blob SHA of the file at path from the contents API, or an empty SHA
+// if path is not a file. Lookups at a full commit SHA are served from the cache if the client
+// has one.
+func (c *Client) GetFileSHA(ctx context.Context, owner, repo, path, ref string) (string, *gogithub.Response, error) {
+	key := c.cacheKey("sha", owner, repo, ref, path)
+	var sha string
+	if c.cached(ref) && c.cache.load(key, &sha) {
+		return sha, &gogithub.Response{Response: cachedResponse(nil, http.Header{}, nil)}, nil
+	}
+
+	opts := &gogithub.RepositoryContentGetOptions{Ref: ref}
+	fileContent, _, resp, err := c.api.Repositories.GetContents(ctx, owner, repo, path, opts)
+	if err != nil || fileContent == nil || fileContent.SHA == nil {
+		return "", resp, err
+	}
+	if c.cached(ref) {
+		c.cache.store(key, fileContent.GetSHA())
+	}
+	return fileContent.GetSHA(), resp, nil
+}
+
+// GetTree returns the Git tree of sha. Trees of a full commit SHA are served from the cache if
+// the client has one.
+func (c *Client) GetTree(ctx context.Context, owner, repo, sha string, recursive bool) (*gogithub.Tree, *gogithub.Response, error) {
+	key := c.cacheKey("tree", owner, repo, sha, strconv.FormatBool(recursive))
+	var tree gogithub.Tree
+	if c.cached(sha) && c.cache.load(key, &tree) {
+		return &tree, &gogithub.Response{Response: cachedResponse(nil, http.Header{}, nil)}, nil
+	}
+
+	fetched, resp, err := c.api.Git.GetTree(ctx, owner, repo, sha, recursive)
+	if err == nil && c.cached(sha) {
+		c.cache.store(key, fetched)
+	}
+	return fetched, resp, err
 }
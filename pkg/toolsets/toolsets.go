@@ -47,6 +47,10 @@ func NewServerPrompt(prompt mcp.Prompt, handler server.PromptHandlerFunc) server
 	}
 }
 
+// ToolMiddleware wraps the handler of a tool, for example to enforce a policy before the tool runs.
+// The tool definition is passed so middleware can inspect its schema and skip tools it does not apply to.
+type ToolMiddleware func(tool mcp.Tool, next server.ToolHandlerFunc) server.ToolHandlerFunc
+
 // Toolset represents a collection of MCP functionality that can be enabled or disabled as a group.
 type Toolset struct {
 	Name        string
@@ -60,14 +64,16 @@ type Toolset struct {
 	resourceTemplates []server.ServerResourceTemplate
 	// prompts are also not tools but are namespaced similarly
 	prompts []server.ServerPrompt
+	// middleware wraps every tool handler when the tools are registered, outermost first
+	middleware []ToolMiddleware
 }
 
 func (t *Toolset) GetActiveTools() []server.ServerTool {
 	if t.Enabled {
 		if t.readOnly {
-			return t.readTools
+			return t.withMiddleware(t.readTools)
 		}
-		return append(t.readTools, t.writeTools...)
+		return t.withMiddleware(append(t.readTools, t.writeTools...))
 	}
 	return nil
 }
@@ -83,16 +89,38 @@ func (t *Toolset) RegisterTools(s *server.MCPServer) {
 	if !t.Enabled {
 		return
 	}
-	for _, tool := range t.readTools {
+	for _, tool := range t.withMiddleware(t.readTools) {
 		s.AddTool(tool.Tool, tool.Handler)
 	}
 	if !t.readOnly {
-		for _, tool := range t.writeTools {
+		for _, tool := range t.withMiddleware(t.writeTools) {
 			s.AddTool(tool.Tool, tool.Handler)
 		}
 	}
 }
 
+// Use adds middleware that wraps the handler of every tool in the toolset
+func (t *Toolset) Use(middleware ...ToolMiddleware) *Toolset {
+	t.middleware = append(t.middleware, middleware...)
+	return t
+}
+
+// withMiddleware returns copies of the tools with their handlers wrapped by the toolset middleware
+func (t *Toolset) withMiddleware(tools []server.ServerTool) []server.ServerTool {
+	if len(t.middleware) == 0 {
+		return tools
+	}
+	wrapped := make([]server.ServerTool, 0, len(tools))
+	for _, tool := range tools {
+		handler := tool.Handler
+		for i := len(t.middleware) - 1; i >= 0; i-- {
+			handler = t.middleware[i](tool.Tool, handler)
+		}
+		wrapped = append(wrapped, server.ServerTool{Tool: tool.Tool, Handler: handler})
+	}
+	return wrapped
+}
+
 func (t *Toolset) AddResourceTemplates(templates ...server.ServerResourceTemplate) *Toolset {
 	t.resourceTemplates = append(t.resourceTemplates, templates...)
 	return t
@@ -164,6 +192,7 @@ type ToolsetGroup struct {
 	Toolsets     map[string]*Toolset
 	everythingOn bool
 	readOnly     bool
+	middleware   []ToolMiddleware
 }
 
 func NewToolsetGroup(readOnly bool) *ToolsetGroup {
@@ -178,9 +207,18 @@ func (tg *ToolsetGroup) AddToolset(ts *Toolset) {
 	if tg.readOnly {
 		ts.SetReadOnly()
 	}
+	ts.Use(tg.middleware...)
 	tg.Toolsets[ts.Name] = ts
 }
 
+// Use adds middleware to every toolset in the group, including toolsets added later
+func (tg *ToolsetGroup) Use(middleware ...ToolMiddleware) {
+	tg.middleware = append(tg.middleware, middleware...)
+	for _, ts := range tg.Toolsets {
+		ts.Use(middleware...)
+	}
+}
+
 func NewToolset(name string, description string) *Toolset {
 	return &Toolset{
 		Name:        name,
@@ -1,8 +1,12 @@
 package toolsets
 
 import (
+	"context"
 	"errors"
 	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
 )
 
 func TestNewToolsetGroupIsEmptyWithoutEverythingOn(t *testing.T) {
@@ -250,3 +254,60 @@ func TestToolsetGroup_GetToolset(t *testing.T) {
 		t.Errorf("expected error to be ToolsetDoesNotExistError, got %v", err)
 	}
 }
+
+func TestToolsetGroup_Use(t *testing.T) {
+	newTool := func(name string, readOnly bool) server.ServerTool {
+		tool := mcp.NewTool(name, mcp.WithToolAnnotation(mcp.ToolAnnotation{ReadOnlyHint: &readOnly}))
+		return NewServerTool(tool, func(_ context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return mcp.NewToolResultText(name), nil
+		})
+	}
+
+	var calls []string
+	record := func(label string) ToolMiddleware {
+		return func(tool mcp.Tool, next server.ToolHandlerFunc) server.ToolHandlerFunc {
+			return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+				calls = append(calls, label+":"+tool.Name)
+				return next(ctx, request)
+			}
+		}
+	}
+
+	tsg := NewToolsetGroup(false)
+	before := NewToolset("before", "added before Use").AddReadTools(newTool("read", true))
+	tsg.AddToolset(before)
+	tsg.Use(record("outer"), record("inner"))
+	after := NewToolset("after", "added after Use").AddWriteTools(newTool("write", false))
+	tsg.AddToolset(after)
+	if err := tsg.EnableToolsets([]string{"all"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	for _, toolset := range []*Toolset{before, after} {
+		for _, tool := range toolset.GetActiveTools() {
+			if _, err := tool.Handler(context.Background(), mcp.CallToolRequest{}); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+		}
+	}
+
+	expected := []string{"outer:read", "inner:read", "outer:write", "inner:write"}
+	if len(calls) != len(expected) {
+		t.Fatalf("expected calls %v, got %v", expected, calls)
+	}
+	for i := range expected {
+		if calls[i] != expected[i] {
+			t.Errorf("expected calls %v, got %v", expected, calls)
+			break
+		}
+	}
+
+	// The tools held by the toolset must not be wrapped themselves
+	calls = nil
+	if _, err := before.readTools[0].Handler(context.Background(), mcp.CallToolRequest{}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(calls) != 0 {
+		t.Errorf("expected unwrapped handler, got calls %v", calls)
+	}
+}
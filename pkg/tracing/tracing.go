@@ -0,0 +1,261 @@
+// Package tracing records OpenTelemetry spans of MCP requests, tool calls and the GitHub
+// requests they make, and exports them over OTLP or to a file for local debugging.
+//
+// Spans are created with the global tracer provider, which Start installs. Until then they are
+// no-ops, so the instrumentation costs nothing when tracing is disabled.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/github/github-mcp-server/pkg/toolsets"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName names the tracer of the server's spans
+const instrumentationName = "github.com/github/github-mcp-server"
+
+// The exporters spans can be sent to
+const (
+	// ExporterNone disables tracing
+	ExporterNone = "none"
+	// ExporterOTLP sends spans to an OpenTelemetry collector over OTLP/gRPC
+	ExporterOTLP = "otlp"
+	// ExporterStdout writes spans as JSON to stdout
+	ExporterStdout = "stdout"
+	// ExporterFile appends spans as JSON to Config.File
+	ExporterFile = "file"
+)
+
+// Config selects where spans are exported
+type Config struct {
+	// Exporter is none (the default), otlp, stdout or file
+	Exporter string
+
+	// Endpoint is the host:port of the OTLP collector, empty uses OTEL_EXPORTER_OTLP_ENDPOINT or
+	// localhost:4317
+	Endpoint string
+
+	// Insecure connects to the OTLP collector without TLS
+	Insecure bool
+
+	// File is the path the file exporter appends to
+	File string
+}
+
+// Start installs a global tracer provider that exports spans as configured, and returns the
+// function that flushes and stops it. With the none exporter nothing is installed.
+func Start(ctx context.Context, config Config, version string) (func(context.Context) error, error) {
+	var exporter sdktrace.SpanExporter
+	closeOutput := func() error { return nil }
+	switch config.Exporter {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterOTLP:
+		opts := []otlptracegrpc.Option{}
+		if config.Endpoint != "" {
+			opts = append(opts, otlptracegrpc.WithEndpoint(config.Endpoint))
+		}
+		if config.Insecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		otlpExporter, err := otlptracegrpc.New(ctx, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create OTLP trace exporter: %w", err)
+		}
+		exporter = otlpExporter
+	case ExporterStdout, ExporterFile:
+		var output io.Writer = os.Stdout
+		if config.Exporter == ExporterFile {
+			if config.File == "" {
+				return nil, errors.New("the file trace exporter requires a trace file")
+			}
+			file, err := os.OpenFile(config.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+			if err != nil {
+				return nil, fmt.Errorf("failed to open trace file: %w", err)
+			}
+			output, closeOutput = file, file.Close
+		}
+		stdoutExporter, err := stdouttrace.New(stdouttrace.WithWriter(output))
+		if err != nil {
+			_ = closeOutput()
+			return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+		}
+		exporter = stdoutExporter
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q, expected none, otlp, stdout or file", config.Exporter)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		semconv.ServiceName("github-mcp-server"),
+		semconv.ServiceVersion(version),
+	))
+	if err != nil {
+		_ = closeOutput()
+		return nil, fmt.Errorf("failed to create trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	return func(ctx context.Context) error {
+		return errors.Join(provider.Shutdown(ctx), closeOutput())
+	}, nil
+}
+
+func tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Middleware records each tool call as a span with the tool name, the repository it targets and
+// its outcome. The GitHub requests of the call become its children.
+func Middleware() toolsets.ToolMiddleware {
+	return func(tool mcp.Tool, next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			attrs := []attribute.KeyValue{attribute.String("mcp.tool.name", tool.Name)}
+			args := request.GetArguments()
+			if owner, _ := args["owner"].(string); owner != "" {
+				attrs = append(attrs, attribute.String("github.owner", owner))
+			}
+			if repo, _ := args["repo"].(string); repo != "" {
+				attrs = append(attrs, attribute.String("github.repo", repo))
+			}
+
+			ctx, span := tracer().Start(ctx, "tools/call "+tool.Name, trace.WithAttributes(attrs...))
+			defer span.End()
+
+			result, err := next(ctx, request)
+			switch {
+			case err != nil:
+				span.SetAttributes(attribute.String("mcp.tool.outcome", "error"))
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			case result != nil && result.IsError:
+				span.SetAttributes(attribute.String("mcp.tool.outcome", "tool_error"))
+				span.SetStatus(codes.Error, resultText(result))
+			default:
+				span.SetAttributes(attribute.String("mcp.tool.outcome", "ok"))
+			}
+			return result, err
+		}
+	}
+}
+
+// resultText returns the text of the first text content of result, e.g. its error message
+func resultText(result *mcp.CallToolResult) string {
+	for _, content := range result.Content {
+		if text, ok := content.(mcp.TextContent); ok {
+			return text.Text
+		}
+	}
+	return ""
+}
+
+// AddHooks records the MCP requests other than tool calls, which Middleware records, as spans.
+// Hooks cannot pass a context on to the request handler, so the GitHub requests made for them
+// are not children of these spans.
+func AddHooks(hooks *server.Hooks) {
+	var spans sync.Map // of trace.Span by request key
+	hooks.AddBeforeAny(func(ctx context.Context, id any, method mcp.MCPMethod, message any) {
+		if id == nil || method == mcp.MethodToolsCall {
+			return
+		}
+		attrs := []attribute.KeyValue{attribute.String("mcp.method", string(method))}
+		if request, ok := message.(*mcp.ReadResourceRequest); ok {
+			attrs = append(attrs, attribute.String("mcp.resource.uri", request.Params.URI))
+		}
+		_, span := tracer().Start(ctx, string(method), trace.WithAttributes(attrs...))
+		spans.Store(requestKey(ctx, id), span)
+	})
+	hooks.AddOnSuccess(func(ctx context.Context, id any, _ mcp.MCPMethod, _ any, _ any) {
+		if span, ok := spans.LoadAndDelete(requestKey(ctx, id)); ok {
+			span.(trace.Span).End()
+		}
+	})
+	hooks.AddOnError(func(ctx context.Context, id any, _ mcp.MCPMethod, _ any, err error) {
+		if span, ok := spans.LoadAndDelete(requestKey(ctx, id)); ok {
+			span.(trace.Span).RecordError(err)
+			span.(trace.Span).SetStatus(codes.Error, err.Error())
+			span.(trace.Span).End()
+		}
+	})
+}
+
+// requestKey identifies a request among those of every session
+func requestKey(ctx context.Context, id any) string {
+	sessionID := ""
+	if session := server.ClientSessionFromContext(ctx); session != nil {
+		sessionID = session.SessionID()
+	}
+	return fmt.Sprintf("%s/%v", sessionID, id)
+}
+
+// NewTransport returns a http.RoundTripper that records each request of transport as a client
+// span of the current trace. api names the GitHub API a request goes to, e.g. rest or graphql.
+func NewTransport(transport http.RoundTripper, api func(*http.Request) string) http.RoundTripper {
+	return &tracingTransport{transport: transport, api: api}
+}
+
+type tracingTransport struct {
+	transport http.RoundTripper
+	api       func(*http.Request) string
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *tracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	api := t.api(req)
+	// The query may carry search terms, so only the rest of the URL is recorded
+	u := *req.URL
+	u.RawQuery, u.User = "", nil
+	ctx, span := tracer().Start(req.Context(), "GitHub "+api+" "+req.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("github.api", api),
+			semconv.HTTPRequestMethodKey.String(req.Method),
+			semconv.URLFull(u.String()),
+			semconv.ServerAddress(req.URL.Hostname()),
+		),
+	)
+	defer span.End()
+
+	resp, err := t.transport.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+	if requestID := resp.Header.Get("X-GitHub-Request-Id"); requestID != "" {
+		span.SetAttributes(attribute.String("github.request_id", requestID))
+	}
+	if remaining, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining")); err == nil {
+		span.SetAttributes(attribute.Int("github.rate_limit.remaining", remaining))
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		span.SetStatus(codes.Error, resp.Status)
+	}
+	return resp, nil
+}
@@ -0,0 +1,168 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// recordSpans installs a tracer provider that records the ended spans for the test
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func attributes(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	attrs := map[attribute.Key]attribute.Value{}
+	for _, kv := range span.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	return attrs
+}
+
+func TestMiddleware(t *testing.T) {
+	recorder := recordSpans(t)
+
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("X-GitHub-Request-Id", "ABCD:1234")
+		w.Header().Set("X-RateLimit-Remaining", "4999")
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer api.Close()
+	client := &http.Client{Transport: NewTransport(http.DefaultTransport, func(*http.Request) string { return "rest" })}
+
+	next := func(ctx context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, api.URL+"/repos/org/repo/issues?q=secret", nil)
+		require.NoError(t, err)
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		return mcp.NewToolResultError("issue not found"), nil
+	}
+
+	request := mcp.CallToolRequest{}
+	request.Params.Name = "get_issue"
+	request.Params.Arguments = map[string]any{"owner": "org", "repo": "repo", "issue_number": 1}
+	_, err := Middleware()(mcp.NewTool("get_issue"), next)(context.Background(), request)
+	require.NoError(t, err)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	githubSpan, toolSpan := spans[0], spans[1]
+
+	assert.Equal(t, "tools/call get_issue", toolSpan.Name())
+	attrs := attributes(toolSpan)
+	assert.Equal(t, "get_issue", attrs["mcp.tool.name"].AsString())
+	assert.Equal(t, "org", attrs["github.owner"].AsString())
+	assert.Equal(t, "repo", attrs["github.repo"].AsString())
+	assert.Equal(t, "tool_error", attrs["mcp.tool.outcome"].AsString())
+	assert.Equal(t, codes.Error, toolSpan.Status().Code)
+	assert.Equal(t, "issue not found", toolSpan.Status().Description)
+
+	// The GitHub request is a child of the tool call
+	assert.Equal(t, "GitHub rest GET", githubSpan.Name())
+	assert.Equal(t, toolSpan.SpanContext().SpanID(), githubSpan.Parent().SpanID())
+	attrs = attributes(githubSpan)
+	assert.Equal(t, "rest", attrs["github.api"].AsString())
+	assert.Equal(t, api.URL+"/repos/org/repo/issues", attrs["url.full"].AsString(), "the query is not recorded")
+	assert.Equal(t, int64(http.StatusNotFound), attrs["http.response.status_code"].AsInt64())
+	assert.Equal(t, "ABCD:1234", attrs["github.request_id"].AsString())
+	assert.Equal(t, int64(4999), attrs["github.rate_limit.remaining"].AsInt64())
+	assert.Equal(t, codes.Error, githubSpan.Status().Code)
+}
+
+func TestMiddleware_Error(t *testing.T) {
+	recorder := recordSpans(t)
+
+	next := func(_ context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return nil, errors.New("boom")
+	}
+	_, err := Middleware()(mcp.NewTool("get_me"), next)(context.Background(), mcp.CallToolRequest{})
+	require.Error(t, err)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "error", attributes(spans[0])["mcp.tool.outcome"].AsString())
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	assert.Len(t, spans[0].Events(), 1, "the error is recorded")
+}
+
+func TestAddHooks(t *testing.T) {
+	recorder := recordSpans(t)
+
+	hooks := &server.Hooks{}
+	AddHooks(hooks)
+	ctx := context.Background()
+
+	read := &mcp.ReadResourceRequest{}
+	read.Params.URI = "repo://org/repo/contents/README.md"
+	hooks.OnBeforeAny[0](ctx, 1, mcp.MethodResourcesRead, read)
+	hooks.OnError[0](ctx, 1, mcp.MethodResourcesRead, read, errors.New("not found"))
+
+	// Tool calls are recorded by the middleware, and notifications have no response to end them
+	hooks.OnBeforeAny[0](ctx, 2, mcp.MethodToolsCall, &mcp.CallToolRequest{})
+	hooks.OnBeforeAny[0](ctx, nil, mcp.MethodNotificationResourcesListChanged, nil)
+
+	hooks.OnBeforeAny[0](ctx, 3, mcp.MethodToolsList, &mcp.ListToolsRequest{})
+	hooks.OnSuccess[0](ctx, 3, mcp.MethodToolsList, &mcp.ListToolsRequest{}, &mcp.ListToolsResult{})
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	assert.Equal(t, "resources/read", spans[0].Name())
+	assert.Equal(t, "repo://org/repo/contents/README.md", attributes(spans[0])["mcp.resource.uri"].AsString())
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	assert.Equal(t, "tools/list", spans[1].Name())
+	assert.Equal(t, codes.Unset, spans[1].Status().Code)
+	assert.Empty(t, recorder.Started()[2:], "no other spans were started")
+}
+
+func TestStart(t *testing.T) {
+	previous := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	ctx := context.Background()
+
+	stop, err := Start(ctx, Config{}, "1.0.0")
+	require.NoError(t, err)
+	require.NoError(t, stop(ctx))
+	assert.Equal(t, previous, otel.GetTracerProvider(), "tracing is off by default")
+
+	_, err = Start(ctx, Config{Exporter: "jaeger"}, "1.0.0")
+	assert.ErrorContains(t, err, `unknown trace exporter "jaeger"`)
+	_, err = Start(ctx, Config{Exporter: ExporterFile}, "1.0.0")
+	assert.ErrorContains(t, err, "requires a trace file")
+
+	path := filepath.Join(t.TempDir(), "traces.json")
+	stop, err = Start(ctx, Config{Exporter: ExporterFile, File: path}, "1.0.0")
+	require.NoError(t, err)
+	_, err = Middleware()(mcp.NewTool("get_me"), func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText("ok"), nil
+	})(ctx, mcp.CallToolRequest{})
+	require.NoError(t, err)
+	require.NoError(t, stop(ctx))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"Name":"tools/call get_me"`)
+	assert.Contains(t, string(data), `"Value":"github-mcp-server"`)
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+}